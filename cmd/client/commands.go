@@ -0,0 +1,1152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"key-box/internal/audit"
+	"key-box/internal/auth"
+	"key-box/internal/breach"
+	"key-box/internal/clipboard"
+	"key-box/internal/config"
+	"key-box/internal/generator"
+	"key-box/internal/strength"
+	"key-box/internal/vault"
+)
+
+// app 子命令共用的服务。
+type app struct {
+	scanner *bufio.Scanner
+	auth    *auth.Service
+	vault   *vault.Manager
+	audit   *audit.Logger
+}
+
+// runCommand 执行非交互子命令，返回进程退出码。
+// 用法: key-box <command> [flags]
+func runCommand(a *app, args []string) int {
+	var err error
+	switch args[0] {
+	case "audit":
+		err = cmdAudit(a, args[1:])
+	case "list":
+		err = cmdList(a, args[1:])
+	case "get":
+		err = cmdGet(a, args[1:])
+	case "match":
+		err = cmdMatch(a, args[1:])
+	case "search":
+		err = cmdSearch(a, args[1:])
+	case "history":
+		err = cmdHistory(a, args[1:])
+	case "generate":
+		err = cmdGenerate(args[1:])
+	case "audit-passwords":
+		err = cmdAuditPasswords(a, args[1:])
+	case "breach":
+		err = cmdBreach(a, args[1:])
+	case "strength":
+		err = cmdStrength(a, args[1:])
+	case "trash":
+		err = cmdTrash(a, args[1:])
+	case "attach":
+		err = cmdAttach(a, args[1:])
+	case "detach":
+		err = cmdDetach(a, args[1:])
+	case "cat":
+		err = cmdCat(a, args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		printUsage()
+		return 2
+	}
+
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `用法: key-box [command] [flags]
+
+不带参数时进入交互菜单。
+
+Commands:
+  audit [--verify]                          查看审计日志 / 校验审计日志完整性
+  list [--folder 路径] [--tag 标签] [--favorites]  列出条目 (不显示密码)
+  get [--clip] [--clear-after 30s] [--folder 路径] [--tag 标签] <关键字>
+                                            输出密码 (安全笔记输出内容)，或复制到剪贴板并在超时后清除
+  match [--json] [--password] <网址>          按网址查找条目，按匹配程度排序
+  search [--json] <查询>                      模糊搜索条目，支持 user: tag: url: folder: note: is:fav
+  history [--show] [--revert N] <关键字>     查看条目的历史版本 / 恢复为第 N 个历史版本
+  audit-passwords [--json] [--max-age 12]   密码库体检: 重复使用、弱密码、长期未修改、重复条目
+  breach check [--data 路径] [--json]       离线检查密码是否出现在本地 HIBP 泄露数据中
+  breach build-index <原始文本> <索引>       由 HIBP 原始文本生成二分查找索引 (不需要登录)
+  generate [--length 20] [--passphrase]     生成随机密码或口令 (不需要登录，-h 查看全部选项)
+  strength [--user-inputs a,b] [密码]        评估密码强度 (不需要登录，省略密码时从标准输入读取)
+  trash list                                查看回收站
+  trash restore <关键字>                     从回收站还原条目
+  trash purge [--all] [<关键字>]              永久删除回收站中的条目 / 清空回收站
+  attach [--name 名称] <关键字> <文件>         为条目添加加密附件
+  attach --list <关键字>                      列出条目的附件
+  detach <关键字> <附件名>                     删除附件
+  cat <关键字> <附件名>                        解密附件并输出到 stdout`)
+}
+
+// login 提示输入用户名、OTP 和主密码并登录，返回用户名和 Key C。
+// 提示信息输出到 stderr，便于 stdout 被重定向或管道处理。
+func (a *app) login() (string, []byte, error) {
+	username := a.prompt("用户名: ")
+	otp := a.prompt("请输入 6 位 OTP 验证码: ")
+	fmt.Fprint(os.Stderr, "主密码 (未启用请直接回车): ")
+	a.scanner.Scan()
+	passphrase := a.scanner.Text()
+
+	keyC, err := a.auth.Login(username, otp, passphrase)
+	if err != nil {
+		return "", nil, fmt.Errorf("登录失败: %v", err)
+	}
+	purgeExpiredTrash(a.vault, a.audit, username, keyC)
+	return username, keyC, nil
+}
+
+// purgeExpiredTrash 登录后永久删除在回收站中超过保留天数 (偏好设置，与 GUI 共用) 的条目。
+// 清理失败不影响登录，只输出警告。
+func purgeExpiredTrash(v *vault.Manager, l *audit.Logger, username string, keyC []byte) {
+	settings, _ := config.LoadSettings()
+	n, err := v.PurgeExpiredTrash(username, settings.TrashRetentionDays)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Warning] 清理回收站失败: %v\n", err)
+		return
+	}
+	if n > 0 {
+		l.Append(username, keyC, audit.EventItemPurge, fmt.Sprintf("自动清理 %d 个超过 %d 天的条目 (CLI)", n, settings.TrashRetentionDays))
+	}
+}
+
+func (a *app) prompt(label string) string {
+	fmt.Fprint(os.Stderr, label)
+	a.scanner.Scan()
+	return strings.TrimSpace(a.scanner.Text())
+}
+
+// cmdAudit key-box audit [--verify]
+func cmdAudit(a *app, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	verify := fs.Bool("verify", false, "校验审计日志哈希链的完整性")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+
+	if *verify {
+		n, err := a.audit.Verify(username, keyC)
+		if err != nil {
+			return fmt.Errorf("校验失败 (前 %d 条完好): %v", n, err)
+		}
+		fmt.Printf("审计日志完整: 共 %d 条记录，哈希链与链头一致。\n", n)
+		return nil
+	}
+
+	entries, err := a.audit.List(username, keyC)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%5d  %s  %s", e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Type.Label())
+		if e.Detail != "" {
+			line += "  " + e.Detail
+		}
+		if e.Pending {
+			line += "  (登录前发生)"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// addFilterFlags 注册 list/get 共用的 --folder、--tag 筛选参数。
+func addFilterFlags(fs *flag.FlagSet) *vault.ItemFilter {
+	f := &vault.ItemFilter{}
+	fs.StringVar(&f.Folder, "folder", "", "只包含该文件夹及其子文件夹中的条目")
+	fs.StringVar(&f.Tag, "tag", "", "只包含带有该标签的条目 (不区分大小写)")
+	return f
+}
+
+// formatOrganize 条目的文件夹、标签和收藏标记，用于列表输出。
+func formatOrganize(item vault.VaultItem) string {
+	var s string
+	if item.Favorite {
+		s += " ★"
+	}
+	if item.Folder != "" {
+		s += " [" + item.Folder + "]"
+	}
+	for _, t := range item.Tags {
+		s += " #" + t
+	}
+	return s
+}
+
+// formatAccount 条目的账号，安全笔记没有账号时显示类型。
+func formatAccount(item vault.VaultItem) string {
+	if item.IsNote() {
+		return "(安全笔记)"
+	}
+	return item.Username
+}
+
+// cmdList key-box list [--folder 路径] [--tag 标签] [--favorites]
+// 只列出网站、账号、文件夹和标签，不输出密码。
+func cmdList(a *app, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	filter := addFilterFlags(fs)
+	fs.BoolVar(&filter.Favorites, "favorites", false, "只包含收藏的条目")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("用法: key-box list [--folder 路径] [--tag 标签] [--favorites]")
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	items = filter.Apply(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Folder != items[j].Folder {
+			return items[i].Folder < items[j].Folder
+		}
+		return strings.ToLower(items[i].Site) < strings.ToLower(items[j].Site)
+	})
+	for _, item := range items {
+		fmt.Printf("%s | %s%s\n", item.Site, formatAccount(item), formatOrganize(item))
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(os.Stderr, "没有匹配的条目。")
+	}
+	return nil
+}
+
+// cmdGet key-box get [--clip] [--clear-after 30s] [--folder 路径] [--tag 标签] <关键字>
+// 输出登录条目的密码或安全笔记的内容。
+func cmdGet(a *app, args []string) error {
+	settings, _ := config.LoadSettings()
+
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	clip := fs.Bool("clip", false, "复制到剪贴板而不是输出到 stdout")
+	clearAfter := fs.Duration("clear-after", time.Duration(settings.ClipboardClearSeconds)*time.Second,
+		"--clip 模式下多久后清除剪贴板 (0 表示直到按 Ctrl+C)")
+	filter := addFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("用法: key-box get [--clip] [--clear-after 30s] [--folder 路径] [--tag 标签] <网站或账号关键字>")
+	}
+
+	// 先确认剪贴板可用，再要求输入登录信息
+	var cb clipboard.Clipboard
+	if *clip {
+		var err error
+		if cb, err = clipboard.Open(); err != nil {
+			return err
+		}
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	items = filter.Apply(items)
+	item, err := a.pickItem(items, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// 安全笔记输出笔记内容
+	secret := item.Password
+	if item.IsNote() {
+		secret = item.Notes
+	}
+
+	if cb == nil {
+		fmt.Println(secret)
+		a.audit.Append(username, keyC, audit.EventItemView, item.Site+" (CLI)")
+		a.vault.MarkUsed(item.ID)
+		return nil
+	}
+
+	if err := cb.Copy(secret); err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventItemCopy, item.Site+" (CLI)")
+	a.vault.MarkUsed(item.ID)
+	return waitAndClear(cb, secret, *clearAfter)
+}
+
+// matchResult match --json 输出的一个候选条目 (不含密码)。
+type matchResult struct {
+	ID       int    `json:"id"`
+	Site     string `json:"site"`
+	Username string `json:"username"`
+	URI      string `json:"uri"`
+	Match    string `json:"match"`
+	Score    int    `json:"score"`
+}
+
+// cmdMatch key-box match [--json] [--password] <网址>
+// 没有匹配的条目时以非 0 退出码结束，便于脚本判断。
+func cmdMatch(a *app, args []string) error {
+	fs := flag.NewFlagSet("match", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出候选条目 (不含密码)")
+	password := fs.Bool("password", false, "只输出最佳匹配条目的密码")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("用法: key-box match [--json] [--password] <网址>")
+	}
+	if _, err := vault.ParseTargetURL(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	candidates, err := vault.MatchItems(items, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("没有与 %s 匹配的条目", fs.Arg(0))
+	}
+
+	if *password {
+		best := candidates[0].Item
+		a.audit.Append(username, keyC, audit.EventItemView, best.Site+" (match, CLI)")
+		a.vault.MarkUsed(best.ID)
+		fmt.Println(best.Password)
+		return nil
+	}
+
+	if *asJSON {
+		results := make([]matchResult, len(candidates))
+		for i, c := range candidates {
+			results[i] = matchResult{ID: c.Item.ID, Site: c.Item.Site, Username: c.Item.Username,
+				URI: c.URI.URI, Match: string(c.URI.Match), Score: c.Score}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	for i, c := range candidates {
+		fmt.Printf("%d. %s | %s  (%s: %s)\n", i+1, c.Item.Site, c.Item.Username, c.URI.Match.Label(), c.URI.URI)
+	}
+	return nil
+}
+
+// searchResult search --json 输出的一个条目 (不含密码和笔记)。
+type searchResult struct {
+	ID       int      `json:"id"`
+	Type     string   `json:"type"`
+	Site     string   `json:"site"`
+	Username string   `json:"username,omitempty"`
+	Folder   string   `json:"folder,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Score    int      `json:"score"`
+}
+
+// cmdSearch key-box search [--json] <查询>
+// 与 GUI 搜索框使用相同的搜索语法和排序 (vault.Search)，只输出条目信息，不输出密码。
+func cmdSearch(a *app, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出 (不含密码)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("用法: key-box search [--json] <查询>，如 key-box search user:alice tag:work github")
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	results := vault.Search(items, query, time.Now())
+
+	if *asJSON {
+		out := make([]searchResult, len(results))
+		for i, r := range results {
+			out[i] = searchResult{ID: r.Item.ID, Type: string(r.Item.Type), Site: r.Item.Site, Username: r.Item.Username,
+				Folder: r.Item.Folder, Tags: r.Item.Tags, Score: r.Score}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	for _, r := range results {
+		fmt.Printf("%s | %s%s\n", r.Item.Site, formatAccount(r.Item), formatOrganize(r.Item))
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "没有匹配的条目。")
+	}
+	return nil
+}
+
+// cmdHistory key-box history [--show] [--revert N] <关键字>
+// 默认不显示密码明文，--show 时才输出。
+func cmdHistory(a *app, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	show := fs.Bool("show", false, "显示历史密码明文")
+	revert := fs.Int("revert", 0, "恢复为第 N 个历史版本 (编号见列表)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("用法: key-box history [--show] [--revert N] <网站或账号关键字>")
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	item, err := a.pickItem(items, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	history, err := a.vault.ListHistory(keyC, item.ID)
+	if err != nil {
+		return err
+	}
+
+	if *revert != 0 {
+		if *revert < 1 || *revert > len(history) {
+			return fmt.Errorf("没有第 %d 个历史版本 (共 %d 个)", *revert, len(history))
+		}
+		settings, _ := config.LoadSettings()
+		a.vault.HistoryLimit = settings.HistoryLimit
+		h := history[*revert-1]
+		if err := a.vault.RevertItem(keyC, item.ID, h.ID); err != nil {
+			return err
+		}
+		a.audit.Append(username, keyC, audit.EventItemRevert, fmt.Sprintf("%s (%s) (CLI)", item.Site, h.SavedAt.Local().Format("2006-01-02 15:04")))
+		fmt.Printf("已将 %s 恢复为 %s 的版本。\n", item.Site, h.SavedAt.Local().Format("2006-01-02 15:04:05"))
+		return nil
+	}
+
+	mask := func(p string) string {
+		if *show {
+			return p
+		}
+		return "********"
+	}
+	fmt.Printf("当前   %s  %s | %s | %s\n", item.UpdatedAt.Local().Format("2006-01-02 15:04:05"), item.Site, item.Username, mask(item.Password))
+	if len(history) == 0 {
+		fmt.Println("没有历史版本。")
+		return nil
+	}
+	for i, h := range history {
+		fmt.Printf("%-6s %s  %s | %s | %s  (使用至 %s)\n", fmt.Sprintf("[%d]", i+1),
+			h.SavedAt.Local().Format("2006-01-02 15:04:05"), h.Site, h.Username, mask(h.Password),
+			h.ArchivedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if *show {
+		a.audit.Append(username, keyC, audit.EventItemView, item.Site+" (历史版本, CLI)")
+	}
+	return nil
+}
+
+// cmdAuditPasswords key-box audit-passwords [--json] [--max-age 12] [--min-score 3]
+// 报告中只有网站和用户名，不输出密码。
+func cmdAuditPasswords(a *app, args []string) error {
+	def := vault.DefaultHealthOptions()
+
+	fs := flag.NewFlagSet("audit-passwords", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出报告")
+	maxAge := fs.Int("max-age", def.MaxAgeMonths, "超过多少个月未修改视为过期 (0 表示不检查)")
+	minScore := fs.Int("min-score", def.MinScore, "强度等级 (0-4) 低于该值视为弱密码")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	report, err := a.vault.HealthReport(username, keyC, vault.HealthOptions{MaxAgeMonths: *maxAge, MinScore: *minScore})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Printf("共 %d 个条目，发现 %d 个问题。\n", report.Total, report.Issues())
+	if len(report.Reused) > 0 {
+		fmt.Printf("\n重复使用的密码 (%d 组):\n", len(report.Reused))
+		for _, g := range report.Reused {
+			fmt.Printf("  - %d 个条目共用: %s\n", len(g.Items), strings.Join(g.Sites, ", "))
+			for _, ref := range g.Items {
+				fmt.Printf("      %s | %s\n", ref.Site, ref.Username)
+			}
+		}
+	}
+	if len(report.Weak) > 0 {
+		fmt.Printf("\n弱密码 (%d):\n", len(report.Weak))
+		for _, w := range report.Weak {
+			line := fmt.Sprintf("  - %s | %s  [%s]", w.Site, w.Username, w.Label)
+			if w.Warning != "" {
+				line += "  " + w.Warning
+			}
+			fmt.Println(line)
+		}
+	}
+	if len(report.Old) > 0 {
+		fmt.Printf("\n超过 %d 个月未修改 (%d):\n", *maxAge, len(report.Old))
+		for _, o := range report.Old {
+			fmt.Printf("  - %s | %s  最后修改 %s (%d 天前)\n",
+				o.Site, o.Username, o.UpdatedAt.Local().Format("2006-01-02"), o.AgeDays)
+		}
+	}
+	if len(report.Duplicates) > 0 {
+		fmt.Printf("\n重复条目 (%d 组):\n", len(report.Duplicates))
+		for _, d := range report.Duplicates {
+			fmt.Printf("  - %s | %s  出现 %d 次\n", d.Site, d.Username, len(d.Items))
+		}
+	}
+	return nil
+}
+
+// cmdBreach key-box breach check|build-index
+func cmdBreach(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("用法: key-box breach check [--data 路径] [--json] | key-box breach build-index <原始文本> <索引>")
+	}
+	switch args[0] {
+	case "check":
+		return cmdBreachCheck(a, args[1:])
+	case "build-index":
+		return cmdBreachBuildIndex(args[1:])
+	default:
+		return fmt.Errorf("unknown breach command %q", args[0])
+	}
+}
+
+// cmdBreachCheck 解密全部条目并在本地泄露数据中查找，只输出网站、用户名和出现次数。
+func cmdBreachCheck(a *app, args []string) error {
+	settings, _ := config.LoadSettings()
+
+	fs := flag.NewFlagSet("breach check", flag.ContinueOnError)
+	data := fs.String("data", settings.BreachDataPath, "HIBP 数据: 原始文本、范围文件目录或 build-index 生成的索引")
+	asJSON := fs.Bool("json", false, "以 JSON 输出结果")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *data == "" {
+		return errors.New("请用 --data 指定本地 HIBP 数据 (也可在 GUI「体检」中设置)")
+	}
+
+	// 先确认数据可用，再要求输入登录信息
+	src, err := breach.Open(*data)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "正在检查 %d 个条目...\n", len(items))
+	exposed, err := breach.CheckItems(src, items)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if exposed == nil {
+			exposed = []breach.Exposed{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(exposed)
+	}
+	if len(exposed) == 0 {
+		fmt.Println("没有发现出现在泄露数据中的密码。")
+		return nil
+	}
+	fmt.Printf("%d 个条目的密码出现在泄露数据中，请尽快修改:\n", len(exposed))
+	for _, e := range exposed {
+		fmt.Printf("  - %s | %s  出现 %d 次\n", e.Site, e.Username, e.Count)
+	}
+	return nil
+}
+
+// cmdBreachBuildIndex 先写入临时文件，完成后再重命名，中断时不会留下不完整的索引。
+func cmdBreachBuildIndex(args []string) error {
+	if len(args) != 2 {
+		return errors.New("用法: key-box breach build-index <pwned-passwords-sha1-ordered-by-hash.txt> <输出索引>")
+	}
+	in, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := args[1] + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	n, err := breach.BuildIndex(in, out, func(n int64) {
+		fmt.Fprintf(os.Stderr, "\r已处理 %d 条", n)
+	})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, args[1]); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\r索引已生成: %s (%d 条记录)\n", args[1], n)
+	return nil
+}
+
+// pickItem 按网站或账号关键字 (不区分大小写) 查找条目。
+// 网站名完全匹配的条目优先；有多个候选时提示选择。
+func (a *app) pickItem(items []vault.VaultItem, keyword string) (*vault.VaultItem, error) {
+	kw := strings.ToLower(keyword)
+	var matches []vault.VaultItem
+	for _, item := range items {
+		if strings.EqualFold(item.Site, keyword) {
+			return &item, nil
+		}
+		if strings.Contains(strings.ToLower(item.Site), kw) || strings.Contains(strings.ToLower(item.Username), kw) {
+			matches = append(matches, item)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("没有与 %q 匹配的条目", keyword)
+	case 1:
+		return &matches[0], nil
+	}
+
+	for i, item := range matches {
+		fmt.Fprintf(os.Stderr, "[%d] %s | %s\n", i+1, item.Site, formatAccount(item))
+	}
+	n, err := strconv.Atoi(a.prompt("选择编号: "))
+	if err != nil || n < 1 || n > len(matches) {
+		return nil, errors.New("无效的编号")
+	}
+	return &matches[n-1], nil
+}
+
+// waitAndClear 等待超时或 Ctrl+C，然后在剪贴板内容仍是 secret 时清除。
+// X11 后端由本进程直接提供剪贴板内容，因此等待期间进程需要保持运行；
+// 剪贴板被其他内容替换后无需再等待，立即退出。
+func waitAndClear(cb clipboard.Clipboard, secret string, d time.Duration) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	var timeout <-chan time.Time
+	if d > 0 {
+		timeout = time.After(d)
+		fmt.Fprintf(os.Stderr, "密码已复制到剪贴板 (%s)，%s 后自动清除，按 Ctrl+C 立即清除。\n", cb.Name(), d)
+	} else {
+		fmt.Fprintf(os.Stderr, "密码已复制到剪贴板 (%s)，按 Ctrl+C 清除并退出。\n", cb.Name())
+	}
+
+	if !cb.Sensitive() {
+		fmt.Fprintln(os.Stderr, "注意: 当前剪贴板后端无法将内容标记为敏感，剪贴板管理器可能会记录这个密码。")
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			if held, err := cb.Holds(secret); err == nil && !held {
+				fmt.Fprintln(os.Stderr, "剪贴板内容已被替换，无需清除。")
+				return nil
+			}
+		case <-timeout:
+			break wait
+		case <-sig:
+			break wait
+		}
+	}
+
+	cleared, err := clipboard.ClearIfHeld(cb, secret)
+	if err != nil {
+		return fmt.Errorf("清除剪贴板失败: %v", err)
+	}
+	if cleared {
+		fmt.Fprintln(os.Stderr, "剪贴板已清除。")
+	}
+	return nil
+}
+
+// cmdGenerate key-box generate [flags]
+// 密码输出到 stdout (每行一个)，熵输出到 stderr。
+func cmdGenerate(args []string) error {
+	def := generator.DefaultOptions()
+	defPhrase := generator.DefaultPassphraseOptions()
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	length := fs.Int("length", def.Length, fmt.Sprintf("密码长度 (%d-%d)", generator.MinLength, generator.MaxLength))
+	lower := fs.Bool("lower", def.Lower, "包含小写字母")
+	upper := fs.Bool("upper", def.Upper, "包含大写字母")
+	digits := fs.Bool("digits", def.Digits, "包含数字")
+	symbols := fs.Bool("symbols", def.Symbols, "包含符号")
+	noAmbiguous := fs.Bool("exclude-ambiguous", false, "排除易混淆字符 ("+generator.AmbiguousChars+")")
+	minLower := fs.Int("min-lower", def.MinLower, "小写字母最少个数")
+	minUpper := fs.Int("min-upper", def.MinUpper, "大写字母最少个数")
+	minDigits := fs.Int("min-digits", def.MinDigits, "数字最少个数")
+	minSymbols := fs.Int("min-symbols", def.MinSymbols, "符号最少个数")
+	phrase := fs.Bool("passphrase", false, "生成 EFF 词表口令而不是字符密码")
+	words := fs.Int("words", defPhrase.Words, "口令单词数")
+	sep := fs.String("separator", defPhrase.Separator, "口令单词分隔符")
+	capitalize := fs.Bool("capitalize", false, "口令单词首字母大写")
+	digit := fs.Bool("digit", false, "口令中追加一位随机数字")
+	count := fs.Int("count", 1, "生成数量")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var entropy float64
+	for i := 0; i < *count; i++ {
+		var res *generator.Result
+		var err error
+		if *phrase {
+			res, err = generator.Passphrase(generator.PassphraseOptions{
+				Words:      *words,
+				Separator:  *sep,
+				Capitalize: *capitalize,
+				Digit:      *digit,
+			})
+		} else {
+			res, err = generator.Generate(generator.Options{
+				Length:           *length,
+				Lower:            *lower,
+				Upper:            *upper,
+				Digits:           *digits,
+				Symbols:          *symbols,
+				ExcludeAmbiguous: *noAmbiguous,
+				MinLower:         *minLower,
+				MinUpper:         *minUpper,
+				MinDigits:        *minDigits,
+				MinSymbols:       *minSymbols,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(res.Password)
+		entropy = res.Entropy
+	}
+	fmt.Fprintf(os.Stderr, "熵: %.1f 比特\n", entropy)
+	return nil
+}
+
+// cmdStrength key-box strength [--user-inputs a,b] [password]
+// 省略密码时从标准输入读取一行，避免密码留在 shell 历史中。
+func cmdStrength(a *app, args []string) error {
+	fs := flag.NewFlagSet("strength", flag.ContinueOnError)
+	userInputs := fs.String("user-inputs", "", "与密码相关的信息 (网站名、用户名等)，逗号分隔")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var password string
+	switch fs.NArg() {
+	case 0:
+		fmt.Fprint(os.Stderr, "密码: ")
+		a.scanner.Scan()
+		password = a.scanner.Text()
+	case 1:
+		password = fs.Arg(0)
+	default:
+		return errors.New("usage: key-box strength [--user-inputs a,b] [password]")
+	}
+	if password == "" {
+		return errors.New("密码不能为空")
+	}
+
+	var inputs []string
+	if *userInputs != "" {
+		inputs = strings.Split(*userInputs, ",")
+	}
+	res := strength.Estimate(password, inputs...)
+
+	fmt.Printf("强度: %d/4 (%s)\n", res.Score, strength.ScoreLabel(res.Score))
+	fmt.Printf("猜测次数: 10^%.1f\n", res.GuessesLog10)
+	fmt.Println("预计破解时间:")
+	fmt.Printf("  在线 (限速 100 次/小时):   %s\n", strength.DisplayTime(res.CrackTimes.OnlineThrottled))
+	fmt.Printf("  在线 (不限速 10 次/秒):    %s\n", strength.DisplayTime(res.CrackTimes.OnlineUnthrottled))
+	fmt.Printf("  离线 (慢哈希 1万次/秒):    %s\n", strength.DisplayTime(res.CrackTimes.OfflineSlowHash))
+	fmt.Printf("  离线 (快哈希 100亿次/秒):  %s\n", strength.DisplayTime(res.CrackTimes.OfflineFastHash))
+	if res.Warning != "" {
+		fmt.Printf("警告: %s\n", res.Warning)
+	}
+	if len(res.Suggestions) > 0 {
+		fmt.Println("建议:")
+		for _, s := range res.Suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
+	fmt.Println("匹配模式:")
+	for _, m := range res.Sequence {
+		detail := string(m.Pattern)
+		if m.Dictionary != "" {
+			detail += "/" + m.Dictionary
+		}
+		fmt.Printf("  %-24q %-24s 10^%.1f\n", m.Token, detail, math.Log10(m.Guesses))
+	}
+	return nil
+}
+
+// cmdTrash key-box trash list|restore|purge
+func cmdTrash(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("用法: key-box trash list|restore <关键字>|purge [--all] [<关键字>]")
+	}
+	switch args[0] {
+	case "list":
+		return cmdTrashList(a, args[1:])
+	case "restore":
+		return cmdTrashRestore(a, args[1:])
+	case "purge":
+		return cmdTrashPurge(a, args[1:])
+	}
+	return fmt.Errorf("unknown trash command %q", args[0])
+}
+
+func cmdTrashList(a *app, args []string) error {
+	if len(args) != 0 {
+		return errors.New("用法: key-box trash list")
+	}
+	username, keyC, err := a.login()
+	if err != nil {
+		return err
+	}
+	items, err := a.vault.ListTrash(username, keyC)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Println("回收站是空的。")
+		return nil
+	}
+	for _, item := range items {
+		fmt.Printf("%s  %s | %s\n", item.DeletedAt.Local().Format("2006-01-02 15:04:05"), item.Site, item.Username)
+	}
+	return nil
+}
+
+func cmdTrashRestore(a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("用法: key-box trash restore <网站或账号关键字>")
+	}
+	username, keyC, item, err := a.pickTrashedItem(args[0])
+	if err != nil {
+		return err
+	}
+	if err := a.vault.RestoreItem(item.ID); err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventItemRestore, item.Site+" (CLI)")
+	fmt.Printf("已还原 %s | %s。\n", item.Site, item.Username)
+	return nil
+}
+
+// cmdTrashPurge key-box trash purge [--all] [<关键字>]
+// 永久删除前需要确认。
+func cmdTrashPurge(a *app, args []string) error {
+	fs := flag.NewFlagSet("trash purge", flag.ContinueOnError)
+	all := fs.Bool("all", false, "清空回收站")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *all == (fs.NArg() == 1) || fs.NArg() > 1 {
+		return errors.New("用法: key-box trash purge --all | key-box trash purge <网站或账号关键字>")
+	}
+
+	if *all {
+		username, keyC, err := a.login()
+		if err != nil {
+			return err
+		}
+		if a.prompt("永久删除回收站中的所有条目及其历史版本，无法恢复！输入 yes 确认: ") != "yes" {
+			return errors.New("已取消")
+		}
+		n, err := a.vault.EmptyTrash(username)
+		if err != nil {
+			return err
+		}
+		a.audit.Append(username, keyC, audit.EventItemPurge, fmt.Sprintf("清空回收站 (%d 个条目) (CLI)", n))
+		fmt.Printf("已永久删除 %d 个条目。\n", n)
+		return nil
+	}
+
+	username, keyC, item, err := a.pickTrashedItem(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if a.prompt(fmt.Sprintf("永久删除 %s | %s 及其历史版本，无法恢复！输入 yes 确认: ", item.Site, item.Username)) != "yes" {
+		return errors.New("已取消")
+	}
+	if err := a.vault.PurgeItem(item.ID); err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventItemPurge, item.Site+" (CLI)")
+	fmt.Printf("已永久删除 %s | %s。\n", item.Site, item.Username)
+	return nil
+}
+
+// pickTrashedItem 登录并按关键字在回收站中查找条目。
+func (a *app) pickTrashedItem(keyword string) (string, []byte, *vault.VaultItem, error) {
+	username, keyC, err := a.login()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	trashed, err := a.vault.ListTrash(username, keyC)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	items := make([]vault.VaultItem, len(trashed))
+	for i, t := range trashed {
+		items[i] = t.VaultItem
+	}
+	item, err := a.pickItem(items, keyword)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return username, keyC, item, nil
+}
+
+// cmdAttach key-box attach [--name 名称] <关键字> <文件>，或 key-box attach --list <关键字>
+// 标准输入用于登录提示，因此附件内容只能来自文件。
+func cmdAttach(a *app, args []string) error {
+	fs := flag.NewFlagSet("attach", flag.ContinueOnError)
+	name := fs.String("name", "", "附件名称 (默认使用文件名)")
+	list := fs.Bool("list", false, "列出条目的附件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		if fs.NArg() != 1 {
+			return errors.New("用法: key-box attach --list <网站或账号关键字>")
+		}
+		_, keyC, item, err := a.loginAndPick(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		attachments, err := a.vault.ListAttachments(keyC, item.ID)
+		if err != nil {
+			return err
+		}
+		if len(attachments) == 0 {
+			fmt.Fprintln(os.Stderr, "没有附件。")
+		}
+		for _, att := range attachments {
+			fmt.Printf("%s  %10d  %s\n", att.CreatedAt.Local().Format("2006-01-02 15:04:05"), att.Size, att.Name)
+		}
+		return nil
+	}
+
+	if fs.NArg() != 2 {
+		return errors.New("用法: key-box attach [--name 名称] <网站或账号关键字> <文件>")
+	}
+	if *name == "" {
+		// 只保留文件名，不把本地路径写入附件元数据
+		*name = filepath.Base(fs.Arg(1))
+	}
+
+	// 先检查文件，再要求输入登录信息
+	f, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s 是目录", fs.Arg(1))
+	}
+	if info.Size() > a.vault.AttachmentLimit {
+		return fmt.Errorf("%w (limit %d MiB)", vault.ErrAttachmentTooLarge, a.vault.AttachmentLimit>>20)
+	}
+
+	username, keyC, item, err := a.loginAndPick(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	att, err := a.vault.AddAttachment(username, keyC, item.ID, *name, f)
+	if err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventAttachAdd, fmt.Sprintf("%s / %s (CLI)", item.Site, att.Name))
+	fmt.Fprintf(os.Stderr, "已将 %s (%d 字节) 添加到 %s。\n", att.Name, att.Size, item.Site)
+	return nil
+}
+
+// cmdDetach key-box detach <关键字> <附件名>
+func cmdDetach(a *app, args []string) error {
+	if len(args) != 2 {
+		return errors.New("用法: key-box detach <网站或账号关键字> <附件名>")
+	}
+	username, keyC, item, att, err := a.pickAttachment(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if a.prompt(fmt.Sprintf("永久删除附件 %s，无法恢复！输入 yes 确认: ", att.Name)) != "yes" {
+		return errors.New("已取消")
+	}
+	if err := a.vault.DeleteAttachment(att.ID); err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventAttachDel, fmt.Sprintf("%s / %s (CLI)", item.Site, att.Name))
+	fmt.Fprintf(os.Stderr, "已删除附件 %s。\n", att.Name)
+	return nil
+}
+
+// cmdCat key-box cat <关键字> <附件名>
+// 内容流式解密到 stdout；认证失败时已输出的部分不可信，以非 0 退出码结束。
+func cmdCat(a *app, args []string) error {
+	if len(args) != 2 {
+		return errors.New("用法: key-box cat <网站或账号关键字> <附件名>")
+	}
+	username, keyC, item, att, err := a.pickAttachment(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(os.Stdout, 1<<16)
+	if err := a.vault.ReadAttachment(keyC, att.ID, w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	a.audit.Append(username, keyC, audit.EventAttachSave, fmt.Sprintf("%s / %s (CLI)", item.Site, att.Name))
+	return nil
+}
+
+// loginAndPick 登录并按关键字查找条目。
+func (a *app) loginAndPick(keyword string) (string, []byte, *vault.VaultItem, error) {
+	username, keyC, err := a.login()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	items, err := a.vault.ListItems(username, keyC)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	item, err := a.pickItem(items, keyword)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return username, keyC, item, nil
+}
+
+// pickAttachment 登录并按条目关键字和附件名查找附件。
+// 附件名完全匹配优先，否则按不区分大小写的部分匹配，有多个候选时提示选择。
+func (a *app) pickAttachment(keyword, name string) (string, []byte, *vault.VaultItem, *vault.Attachment, error) {
+	username, keyC, item, err := a.loginAndPick(keyword)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	attachments, err := a.vault.ListAttachments(keyC, item.ID)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	var matches []vault.Attachment
+	for _, att := range attachments {
+		if att.Name == name {
+			return username, keyC, item, &att, nil
+		}
+		if strings.Contains(strings.ToLower(att.Name), strings.ToLower(name)) {
+			matches = append(matches, att)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", nil, nil, nil, fmt.Errorf("%s 没有与 %q 匹配的附件", item.Site, name)
+	case 1:
+		return username, keyC, item, &matches[0], nil
+	}
+	for i, att := range matches {
+		fmt.Fprintf(os.Stderr, "[%d] %s (%d 字节)\n", i+1, att.Name, att.Size)
+	}
+	n, err := strconv.Atoi(a.prompt("选择编号: "))
+	if err != nil || n < 1 || n > len(matches) {
+		return "", nil, nil, nil, errors.New("无效的编号")
+	}
+	return username, keyC, item, &matches[n-1], nil
+}
@@ -6,10 +6,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
+	"key-box/internal/audit"
 	"key-box/internal/auth"
 	"key-box/internal/config"
+	"key-box/internal/crypto"
 	"key-box/internal/db"
 	"key-box/internal/vault"
 )
@@ -46,17 +49,38 @@ func main() {
 		os.Exit(1)
 	}
 
-	authService := auth.NewService(database)
-	vaultManager := vault.NewManager(database)
 	scanner := bufio.NewScanner(os.Stdin)
 
+	// 3. Root Key Provider (KEY_BOX_ROOT_KEY_PROVIDER)
+	rootKeys, err := crypto.NewRootKeyProvider(config.GetRootKeyProvider(), func() (string, error) {
+		fmt.Print("Root Key 口令: ")
+		if !scanner.Scan() {
+			return "", fmt.Errorf("no passphrase entered")
+		}
+		return strings.TrimSpace(scanner.Text()), nil
+	})
+	if err != nil {
+		fmt.Printf("Error initializing root key provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	authService := auth.NewService(database, rootKeys)
+	vaultManager := vault.NewManager(database)
+	auditLog := audit.NewLogger(database)
+
+	// 4. 子命令模式 (key-box <command>)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(&app{scanner: scanner, auth: authService, vault: vaultManager, audit: auditLog}, os.Args[1:]))
+	}
+
 	for {
 		fmt.Println("\n=== 本地密码管理器 (Local Password Manager) ===")
 		fmt.Println("1. 注册 (Register)")
 		fmt.Println("2. 登录 (Login)")
 		fmt.Println("3. 重置密码 (Reset Password)")
-		fmt.Println("4. 退出 (Exit)")
-		fmt.Print("请选择 (1-4): ")
+		fmt.Println("4. 使用恢复码 (Recovery Code)")
+		fmt.Println("5. 退出 (Exit)")
+		fmt.Print("请选择 (1-5): ")
 
 		if !scanner.Scan() {
 			break
@@ -67,10 +91,12 @@ func main() {
 		case "1":
 			handleRegister(scanner, authService)
 		case "2":
-			handleLogin(scanner, authService, vaultManager)
+			handleLogin(scanner, authService, vaultManager, auditLog)
 		case "3":
 			handleReset(scanner, authService)
 		case "4":
+			handleRedeemRecoveryCode(scanner, authService)
+		case "5":
 			fmt.Println("Bye!")
 			return
 		default:
@@ -98,23 +124,18 @@ func handleRegister(scanner *bufio.Scanner, s *auth.Service) {
 	scanner.Scan()
 	username := strings.TrimSpace(scanner.Text())
 
-	fmt.Print("密保问题 1: ")
-	scanner.Scan()
-	q1 := strings.TrimSpace(scanner.Text())
+	printQuestionPool()
+	q1 := readPoolQuestion(scanner, "密保问题 1")
 	fmt.Print("答案 1: ")
 	scanner.Scan()
 	a1 := strings.TrimSpace(scanner.Text())
 
-	fmt.Print("密保问题 2: ")
-	scanner.Scan()
-	q2 := strings.TrimSpace(scanner.Text())
+	q2 := readPoolQuestion(scanner, "密保问题 2")
 	fmt.Print("答案 2: ")
 	scanner.Scan()
 	a2 := strings.TrimSpace(scanner.Text())
 
-	fmt.Print("密保问题 3: ")
-	scanner.Scan()
-	q3 := strings.TrimSpace(scanner.Text())
+	q3 := readPoolQuestion(scanner, "密保问题 3")
 	fmt.Print("答案 3: ")
 	scanner.Scan()
 	a3 := strings.TrimSpace(scanner.Text())
@@ -131,9 +152,19 @@ func handleRegister(scanner *bufio.Scanner, s *auth.Service) {
 	fmt.Printf("Secret Key (Base32): %s\n", res.SecretKeyBBase32)
 	fmt.Println("----------------------------------------------------------------")
 	fmt.Println("您也可以手动输入上述 Key 到 App 中。")
+	printRecoveryCodes(res.RecoveryCodes)
+}
+
+func printRecoveryCodes(codes []string) {
+	fmt.Println("\n一次性恢复码 (每个只能使用一次，可代替密保答案重置 Key B，请离线保存):")
+	fmt.Println("----------------------------------------------------------------")
+	for i, code := range codes {
+		fmt.Printf("%2d. %s\n", i+1, code)
+	}
+	fmt.Println("----------------------------------------------------------------")
 }
 
-func handleLogin(scanner *bufio.Scanner, s *auth.Service, v *vault.Manager) {
+func handleLogin(scanner *bufio.Scanner, s *auth.Service, v *vault.Manager, l *audit.Logger) {
 	if autoGeneratedSalt != "" {
 		fmt.Println("\n[Warning] 未检测到 Salt 配置。")
 		fmt.Printf("已自动生成 Salt: %s\n", autoGeneratedSalt)
@@ -155,22 +186,32 @@ func handleLogin(scanner *bufio.Scanner, s *auth.Service, v *vault.Manager) {
 	scanner.Scan()
 	otp := strings.TrimSpace(scanner.Text())
 
-	keyC, err := s.Login(username, otp)
+	fmt.Print("主密码 (未启用请直接回车): ")
+	scanner.Scan()
+	passphrase := scanner.Text()
+
+	keyC, err := s.Login(username, otp, passphrase)
 	if err != nil {
 		fmt.Printf("登录失败: %v\n", err)
 		return
 	}
 
 	fmt.Println("登录成功! 进入密码库...")
-	handleVault(scanner, v, username, keyC)
+	purgeExpiredTrash(v, l, username, keyC)
+	handleVault(scanner, s, v, l, username, keyC)
 }
 
-func handleVault(scanner *bufio.Scanner, v *vault.Manager, username string, keyC []byte) {
+func handleVault(scanner *bufio.Scanner, s *auth.Service, v *vault.Manager, l *audit.Logger, username string, keyC []byte) {
 	for {
 		fmt.Printf("\n=== 密码库 (%s) ===\n", username)
 		fmt.Println("1. 查看所有密码 (List)")
 		fmt.Println("2. 添加密码 (Add)")
-		fmt.Println("3. 退出登录 (Logout)")
+		fmt.Println("3. 主密码设置 (Passphrase)")
+		fmt.Println("4. 恢复码 (Recovery Codes)")
+		fmt.Println("5. 修改密保问题 (Security Questions)")
+		fmt.Println("6. 注销账户 (Delete Account)")
+		fmt.Println("7. 修改用户名 (Rename)")
+		fmt.Println("8. 退出登录 (Logout)")
 		fmt.Print("请选择: ")
 
 		if !scanner.Scan() {
@@ -186,8 +227,13 @@ func handleVault(scanner *bufio.Scanner, v *vault.Manager, username string, keyC
 			} else {
 				fmt.Println("\n[存储的密码]")
 				for _, item := range items {
-					fmt.Printf("ID: %d | Site: %s | User: %s | Pass: %s\n", item.ID, item.Site, item.Username, item.Password)
+					if item.IsNote() {
+						fmt.Printf("ID: %d | Note: %s%s\n%s\n", item.ID, item.Site, formatOrganize(item), item.Notes)
+						continue
+					}
+					fmt.Printf("ID: %d | Site: %s | User: %s | Pass: %s%s\n", item.ID, item.Site, item.Username, item.Password, formatOrganize(item))
 				}
+				l.Append(username, keyC, audit.EventItemView, fmt.Sprintf("listed %d items (CLI)", len(items)))
 			}
 		case "2":
 			fmt.Print("网站/应用名: ")
@@ -199,13 +245,38 @@ func handleVault(scanner *bufio.Scanner, v *vault.Manager, username string, keyC
 			fmt.Print("密码: ")
 			scanner.Scan()
 			p := strings.TrimSpace(scanner.Text())
+			fmt.Print("文件夹 (如 工作/服务器，可留空): ")
+			scanner.Scan()
+			folder := scanner.Text()
+			fmt.Print("标签 (逗号分隔，可留空): ")
+			scanner.Scan()
+			tags := vault.ParseTags(scanner.Text())
+			fmt.Print("网址 (按基础域名匹配，可留空): ")
+			scanner.Scan()
+			uris := vault.NormalizeURIs([]vault.URI{{URI: scanner.Text()}})
 
-			if err := v.AddItem(username, keyC, site, u, p); err != nil {
+			item := vault.VaultItem{Site: site, Username: u, Password: p, Folder: folder, Tags: tags, URIs: uris}
+			if err := v.AddItem(username, keyC, item); err != nil {
 				fmt.Printf("添加失败: %v\n", err)
 			} else {
+				l.Append(username, keyC, audit.EventItemAdd, site)
 				fmt.Println("添加成功!")
 			}
 		case "3":
+			handlePassphrase(scanner, s, username)
+		case "4":
+			handleRecoveryCodes(scanner, s, username)
+		case "5":
+			handleChangeSecurityQuestions(scanner, s, username)
+		case "6":
+			if handleDeleteAccount(scanner, s, username) {
+				return
+			}
+		case "7":
+			if newName := handleRenameUser(scanner, s, username); newName != "" {
+				username = newName
+			}
+		case "8":
 			return
 		default:
 			fmt.Println("无效选项")
@@ -249,4 +320,262 @@ func handleReset(scanner *bufio.Scanner, s *auth.Service) {
 	fmt.Println("重置成功!")
 	fmt.Println("新的最高权限恢复凭证 (Key B):")
 	fmt.Printf("Secret Key (Base32): %s\n", res.SecretKeyBBase32)
+	fmt.Println("注意: 如之前启用了主密码，重置后主密码已关闭，可登录后重新启用。")
+}
+
+func handleRedeemRecoveryCode(scanner *bufio.Scanner, s *auth.Service) {
+	fmt.Println("\n--- 使用恢复码重置 ---")
+	fmt.Print("用户名: ")
+	scanner.Scan()
+	username := strings.TrimSpace(scanner.Text())
+
+	fmt.Print("恢复码: ")
+	scanner.Scan()
+	code := strings.TrimSpace(scanner.Text())
+
+	res, err := s.RedeemRecoveryCode(username, code)
+	if err != nil {
+		fmt.Printf("重置失败: %v\n", err)
+		return
+	}
+
+	remaining, _ := s.RemainingRecoveryCodes(username)
+	fmt.Println("重置成功! 该恢复码已失效。")
+	fmt.Println("新的最高权限恢复凭证 (Key B):")
+	fmt.Printf("Secret Key (Base32): %s\n", res.SecretKeyBBase32)
+	fmt.Printf("剩余可用恢复码: %d 个\n", remaining)
+	fmt.Println("注意: 如之前启用了主密码，重置后主密码已关闭，可登录后重新启用。")
+}
+
+func handleRecoveryCodes(scanner *bufio.Scanner, s *auth.Service, username string) {
+	remaining, err := s.RemainingRecoveryCodes(username)
+	if err != nil {
+		fmt.Printf("查询失败: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- 恢复码 ---")
+	fmt.Printf("剩余可用恢复码: %d / %d 个\n", remaining, auth.RecoveryCodeCount)
+	fmt.Println("1. 重新生成 (旧恢复码全部作废)")
+	fmt.Println("0. 返回")
+	fmt.Print("请选择: ")
+	scanner.Scan()
+	if strings.TrimSpace(scanner.Text()) != "1" {
+		return
+	}
+
+	fmt.Print("请输入 6 位 OTP 验证码: ")
+	scanner.Scan()
+	otp := strings.TrimSpace(scanner.Text())
+	fmt.Print("主密码 (未启用请直接回车): ")
+	scanner.Scan()
+	passphrase := scanner.Text()
+
+	codes, err := s.RegenerateRecoveryCodes(username, otp, passphrase)
+	if err != nil {
+		fmt.Printf("生成失败: %v\n", err)
+		return
+	}
+	printRecoveryCodes(codes)
+}
+
+// printQuestionPool 列出可选的密保问题
+func printQuestionPool() {
+	fmt.Println("可选的密保问题:")
+	for i, q := range auth.SecurityQuestionPool {
+		fmt.Printf("%2d. %s\n", i+1, q)
+	}
+}
+
+// readPoolQuestion 读取密保问题编号，返回对应的问题 (编号无效时返回空，由服务层拒绝)
+func readPoolQuestion(scanner *bufio.Scanner, prompt string) string {
+	fmt.Printf("%s (输入编号): ", prompt)
+	scanner.Scan()
+	n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil || n < 1 || n > len(auth.SecurityQuestionPool) {
+		return ""
+	}
+	return auth.SecurityQuestionPool[n-1]
+}
+
+func handleChangeSecurityQuestions(scanner *bufio.Scanner, s *auth.Service, username string) {
+	fmt.Println("\n--- 修改密保问题 ---")
+	fmt.Println("身份验证方式:")
+	fmt.Println("1. 当前密保答案")
+	fmt.Println("2. OTP 验证码")
+	fmt.Print("请选择: ")
+	scanner.Scan()
+
+	var proof auth.SecurityProof
+	switch strings.TrimSpace(scanner.Text()) {
+	case "1":
+		qs, err := s.GetSecurityQuestions(username)
+		if err != nil {
+			fmt.Printf("查询失败: %v\n", err)
+			return
+		}
+		for i, q := range qs {
+			fmt.Printf("当前问题 %d: %s\n答案: ", i+1, q)
+			scanner.Scan()
+			proof.Answers = append(proof.Answers, strings.TrimSpace(scanner.Text()))
+		}
+	case "2":
+		fmt.Print("请输入 6 位 OTP 验证码: ")
+		scanner.Scan()
+		proof.Code = strings.TrimSpace(scanner.Text())
+		fmt.Print("主密码 (未启用请直接回车): ")
+		scanner.Scan()
+		proof.Passphrase = scanner.Text()
+	default:
+		return
+	}
+
+	printQuestionPool()
+	questions := make([]string, 3)
+	answers := make([]string, 3)
+	for i := range questions {
+		questions[i] = readPoolQuestion(scanner, fmt.Sprintf("新密保问题 %d", i+1))
+		fmt.Printf("新答案 %d: ", i+1)
+		scanner.Scan()
+		answers[i] = strings.TrimSpace(scanner.Text())
+	}
+
+	if err := s.ChangeSecurityQuestions(username, proof, questions, answers); err != nil {
+		fmt.Printf("修改失败: %v\n", err)
+		return
+	}
+	fmt.Println("密保问题已修改，旧答案已失效。Key B、主密码和恢复码保持不变。")
+}
+
+// handleRenameUser 修改用户名，成功时返回新用户名
+func handleRenameUser(scanner *bufio.Scanner, s *auth.Service, username string) string {
+	fmt.Println("\n--- 修改用户名 ---")
+	fmt.Println("注意: 修改后将生成新的 Key B，需要在 TOTP 应用中重新绑定。")
+	fmt.Print("新用户名: ")
+	scanner.Scan()
+	newName := strings.TrimSpace(scanner.Text())
+
+	fmt.Print("请输入 6 位 OTP 验证码: ")
+	scanner.Scan()
+	otp := strings.TrimSpace(scanner.Text())
+	fmt.Print("主密码 (未启用请直接回车): ")
+	scanner.Scan()
+	passphrase := scanner.Text()
+
+	res, err := s.RenameUser(username, newName, otp, passphrase)
+	if err != nil {
+		fmt.Printf("修改失败: %v\n", err)
+		return ""
+	}
+
+	fmt.Printf("用户名已修改为 '%s'，旧的 Key B 已失效。\n", newName)
+	fmt.Println("新的最高权限恢复凭证 (Key B):")
+	fmt.Printf("Secret Key (Base32): %s\n", res.SecretKeyBBase32)
+	fmt.Printf("otpauth URI: %s\n", res.OTPAuthURI)
+	return newName
+}
+
+// handleDeleteAccount 注销账户，返回 true 表示账户已删除
+func handleDeleteAccount(scanner *bufio.Scanner, s *auth.Service, username string) bool {
+	counts, err := s.AccountDataCounts(username)
+	if err != nil {
+		fmt.Printf("读取失败: %v\n", err)
+		return false
+	}
+
+	fmt.Println("\n--- 注销账户 ---")
+	fmt.Printf("将永久删除账户 '%s' 及其 %s、恢复码等全部数据，且无法恢复！\n", username, describeUserData(counts))
+	fmt.Print("请输入用户名确认: ")
+	scanner.Scan()
+	if strings.TrimSpace(scanner.Text()) != username {
+		fmt.Println("用户名不匹配，已取消")
+		return false
+	}
+
+	fmt.Print("请输入 6 位 OTP 验证码: ")
+	scanner.Scan()
+	otp := strings.TrimSpace(scanner.Text())
+	fmt.Print("主密码 (未启用请直接回车): ")
+	scanner.Scan()
+	passphrase := scanner.Text()
+
+	deleted, err := s.DeleteAccount(username, otp, passphrase)
+	if err != nil {
+		fmt.Printf("注销失败: %v\n", err)
+		return false
+	}
+	fmt.Printf("账户 '%s' 已删除，共擦除 %s。\n", username, describeUserData(deleted))
+	return true
+}
+
+// describeUserData 账户数据统计的文字说明
+func describeUserData(c db.UserDataCounts) string {
+	return fmt.Sprintf("%d 条密码记录、回收站中 %d 条、%d 个历史版本、%d 个附件", c.Items, c.Trashed, c.History, c.Attachments)
+}
+
+func handlePassphrase(scanner *bufio.Scanner, s *auth.Service, username string) {
+	enabled, err := s.PassphraseEnabled(username)
+	if err != nil {
+		fmt.Printf("查询失败: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- 主密码设置 ---")
+	if enabled {
+		fmt.Println("当前状态: 已启用 (登录需要 OTP + 主密码)")
+		fmt.Println("1. 修改主密码")
+		fmt.Println("2. 关闭主密码")
+	} else {
+		fmt.Println("当前状态: 未启用 (登录仅需 OTP)")
+		fmt.Println("1. 启用主密码")
+	}
+	fmt.Println("0. 返回")
+	fmt.Print("请选择: ")
+	scanner.Scan()
+	choice := strings.TrimSpace(scanner.Text())
+	if choice != "1" && !(enabled && choice == "2") {
+		return
+	}
+
+	fmt.Print("请输入 6 位 OTP 验证码: ")
+	scanner.Scan()
+	otp := strings.TrimSpace(scanner.Text())
+
+	switch {
+	case !enabled:
+		fmt.Printf("新主密码 (至少 %d 位): ", auth.MinPassphraseLength)
+		scanner.Scan()
+		newPass := scanner.Text()
+		fmt.Print("确认新主密码: ")
+		scanner.Scan()
+		if scanner.Text() != newPass {
+			fmt.Println("两次输入的主密码不一致")
+			return
+		}
+		err = s.EnablePassphrase(username, otp, newPass)
+	case choice == "1":
+		fmt.Print("当前主密码: ")
+		scanner.Scan()
+		oldPass := scanner.Text()
+		fmt.Printf("新主密码 (至少 %d 位): ", auth.MinPassphraseLength)
+		scanner.Scan()
+		newPass := scanner.Text()
+		fmt.Print("确认新主密码: ")
+		scanner.Scan()
+		if scanner.Text() != newPass {
+			fmt.Println("两次输入的主密码不一致")
+			return
+		}
+		err = s.ChangePassphrase(username, otp, oldPass, newPass)
+	default:
+		fmt.Print("当前主密码: ")
+		scanner.Scan()
+		err = s.DisablePassphrase(username, otp, scanner.Text())
+	}
+
+	if err != nil {
+		fmt.Printf("操作失败: %v\n", err)
+		return
+	}
+	fmt.Println("操作成功! 下次登录请按新的设置输入。")
 }
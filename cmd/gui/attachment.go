@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+	"key-box/internal/vault"
+)
+
+// showAttachmentsDialog 条目的附件: 添加文件、另存为、删除。
+// 加密和解密按块流式进行，在后台执行，大文件不会整体读入内存。
+func showAttachmentsDialog(item vault.VaultItem) {
+	list := container.NewVBox()
+	progress := widget.NewProgressBarInfinite()
+	progress.Hide()
+	status := widget.NewLabel(fmt.Sprintf("单个附件最大 %s，内容使用独立的文件密钥分块加密。", formatSize(vaultManager.AttachmentLimit)))
+
+	var btnAdd *widget.Button
+	// busy 后台加解密期间禁用按钮，done 在界面线程中调用
+	busy := func(text string) {
+		btnAdd.Disable()
+		progress.Show()
+		progress.Start()
+		status.SetText(text)
+	}
+	done := func(text string) {
+		progress.Stop()
+		progress.Hide()
+		btnAdd.Enable()
+		status.SetText(text)
+	}
+
+	var refresh func()
+	refresh = func() {
+		attachments, err := vaultManager.ListAttachments(currentKeyC, item.ID)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("读取附件失败: %v", err), myWindow)
+			return
+		}
+
+		list.RemoveAll()
+		if len(attachments) == 0 {
+			list.Add(container.NewCenter(widget.NewLabelWithStyle("没有附件", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})))
+		}
+		for _, att := range attachments {
+			att := att
+
+			btnSave := widget.NewButtonWithIcon("另存为...", theme.DocumentSaveIcon(), func() {
+				d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+					if err != nil || w == nil {
+						return
+					}
+					busy(fmt.Sprintf("正在解密「%s」...", att.Name))
+					keyC := sessionKeyCopy()
+					go func() {
+						err := vaultManager.ReadAttachment(keyC, att.ID, w)
+						wipe(keyC)
+						if cerr := w.Close(); err == nil {
+							err = cerr
+						}
+						if err != nil {
+							// 不保留解密了一半的文件
+							storage.Delete(w.URI())
+						}
+						fyne.Do(func() {
+							if err != nil {
+								done(fmt.Sprintf("⚠️ 导出失败: %v", err))
+								return
+							}
+							logEvent(audit.EventAttachSave, fmt.Sprintf("%s / %s", item.Site, att.Name))
+							done(fmt.Sprintf("已导出「%s」", att.Name))
+						})
+					}()
+				}, myWindow)
+				d.SetFileName(att.Name)
+				d.Show()
+			})
+			btnDelete := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("删除附件", fmt.Sprintf("永久删除附件「%s」？此操作无法撤销。", att.Name), func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := vaultManager.DeleteAttachment(att.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("删除失败: %v", err), myWindow)
+						return
+					}
+					logEvent(audit.EventAttachDel, fmt.Sprintf("%s / %s", item.Site, att.Name))
+					refresh()
+				}, myWindow)
+			})
+			btnDelete.Importance = widget.DangerImportance
+
+			label := widget.NewLabel(fmt.Sprintf("📎 %s  —  %s，%s", att.Name, formatSize(att.Size), att.CreatedAt.Local().Format("2006-01-02 15:04")))
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(btnSave, btnDelete), label))
+		}
+	}
+
+	btnAdd = widget.NewButtonWithIcon("添加附件...", theme.ContentAddIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if err != nil || r == nil {
+				return
+			}
+			name := r.URI().Name()
+			busy(fmt.Sprintf("正在加密「%s」...", name))
+			username, keyC := currentUser, sessionKeyCopy()
+			go func() {
+				_, err := vaultManager.AddAttachment(username, keyC, item.ID, name, r)
+				wipe(keyC)
+				r.Close()
+				fyne.Do(func() {
+					if err != nil {
+						done(fmt.Sprintf("⚠️ 添加失败: %v", err))
+						return
+					}
+					logEvent(audit.EventAttachAdd, fmt.Sprintf("%s / %s", item.Site, name))
+					done(fmt.Sprintf("已添加「%s」", name))
+					// 添加期间会话被锁定时不再刷新
+					if currentKeyC != nil {
+						refresh()
+					}
+				})
+			}()
+		}, myWindow)
+	})
+	refresh()
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil, btnAdd, widget.NewLabel(fmt.Sprintf("「%s」的附件", item.Site))),
+			widget.NewSeparator(),
+		),
+		container.NewVBox(progress, status),
+		nil, nil,
+		container.NewVScroll(list),
+	)
+
+	d := dialog.NewCustom("附件", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(640, 420))
+	d.Show()
+}
+
+// sessionKeyCopy 复制 Key C 供后台任务使用: 锁定会清零 currentKeyC，
+// 任务进行到一半时密钥被清零会用全零密钥加密数据。任务结束后用 wipe 清零副本。
+func sessionKeyCopy() []byte {
+	return append([]byte(nil), currentKeyC...)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// formatSize 以 B / KiB / MiB 显示字节数
+func formatSize(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+)
+
+// logEvent 为当前登录用户追加一条审计事件。
+// 审计写入失败不影响正常操作，完整性问题可在「日志」中校验发现。
+func logEvent(typ audit.EventType, detail string) {
+	if currentUser == "" || currentKeyC == nil {
+		return
+	}
+	touchActivity()
+	auditLog.Append(currentUser, currentKeyC, typ, detail)
+}
+
+// showAuditDialog 显示审计日志 (最新的在前)，并支持完整性校验
+func showAuditDialog() {
+	entries, err := auditLog.List(currentUser, currentKeyC)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取审计日志失败: %v", err), myWindow)
+		return
+	}
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel("0000-00-00 00:00:00"),
+				widget.NewLabelWithStyle("事件类型", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabel("详情"),
+			)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			e := entries[len(entries)-1-id]
+			row := obj.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(e.Time.Local().Format("2006-01-02 15:04:05"))
+			row.Objects[1].(*widget.Label).SetText(e.Type.Label())
+			detail := e.Detail
+			if e.Pending {
+				detail += " (登录前发生)"
+			}
+			row.Objects[2].(*widget.Label).SetText(truncateText(detail, 40))
+		},
+	)
+
+	status := widget.NewLabel(fmt.Sprintf("共 %d 条记录", len(entries)))
+
+	btnVerify := widget.NewButtonWithIcon("校验完整性", theme.ConfirmIcon(), func() {
+		n, err := auditLog.Verify(currentUser, currentKeyC)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("审计日志校验失败 (前 %d 条完好):\n%v", n, err), myWindow)
+			return
+		}
+		dialog.ShowInformation("校验通过", fmt.Sprintf("审计日志完整: 共 %d 条记录，哈希链与链头一致。", n), myWindow)
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("记录登录、重置、密码查看/复制/编辑/删除、备份与恢复等操作。\n日志以 Key C 派生密钥加密并哈希链接，篡改或截断可被校验发现。"),
+			widget.NewSeparator(),
+		),
+		container.NewHBox(status, btnVerify),
+		nil, nil,
+		list,
+	)
+
+	d := dialog.NewCustom("审计日志", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
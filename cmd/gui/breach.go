@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/breach"
+	"key-box/internal/vault"
+)
+
+// showBreachDialog 离线泄露检查: 在本地 HIBP 数据 (原始文本、范围文件目录或索引) 中查找密码库中的密码。
+// 数据路径保存在偏好设置中。原始文本需要顺序读取整个文件，因此查找在后台进行。
+func showBreachDialog(onChanged func()) {
+	labelPath := widget.NewLabel("")
+	setPathText := func() {
+		if settings.BreachDataPath == "" {
+			labelPath.SetText("数据: 未设置")
+		} else {
+			labelPath.SetText("数据: " + settings.BreachDataPath)
+		}
+	}
+	setPathText()
+
+	choose := func(path string) {
+		settings.BreachDataPath = path
+		saveSettings()
+		setPathText()
+	}
+	btnFile := widget.NewButtonWithIcon("选择文件...", theme.FileIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if err != nil || r == nil {
+				return
+			}
+			r.Close()
+			choose(r.URI().Path())
+		}, myWindow)
+	})
+	btnDir := widget.NewButtonWithIcon("选择目录...", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(u fyne.ListableURI, err error) {
+			if err != nil || u == nil {
+				return
+			}
+			choose(u.Path())
+		}, myWindow)
+	})
+
+	progress := widget.NewProgressBarInfinite()
+	progress.Hide()
+	status := widget.NewLabel("")
+	results := container.NewVBox()
+
+	var btnCheck *widget.Button
+	btnCheck = widget.NewButtonWithIcon("开始检查", theme.SearchIcon(), func() {
+		if settings.BreachDataPath == "" {
+			dialog.ShowError(fmt.Errorf("请先选择本地 HIBP 数据"), myWindow)
+			return
+		}
+		items, err := vaultManager.ListItems(currentUser, currentKeyC)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("读取失败: %v", err), myWindow)
+			return
+		}
+
+		btnCheck.Disable()
+		progress.Show()
+		progress.Start()
+		status.SetText(fmt.Sprintf("正在检查 %d 个条目...", len(items)))
+		results.RemoveAll()
+
+		path := settings.BreachDataPath
+		go func() {
+			exposed, err := checkBreach(path, items)
+			fyne.Do(func() {
+				progress.Stop()
+				progress.Hide()
+				btnCheck.Enable()
+				// 检查期间会话被锁定时丢弃结果
+				if currentKeyC == nil {
+					status.SetText("")
+					return
+				}
+				if err != nil {
+					status.SetText(fmt.Sprintf("⚠️ 检查失败: %v", err))
+					return
+				}
+				showBreachResults(status, results, exposed, items, onChanged)
+			})
+		}()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("在本地下载的 Have I Been Pwned 泄露密码数据中查找密码库中的密码，不访问网络。\n支持原始文本 (ordered-by-hash)、范围文件目录，或用 `key-box breach build-index` 生成的索引。"),
+			labelPath,
+			container.NewHBox(btnFile, btnDir, btnCheck),
+			progress,
+			status,
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		container.NewVScroll(results),
+	)
+
+	d := dialog.NewCustom("泄露检查", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(680, 480))
+	d.Show()
+}
+
+func checkBreach(path string, items []vault.VaultItem) ([]breach.Exposed, error) {
+	src, err := breach.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return breach.CheckItems(src, items)
+}
+
+func showBreachResults(status *widget.Label, results *fyne.Container, exposed []breach.Exposed, items []vault.VaultItem, onChanged func()) {
+	if len(exposed) == 0 {
+		status.SetText("✅ 没有发现出现在泄露数据中的密码。")
+		return
+	}
+	status.SetText(fmt.Sprintf("⚠️ %d 个条目的密码出现在泄露数据中，请尽快修改。", len(exposed)))
+
+	byID := make(map[int]vault.VaultItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	for _, e := range exposed {
+		e := e
+		btnEdit := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+			if item, ok := byID[e.ID]; ok {
+				showEditVaultItemDialog(item, onChanged)
+			}
+		})
+		label := widget.NewLabel(fmt.Sprintf("%s | %s  —  出现 %d 次", e.Site, e.Username, e.Count))
+		label.Importance = widget.DangerImportance
+		results.Add(container.NewBorder(nil, nil, nil, btnEdit, label))
+	}
+}
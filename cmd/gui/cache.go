@@ -0,0 +1,117 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"key-box/internal/vault"
+)
+
+// vaultCache 会话内已解密的条目。搜索、筛选和切换选中时直接使用缓存，不再重新解密整个密码库；
+// 条目被修改 (vaultManager.Generation 变化) 或切换用户后重新加载。
+// 安全决策: 锁定和退出时清空缓存，锁定期间内存中不保留解密后的条目。
+var vaultCache struct {
+	username   string
+	generation uint64
+	items      []vault.VaultItem
+	valid      bool
+
+	// 后台加载: loadSeq 标识最近一次加载，旧的加载结果被丢弃；
+	// 加载期间再次请求时只更新该使用方的回调，完成后调用各使用方最新的回调
+	loading  bool
+	loadSeq  int
+	loadUser string
+	loadGen  uint64
+	waiters  map[string]loadCallbacks
+}
+
+// loadCallbacks 一个使用方 (密码库界面、快速访问窗口) 等待加载的回调
+type loadCallbacks struct {
+	onProgress func(done, total int)
+	onLoaded   func(err error)
+}
+
+// cachedItems 返回缓存的条目 (调用方不要修改)，缓存无效时 ok 为 false。
+func cachedItems() (items []vault.VaultItem, ok bool) {
+	c := &vaultCache
+	if !c.valid || c.username != currentUser || c.generation != vaultManager.Generation() {
+		return nil, false
+	}
+	return c.items, true
+}
+
+// loadItems 在后台并行解密当前用户的全部条目并写入缓存。
+// owner 标识使用方，同一使用方多次请求时只保留最新的回调。
+// progress 和 done 在界面线程中调用；加载期间会话被锁定或退出时不调用 done。
+func loadItems(owner string, progress func(done, total int), done func(err error)) {
+	c := &vaultCache
+	if c.waiters == nil {
+		c.waiters = make(map[string]loadCallbacks)
+	}
+	c.waiters[owner] = loadCallbacks{onProgress: progress, onLoaded: done}
+	generation := vaultManager.Generation()
+	if c.loading && c.loadUser == currentUser && c.loadGen == generation {
+		return
+	}
+
+	c.loadSeq++
+	seq := c.loadSeq
+	c.loading, c.loadUser, c.loadGen = true, currentUser, generation
+	username, keyC := currentUser, sessionKeyCopy()
+	go func() {
+		items, err := vaultManager.ListItemsProgress(username, keyC, func(n, total int) {
+			fyne.Do(func() {
+				if seq != c.loadSeq {
+					return
+				}
+				for _, w := range c.waiters {
+					if w.onProgress != nil {
+						w.onProgress(n, total)
+					}
+				}
+			})
+		})
+		wipe(keyC)
+		fyne.Do(func() {
+			if seq != c.loadSeq {
+				return
+			}
+			c.loading = false
+			waiters := c.waiters
+			c.waiters = nil
+			if currentKeyC == nil || currentUser != username {
+				return
+			}
+			if err == nil {
+				c.username, c.generation, c.items, c.valid = username, generation, items, true
+			}
+			for _, w := range waiters {
+				if w.onLoaded != nil {
+					w.onLoaded(err)
+				}
+			}
+		})
+	}()
+}
+
+// touchCachedItem 更新缓存中条目的最近使用时间。
+// 记录使用时间不改变 vaultManager.Generation (否则每次查看或复制都要重新解密整个密码库)，由这里同步到缓存。
+func touchCachedItem(id int, now time.Time) {
+	c := &vaultCache
+	for i := range c.items {
+		if c.items[i].ID == id {
+			c.items[i].UsedAt = now
+			return
+		}
+	}
+}
+
+// clearItemCache 清空缓存并丢弃正在进行的加载 (锁定、退出时调用)。
+func clearItemCache() {
+	c := &vaultCache
+	c.items, c.valid = nil, false
+	c.loading = false
+	c.loadSeq++
+	c.waiters = nil
+}
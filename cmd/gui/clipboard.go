@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/clipboard"
+)
+
+var (
+	// secretClipboard 复制密码等敏感内容使用的剪贴板 (带密码管理器提示)
+	secretClipboard clipboard.Clipboard
+
+	// clipboardSecret 最近一次复制的敏感内容，等待自动清除
+	clipboardSecret string
+	clipboardTimer  *time.Timer
+)
+
+// unprotectedClipboardNote 剪贴板后端无法附带敏感内容提示时给用户的警告
+const unprotectedClipboardNote = "注意: 当前剪贴板后端无法将内容标记为敏感，剪贴板管理器可能会记录复制的密码"
+
+// initClipboard 选择敏感内容剪贴板后端，没有可用后端时退回 Fyne 自带剪贴板 (不带提示)。
+// 窗口关闭时清除尚未过期的敏感内容。
+func initClipboard() {
+	cb, err := clipboard.Open()
+	if err != nil {
+		cb = fyneClipboard{}
+	}
+	secretClipboard = cb
+
+	myWindow.SetOnClosed(clearClipboardSecret)
+}
+
+// copySecret 复制敏感内容 (密码、Key B、恢复码等)，按剪贴板设置的时间自动清除。
+func copySecret(text, what string) {
+	msg, err := putSecret(text, what, settings.ClipboardClearSeconds)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("复制失败: %v", err), myWindow)
+		return
+	}
+	dialog.ShowInformation("已复制", msg, myWindow)
+}
+
+// putSecret 将敏感内容写入剪贴板，返回给用户的提示。
+// 核心逻辑:
+// 1. 写入剪贴板时附带 x-kde-passwordManagerHint / CLIPBOARD_SENSITIVE 提示，剪贴板管理器不记录历史；
+// 后端不支持提示时在返回的提示中警告用户。
+// 2. clearSeconds 秒后 (0 表示不清除)，仅当剪贴板内容仍是该秘密时才清除，不影响用户之后复制的其他内容。
+func putSecret(text, what string, clearSeconds int) (string, error) {
+	if err := secretClipboard.Copy(text); err != nil {
+		return "", err
+	}
+
+	clipboardSecret = text
+	if clipboardTimer != nil {
+		clipboardTimer.Stop()
+		clipboardTimer = nil
+	}
+
+	msg := fmt.Sprintf("%s已复制到剪贴板", what)
+	if clearSeconds > 0 {
+		clipboardTimer = time.AfterFunc(time.Duration(clearSeconds)*time.Second, func() {
+			fyne.Do(clearClipboardSecret)
+		})
+		msg += fmt.Sprintf("，%d 秒后自动清除", clearSeconds)
+	}
+	if !secretClipboard.Sensitive() {
+		msg += "。\n" + unprotectedClipboardNote
+	}
+	return msg, nil
+}
+
+// clearClipboardSecret 剪贴板仍是最近复制的敏感内容时清除
+func clearClipboardSecret() {
+	if clipboardTimer != nil {
+		clipboardTimer.Stop()
+		clipboardTimer = nil
+	}
+	if clipboardSecret == "" {
+		return
+	}
+	clipboard.ClearIfHeld(secretClipboard, clipboardSecret)
+	clipboardSecret = ""
+}
+
+// newClipboardSettingsBox 账户安全对话框中的剪贴板设置，修改后立即保存
+func newClipboardSettingsBox() fyne.CanvasObject {
+	selectClear := newPresetSelect([]int{0, 10, 20, 30, 60, 120}, "秒", settings.ClipboardClearSeconds, func(v int) {
+		settings.ClipboardClearSeconds = v
+		saveSettings()
+	})
+
+	box := container.NewVBox(
+		container.NewHBox(widget.NewLabel("复制后自动清除:"), selectClear),
+		widget.NewLabel(fmt.Sprintf("剪贴板后端: %s (仅当剪贴板内容未被替换时清除)", secretClipboard.Name())),
+	)
+	if !secretClipboard.Sensitive() {
+		warning := widget.NewLabel("⚠ " + unprotectedClipboardNote)
+		warning.Wrapping = fyne.TextWrapWord
+		box.Add(warning)
+	}
+	return box
+}
+
+// fyneClipboard 没有 X11 / wl-clipboard 时使用 Fyne 自带剪贴板，只能写入纯文本。
+// 只能在界面线程中调用。
+type fyneClipboard struct{}
+
+func (fyneClipboard) Name() string {
+	return "fyne"
+}
+
+func (fyneClipboard) Sensitive() bool {
+	return false
+}
+
+func (fyneClipboard) Copy(text string) error {
+	myWindow.Clipboard().SetContent(text)
+	return nil
+}
+
+func (fyneClipboard) Holds(text string) (bool, error) {
+	return myWindow.Clipboard().Content() == text, nil
+}
+
+func (fyneClipboard) Clear() error {
+	myWindow.Clipboard().SetContent("")
+	return nil
+}
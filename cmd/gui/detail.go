@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+	"key-box/internal/vault"
+)
+
+// newItemDetail 右侧详情栏: 条目的全部字段 (逐个显示/复制)、创建和修改时间，以及编辑、历史、附件、删除等操作。
+// 密码和笔记默认隐藏，显示或复制时记入审计日志。refreshCallback 在条目被修改或删除后调用。
+func newItemDetail(item vault.VaultItem, refreshCallback func()) fyne.CanvasObject {
+	title := widget.NewLabelWithStyle(item.Site, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	title.Wrapping = fyne.TextWrapWord
+	kind := "登录"
+	if item.IsNote() {
+		kind = "安全笔记"
+	}
+
+	form := widget.NewForm()
+	if !item.IsNote() {
+		form.Append("账号", newDetailValue(item, "账号", item.Username, false))
+		form.Append("密码", container.NewVBox(
+			newDetailValue(item, "密码", item.Password, true),
+			newStrengthBadge(item),
+		))
+		for _, u := range item.URIs {
+			form.Append("网址", container.NewVBox(
+				newDetailValue(item, "网址", u.URI, false),
+				widget.NewLabelWithStyle("匹配方式: "+u.Match.Label(), fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+			))
+		}
+	}
+	if item.Notes != "" {
+		form.Append("笔记", newNotesValue(item))
+	}
+	if item.Folder != "" {
+		form.Append("文件夹", newDetailValue(item, "文件夹", item.Folder, false))
+	}
+	if len(item.Tags) > 0 {
+		form.Append("标签", newDetailValue(item, "标签", strings.Join(item.Tags, ", "), false))
+	}
+	created := "未记录"
+	if !item.CreatedAt.IsZero() {
+		created = item.CreatedAt.Local().Format("2006-01-02 15:04:05")
+	}
+	form.Append("创建时间", widget.NewLabel(created))
+	form.Append("修改时间", widget.NewLabel(item.UpdatedAt.Local().Format("2006-01-02 15:04:05")))
+
+	btnDelete := widget.NewButtonWithIcon("删除", theme.DeleteIcon(), func() {
+		dialog.ShowCustomConfirm("确认删除", "删除", "取消",
+			widget.NewLabel(fmt.Sprintf("确定要将「%s」移到回收站吗？", item.Site)),
+			func(confirm bool) {
+				if !confirm {
+					return
+				}
+				if err := vaultManager.DeleteItem(item.ID); err != nil {
+					dialog.ShowError(fmt.Errorf("删除失败: %v", err), myWindow)
+					return
+				}
+				logEvent(audit.EventItemDelete, item.Site)
+				dialog.ShowInformation("成功", "已移到回收站，可在「回收站」中还原", myWindow)
+				refreshCallback()
+			}, myWindow)
+	})
+	btnDelete.Importance = widget.DangerImportance
+	actions := container.NewHBox(
+		widget.NewButtonWithIcon("编辑", theme.DocumentCreateIcon(), func() {
+			showEditVaultItemDialog(item, refreshCallback)
+		}),
+		widget.NewButtonWithIcon("历史", theme.HistoryIcon(), func() {
+			showHistoryDialog(item, refreshCallback)
+		}),
+		widget.NewButtonWithIcon("附件", theme.MailAttachmentIcon(), func() {
+			showAttachmentsDialog(item)
+		}),
+		layout.NewSpacer(),
+		btnDelete,
+	)
+
+	return container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil, newFavoriteButton(item, refreshCallback), title),
+			widget.NewLabelWithStyle(kind, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+			widget.NewSeparator(),
+		),
+		container.NewVBox(widget.NewSeparator(), actions),
+		nil, nil,
+		container.NewVScroll(form),
+	)
+}
+
+// newDetailPlaceholder 未选择条目时的详情栏
+func newDetailPlaceholder() fyne.CanvasObject {
+	return container.NewCenter(widget.NewLabelWithStyle("选择左侧的条目查看详情", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+}
+
+// newDetailValue 详情中的一个字段值，带复制按钮，长内容自动换行。
+// hidden 为 true 时默认显示为 ********，点击显示按钮后才显示明文。
+func newDetailValue(item vault.VaultItem, name, value string, hidden bool) fyne.CanvasObject {
+	label := widget.NewLabel(value)
+	label.Wrapping = fyne.TextWrapBreak
+	label.Selectable = !hidden
+
+	buttons := container.NewHBox()
+	if hidden {
+		label.SetText("********")
+		shown := false
+		var btnToggle *widget.Button
+		btnToggle = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+			shown = !shown
+			if shown {
+				label.SetText(value)
+				btnToggle.SetIcon(theme.VisibilityOffIcon())
+				logEvent(audit.EventItemView, item.Site)
+				markUsed(item)
+			} else {
+				label.SetText("********")
+				btnToggle.SetIcon(theme.VisibilityIcon())
+			}
+		})
+		buttons.Add(btnToggle)
+	}
+	buttons.Add(widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		copySecret(value, name)
+		if hidden {
+			logEvent(audit.EventItemCopy, item.Site)
+			markUsed(item)
+		}
+	}))
+	return container.NewBorder(nil, nil, nil, buttons, label)
+}
+
+// newNotesValue 笔记: 默认折叠，显示时渲染为只读 Markdown，复制时复制原文。
+func newNotesValue(item vault.VaultItem) fyne.CanvasObject {
+	view := newMarkdownView(item.Notes)
+	view.Hide()
+	hiddenLabel := widget.NewLabelWithStyle("笔记内容已隐藏", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+
+	var btnToggle *widget.Button
+	btnToggle = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+		if view.Visible() {
+			view.Hide()
+			hiddenLabel.Show()
+			btnToggle.SetIcon(theme.VisibilityIcon())
+			return
+		}
+		hiddenLabel.Hide()
+		view.Show()
+		btnToggle.SetIcon(theme.VisibilityOffIcon())
+		logEvent(audit.EventItemView, item.Site+" (笔记)")
+		markUsed(item)
+	})
+	btnCopy := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		copySecret(item.Notes, "笔记")
+		logEvent(audit.EventItemCopy, item.Site+" (笔记)")
+		markUsed(item)
+	})
+	return container.NewBorder(nil, nil, nil, container.NewVBox(btnToggle, btnCopy), container.NewVBox(hiddenLabel, view))
+}
+
+// markUsed 记录条目被使用 (显示或复制密码、笔记)，搜索时最近使用的条目排在前面。
+// 只影响排序，失败时不打扰用户。
+func markUsed(item vault.VaultItem) {
+	if err := vaultManager.MarkUsed(item.ID); err != nil {
+		fyne.LogError("记录使用时间失败", err)
+		return
+	}
+	touchCachedItem(item.ID, time.Now().UTC())
+}
+
+// newMarkdownView 将笔记渲染为只读富文本 (Fyne 内置的 goldmark 解析)。
+// 安全决策:
+//   - 图片不加载，只显示说明和地址: 渲染时会按地址读取本地文件或发起网络请求，可被用来探测用户何时查看了笔记。
+//   - 只有 http/https/mailto 链接可以点击，其他协议 (如 file:) 显示为文本。
+func newMarkdownView(text string) *widget.RichText {
+	rt := widget.NewRichTextFromMarkdown(text)
+	rt.Wrapping = fyne.TextWrapWord
+	rt.Segments = sanitizeMarkdown(rt.Segments)
+	rt.Refresh()
+	return rt
+}
+
+func sanitizeMarkdown(segments []widget.RichTextSegment) []widget.RichTextSegment {
+	for i, seg := range segments {
+		switch s := seg.(type) {
+		case *widget.ImageSegment:
+			text := "[图片]"
+			if s.Title != "" {
+				text = fmt.Sprintf("[图片: %s]", s.Title)
+			}
+			if s.Source != nil {
+				text += " " + s.Source.String()
+			}
+			segments[i] = &widget.TextSegment{Style: widget.RichTextStyleParagraph, Text: text}
+		case *widget.HyperlinkSegment:
+			if s.URL == nil {
+				segments[i] = &widget.TextSegment{Style: widget.RichTextStyleInline, Text: s.Text}
+				continue
+			}
+			switch strings.ToLower(s.URL.Scheme) {
+			case "http", "https", "mailto":
+			default:
+				segments[i] = &widget.TextSegment{Style: widget.RichTextStyleInline, Text: fmt.Sprintf("%s (%s)", s.Text, s.URL)}
+			}
+		case *widget.ParagraphSegment:
+			s.Texts = sanitizeMarkdown(s.Texts)
+		case *widget.ListSegment:
+			s.Items = sanitizeMarkdown(s.Items)
+		}
+	}
+	return segments
+}
+
+// newNotesEntry 添加/编辑对话框中的多行笔记编辑框
+func newNotesEntry(text string) *widget.Entry {
+	entry := widget.NewMultiLineEntry()
+	entry.PlaceHolder = "笔记 (支持 Markdown)"
+	entry.Wrapping = fyne.TextWrapWord
+	entry.SetMinRowsVisible(6)
+	entry.SetText(text)
+	return entry
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/generator"
+)
+
+// newPasswordField 密码输入框 + 「生成」按钮，生成的密码填入输入框
+func newPasswordField(entryPass *widget.Entry) fyne.CanvasObject {
+	btnGenerate := widget.NewButtonWithIcon("生成", theme.ViewRefreshIcon(), func() {
+		showGeneratorDialog(func(password string) {
+			entryPass.SetText(password)
+		})
+	})
+	return container.NewBorder(nil, nil, nil, btnGenerate, entryPass)
+}
+
+// showGeneratorDialog 密码生成器: 随机字符或 EFF 词表口令，选项变化时实时重新生成并显示熵
+func showGeneratorDialog(onUse func(password string)) {
+	opts := generator.DefaultOptions()
+	phraseOpts := generator.DefaultPassphraseOptions()
+	usePhrase := false
+
+	preview := widget.NewEntry()
+	preview.TextStyle = fyne.TextStyle{Monospace: true}
+	entropyLabel := widget.NewLabel("")
+
+	regenerate := func() {
+		var res *generator.Result
+		var err error
+		if usePhrase {
+			res, err = generator.Passphrase(phraseOpts)
+		} else {
+			res, err = generator.Generate(opts)
+		}
+		if err != nil {
+			preview.SetText("")
+			entropyLabel.SetText(fmt.Sprintf("⚠️ %v", err))
+			return
+		}
+		preview.SetText(res.Password)
+		entropyLabel.SetText(fmt.Sprintf("熵: %.1f 比特", res.Entropy))
+	}
+
+	// 随机字符选项
+	lengthLabel := widget.NewLabel(fmt.Sprintf("长度: %d", opts.Length))
+	sliderLength := widget.NewSlider(8, 64)
+	sliderLength.Step = 1
+	sliderLength.SetValue(float64(opts.Length))
+	sliderLength.OnChanged = func(v float64) {
+		opts.Length = int(v)
+		lengthLabel.SetText(fmt.Sprintf("长度: %d", opts.Length))
+		regenerate()
+	}
+
+	optionCheck := func(label string, enabled *bool) *widget.Check {
+		check := widget.NewCheck(label, func(on bool) {
+			*enabled = on
+			regenerate()
+		})
+		check.Checked = *enabled
+		return check
+	}
+	charOptions := container.NewVBox(
+		container.NewBorder(nil, nil, lengthLabel, nil, sliderLength),
+		container.NewGridWithColumns(4,
+			optionCheck("a-z", &opts.Lower),
+			optionCheck("A-Z", &opts.Upper),
+			optionCheck("0-9", &opts.Digits),
+			optionCheck("!@#$", &opts.Symbols),
+		),
+		optionCheck("排除易混淆字符 ("+generator.AmbiguousChars+")", &opts.ExcludeAmbiguous),
+	)
+
+	// 口令选项
+	wordsLabel := widget.NewLabel(fmt.Sprintf("单词数: %d", phraseOpts.Words))
+	sliderWords := widget.NewSlider(generator.MinWords, 12)
+	sliderWords.Step = 1
+	sliderWords.SetValue(float64(phraseOpts.Words))
+	sliderWords.OnChanged = func(v float64) {
+		phraseOpts.Words = int(v)
+		wordsLabel.SetText(fmt.Sprintf("单词数: %d", phraseOpts.Words))
+		regenerate()
+	}
+	entrySep := widget.NewEntry()
+	entrySep.SetText(phraseOpts.Separator)
+	entrySep.OnChanged = func(s string) {
+		phraseOpts.Separator = s
+		regenerate()
+	}
+	phraseOptions := container.NewVBox(
+		container.NewBorder(nil, nil, wordsLabel, nil, sliderWords),
+		container.NewBorder(nil, nil, widget.NewLabel("分隔符:"), nil, entrySep),
+		container.NewGridWithColumns(2,
+			optionCheck("首字母大写", &phraseOpts.Capitalize),
+			optionCheck("追加数字", &phraseOpts.Digit),
+		),
+	)
+	phraseOptions.Hide()
+
+	radioMode := widget.NewRadioGroup([]string{"随机字符", "单词口令"}, func(selected string) {
+		usePhrase = selected == "单词口令"
+		if usePhrase {
+			charOptions.Hide()
+			phraseOptions.Show()
+		} else {
+			phraseOptions.Hide()
+			charOptions.Show()
+		}
+		regenerate()
+	})
+	radioMode.Horizontal = true
+	radioMode.Required = true
+	radioMode.SetSelected("随机字符")
+
+	content := container.NewVBox(
+		radioMode,
+		charOptions,
+		phraseOptions,
+		widget.NewSeparator(),
+		container.NewBorder(nil, nil, nil,
+			widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), regenerate),
+			preview,
+		),
+		entropyLabel,
+	)
+
+	d := dialog.NewCustomConfirm("生成密码", "使用", "取消", content, func(confirm bool) {
+		if confirm && preview.Text != "" {
+			onUse(preview.Text)
+		}
+	}, myWindow)
+	d.Resize(fyne.NewSize(480, 360))
+	d.Show()
+}
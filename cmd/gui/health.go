@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/vault"
+)
+
+// showHealthDialog 密码库体检: 重复使用、弱密码、长期未修改、重复条目。
+// 每个问题条目可直接打开编辑对话框，保存后报告和密码列表一起刷新。
+func showHealthDialog(onChanged func()) {
+	opts := vault.DefaultHealthOptions()
+	body := container.NewStack()
+	selectedTab := 0
+
+	var refresh func()
+	refresh = func() {
+		items, err := vaultManager.ListItems(currentUser, currentKeyC)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("读取失败: %v", err), myWindow)
+			return
+		}
+		byID := make(map[int]vault.VaultItem, len(items))
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		report := vault.AnalyzeHealth(items, opts, time.Now())
+
+		edit := func(ref vault.ItemRef) fyne.CanvasObject {
+			return widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				if item, ok := byID[ref.ID]; ok {
+					showEditVaultItemDialog(item, func() {
+						refresh()
+						onChanged()
+					})
+				}
+			})
+		}
+		row := func(ref vault.ItemRef, detail string) fyne.CanvasObject {
+			text := ref.Site + " | " + ref.Username
+			if detail != "" {
+				text += "  —  " + detail
+			}
+			return container.NewBorder(nil, nil, nil, edit(ref), widget.NewLabel(text))
+		}
+		section := func(objects []fyne.CanvasObject) fyne.CanvasObject {
+			if len(objects) == 0 {
+				return container.NewCenter(widget.NewLabelWithStyle("✅ 没有发现问题", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+			}
+			return container.NewVScroll(container.NewVBox(objects...))
+		}
+
+		var reused []fyne.CanvasObject
+		for _, g := range report.Reused {
+			reused = append(reused, widget.NewLabelWithStyle(
+				fmt.Sprintf("%d 个条目共用同一密码: %s", len(g.Items), strings.Join(g.Sites, ", ")),
+				fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			for _, ref := range g.Items {
+				reused = append(reused, row(ref, ""))
+			}
+			reused = append(reused, widget.NewSeparator())
+		}
+
+		var weak []fyne.CanvasObject
+		for _, w := range report.Weak {
+			detail := w.Label
+			if w.Warning != "" {
+				detail += "，" + w.Warning
+			}
+			weak = append(weak, row(w.ItemRef, detail))
+		}
+
+		var old []fyne.CanvasObject
+		for _, o := range report.Old {
+			old = append(old, row(o.ItemRef, fmt.Sprintf("最后修改 %s (%d 天前)", o.UpdatedAt.Local().Format("2006-01-02"), o.AgeDays)))
+		}
+
+		var duplicates []fyne.CanvasObject
+		for _, d := range report.Duplicates {
+			duplicates = append(duplicates, widget.NewLabelWithStyle(
+				fmt.Sprintf("%s | %s 出现 %d 次", d.Site, d.Username, len(d.Items)),
+				fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			for _, ref := range d.Items {
+				duplicates = append(duplicates, row(ref, "修改于 "+ref.UpdatedAt.Local().Format("2006-01-02")))
+			}
+			duplicates = append(duplicates, widget.NewSeparator())
+		}
+
+		summary := widget.NewLabel(fmt.Sprintf("共 %d 个条目，发现 %d 个问题。", report.Total, report.Issues()))
+		if report.Issues() > 0 {
+			summary.Importance = widget.WarningImportance
+		} else {
+			summary.Importance = widget.SuccessImportance
+		}
+
+		tabs := container.NewAppTabs(
+			container.NewTabItemWithIcon(fmt.Sprintf("重复使用 (%d)", len(report.Reused)), theme.ContentCopyIcon(), section(reused)),
+			container.NewTabItemWithIcon(fmt.Sprintf("弱密码 (%d)", len(report.Weak)), theme.WarningIcon(), section(weak)),
+			container.NewTabItemWithIcon(fmt.Sprintf("长期未修改 (%d)", len(report.Old)), theme.HistoryIcon(), section(old)),
+			container.NewTabItemWithIcon(fmt.Sprintf("重复条目 (%d)", len(report.Duplicates)), theme.ListIcon(), section(duplicates)),
+		)
+		tabs.SelectIndex(selectedTab)
+		tabs.OnSelected = func(*container.TabItem) { selectedTab = tabs.SelectedIndex() }
+
+		body.Objects = []fyne.CanvasObject{container.NewBorder(summary, nil, nil, nil, tabs)}
+		body.Refresh()
+	}
+
+	selectAge := newPresetSelect([]int{0, 3, 6, 12, 24}, "个月", opts.MaxAgeMonths, func(months int) {
+		opts.MaxAgeMonths = months
+		refresh()
+	})
+	refresh()
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("检查所有条目的密码是否重复使用、强度不足或长期未修改，以及是否有重复的网站/账号。"),
+			container.NewHBox(
+				widget.NewLabel("超过多久未修改视为过期:"), selectAge,
+				layout.NewSpacer(),
+				widget.NewButtonWithIcon("泄露检查...", theme.SearchIcon(), func() {
+					showBreachDialog(func() {
+						refresh()
+						onChanged()
+					})
+				}),
+			),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		body,
+	)
+
+	d := dialog.NewCustom("密码体检", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(720, 520))
+	d.Show()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+	"key-box/internal/vault"
+)
+
+// showHistoryDialog 显示条目的历史版本 (最新的在前)，可查看、复制或恢复某个版本。
+// 恢复前的当前内容同样会被归档，因此恢复可以撤销。
+func showHistoryDialog(item vault.VaultItem, refreshCallback func()) {
+	history, err := vaultManager.ListHistory(currentKeyC, item.ID)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取历史版本失败: %v", err), myWindow)
+		return
+	}
+
+	var d *dialog.CustomDialog
+	list := container.NewVBox()
+	for _, h := range history {
+		h := h
+
+		passEntry := widget.NewPasswordEntry()
+		passEntry.SetText(h.Password)
+		passEntry.Disable()
+		var secretView fyne.CanvasObject = passEntry
+		secret, what := h.Password, "历史密码"
+
+		// 安全笔记的历史版本显示笔记内容，默认隐藏
+		notesEntry := widget.NewMultiLineEntry()
+		notesHidden := widget.NewLabelWithStyle("笔记内容已隐藏", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+		if item.IsNote() {
+			notesEntry.SetText(h.Notes)
+			notesEntry.Wrapping = fyne.TextWrapWord
+			notesEntry.Disable()
+			notesEntry.Hide()
+			secretView = container.NewVBox(notesHidden, notesEntry)
+			secret, what = h.Notes, "历史笔记"
+		}
+
+		var btnToggle *widget.Button
+		btnToggle = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+			passEntry.Password = !passEntry.Password
+			if passEntry.Password {
+				btnToggle.SetIcon(theme.VisibilityIcon())
+				notesEntry.Hide()
+				notesHidden.Show()
+			} else {
+				btnToggle.SetIcon(theme.VisibilityOffIcon())
+				notesHidden.Hide()
+				notesEntry.Show()
+				logEvent(audit.EventItemView, item.Site+" (历史版本)")
+			}
+			passEntry.Refresh()
+		})
+		btnCopy := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+			copySecret(secret, what)
+			logEvent(audit.EventItemCopy, item.Site+" (历史版本)")
+		})
+		btnRevert := widget.NewButtonWithIcon("恢复", theme.MediaReplayIcon(), func() {
+			dialog.ShowConfirm("恢复历史版本",
+				fmt.Sprintf("将「%s」恢复为 %s 的版本？\n当前内容会保存为新的历史版本。", item.Site, h.SavedAt.Local().Format("2006-01-02 15:04")),
+				func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := vaultManager.RevertItem(currentKeyC, item.ID, h.ID); err != nil {
+						dialog.ShowError(fmt.Errorf("恢复失败: %v", err), myWindow)
+						return
+					}
+					logEvent(audit.EventItemRevert, fmt.Sprintf("%s (%s)", item.Site, h.SavedAt.Local().Format("2006-01-02 15:04")))
+					d.Hide()
+					dialog.ShowInformation("成功", "已恢复为所选版本", myWindow)
+					refreshCallback()
+				}, myWindow)
+		})
+
+		header := widget.NewLabelWithStyle(
+			fmt.Sprintf("%s — %s", h.SavedAt.Local().Format("2006-01-02 15:04:05"), h.ArchivedAt.Local().Format("2006-01-02 15:04:05")),
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		account := widget.NewLabel(h.Site + " | " + h.Username)
+		list.Add(container.NewVBox(
+			header,
+			account,
+			container.NewBorder(nil, nil, nil, container.NewHBox(btnToggle, btnCopy, btnRevert), secretView),
+			widget.NewSeparator(),
+		))
+	}
+
+	var body fyne.CanvasObject = container.NewVScroll(list)
+	if len(history) == 0 {
+		body = container.NewCenter(widget.NewLabelWithStyle("没有历史版本", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+	}
+
+	limit := "不保留历史版本"
+	if vaultManager.HistoryLimit > 0 {
+		limit = fmt.Sprintf("每个条目最多保留 %d 个历史版本", vaultManager.HistoryLimit)
+	}
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("「%s」每次修改前的内容 (使用期间)。%s，可在「安全」中设置。", item.Site, limit)),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		body,
+	)
+
+	d = dialog.NewCustom("历史版本", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(640, 460))
+	d.Show()
+}
+
+// newHistorySettingsBox 账户安全对话框中的历史版本设置，修改后立即保存
+func newHistorySettingsBox() fyne.CanvasObject {
+	selectLimit := newPresetSelect([]int{0, 5, 10, 20, 50}, "个", settings.HistoryLimit, func(v int) {
+		settings.HistoryLimit = v
+		vaultManager.HistoryLimit = v
+		saveSettings()
+	})
+
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabel("每个条目保留历史版本:"), selectLimit),
+		widget.NewLabel("超出数量的最旧版本会在下次修改该条目时删除。"),
+	)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+	"key-box/internal/hotkey"
+	"key-box/internal/vault"
+	"key-box/internal/x11"
+)
+
+const (
+	// launcherMaxResults 快速访问窗口最多显示的条目数
+	launcherMaxResults = 50
+	// launcherMaxClearSeconds 快速访问复制的内容最长保留时间 (剪贴板设置为更短时按设置)
+	launcherMaxClearSeconds = 20
+
+	launcherHint = "Enter 复制密码 · Ctrl+Enter 复制账号 · ↑↓ 选择 · Esc 关闭"
+)
+
+var (
+	// launcherWindow 快速访问窗口，首次打开时创建，关闭时只隐藏
+	launcherWindow fyne.Window
+	launcherShown  bool
+
+	// stopLauncherHotkey 注销全局快捷键，launcherHotkeyErr 为注册失败的原因
+	stopLauncherHotkey func()
+	launcherHotkeyErr  error
+	// launcherShortcut 主窗口内的同名快捷键 (全局快捷键不可用时仍可在主窗口中使用)
+	launcherShortcut *desktop.CustomShortcut
+)
+
+// initLauncher 按设置注册打开快速访问窗口的快捷键。
+func initLauncher() {
+	launcherHotkeyErr = registerLauncherHotkey()
+}
+
+// registerLauncherHotkey 注销旧的快捷键并注册 settings.LauncherHotkey (为空时不注册)。
+// 核心逻辑:
+//  1. 在主窗口中注册同名快捷键。
+//  2. 通过 X11 键盘抓取注册全局快捷键，任何程序获得焦点时都能打开快速访问窗口；
+//     没有 X11 显示 (纯 Wayland) 或被其他程序占用时返回错误，主窗口中的快捷键仍然有效。
+func registerLauncherHotkey() error {
+	if stopLauncherHotkey != nil {
+		stopLauncherHotkey()
+		stopLauncherHotkey = nil
+	}
+	if launcherShortcut != nil {
+		myWindow.Canvas().RemoveShortcut(launcherShortcut)
+		launcherShortcut = nil
+	}
+	if settings.LauncherHotkey == "" {
+		return nil
+	}
+
+	h, err := hotkey.Parse(settings.LauncherHotkey)
+	if err != nil {
+		return err
+	}
+	launcherShortcut = fyneShortcut(h)
+	myWindow.Canvas().AddShortcut(launcherShortcut, func(fyne.Shortcut) {
+		toggleLauncher()
+	})
+
+	stop, err := hotkey.Register(h, func() {
+		fyne.Do(toggleLauncher)
+	})
+	if err != nil {
+		return err
+	}
+	stopLauncherHotkey = stop
+	return nil
+}
+
+// fyneShortcut 全局快捷键对应的 Fyne 窗口内快捷键
+func fyneShortcut(h hotkey.Hotkey) *desktop.CustomShortcut {
+	key := fyne.KeyName(h.Key)
+	switch h.Key {
+	case "Enter":
+		key = fyne.KeyReturn
+	case "PageUp":
+		key = fyne.KeyPageUp
+	case "PageDown":
+		key = fyne.KeyPageDown
+	}
+
+	var mods fyne.KeyModifier
+	for hm, fm := range map[hotkey.Modifier]fyne.KeyModifier{
+		hotkey.ModCtrl:  fyne.KeyModifierControl,
+		hotkey.ModAlt:   fyne.KeyModifierAlt,
+		hotkey.ModShift: fyne.KeyModifierShift,
+		hotkey.ModSuper: fyne.KeyModifierSuper,
+	} {
+		if h.Modifiers&hm != 0 {
+			mods |= fm
+		}
+	}
+	return &desktop.CustomShortcut{KeyName: key, Modifier: mods}
+}
+
+// newLauncherSettingsBox 账户安全对话框中的快速访问设置，应用后立即重新注册并保存
+func newLauncherSettingsBox() fyne.CanvasObject {
+	entryHotkey := widget.NewEntry()
+	entryHotkey.SetText(settings.LauncherHotkey)
+	entryHotkey.PlaceHolder = "如 Ctrl+Alt+K，留空不使用"
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	showStatus := func() {
+		switch {
+		case settings.LauncherHotkey == "":
+			status.SetText("未设置快捷键")
+		case launcherHotkeyErr != nil:
+			status.SetText(fmt.Sprintf("全局快捷键不可用，仅在主窗口中有效: %v", launcherHotkeyErr))
+		default:
+			status.SetText("已注册为全局快捷键，在任何程序中按下即可打开")
+		}
+	}
+
+	btnApply := widget.NewButton("应用", func() {
+		text := strings.TrimSpace(entryHotkey.Text)
+		if text != "" {
+			h, err := hotkey.Parse(text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("快捷键无效: %v", err), myWindow)
+				return
+			}
+			text = h.String()
+			entryHotkey.SetText(text)
+		}
+		settings.LauncherHotkey = text
+		saveSettings()
+		launcherHotkeyErr = registerLauncherHotkey()
+		updateTray()
+		showStatus()
+	})
+	showStatus()
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("打开快捷键:"), btnApply, entryHotkey),
+		status,
+		widget.NewLabel(fmt.Sprintf("快速访问复制的内容最多保留 %d 秒；纯 Wayland 会话不支持全局快捷键", launcherMaxClearSeconds)),
+	)
+}
+
+// toggleLauncher 快捷键: 快速访问窗口已显示时隐藏，否则显示
+func toggleLauncher() {
+	if launcherShown {
+		hideLauncher()
+		return
+	}
+	showLauncher()
+}
+
+// showLauncher 显示快速访问窗口: 置顶、获得焦点，按会话状态显示搜索或解锁界面。
+func showLauncher() {
+	if launcherWindow == nil {
+		launcherWindow = myApp.NewWindow("Key-Box 快速访问")
+		launcherWindow.Resize(fyne.NewSize(520, 380))
+		launcherWindow.SetFixedSize(true)
+		launcherWindow.SetCloseIntercept(hideLauncher)
+		if dc, ok := launcherWindow.Canvas().(desktop.Canvas); ok {
+			dc.SetOnKeyDown(func(*fyne.KeyEvent) {
+				touchActivity()
+			})
+		}
+	}
+
+	content, focus := newLauncherContent()
+	launcherWindow.SetContent(content)
+	launcherWindow.CenterOnScreen()
+	launcherWindow.Show()
+	launcherShown = true
+	launcherWindow.RequestFocus()
+	if focus != nil {
+		launcherWindow.Canvas().Focus(focus)
+	}
+	keepLauncherAbove()
+}
+
+// hideLauncher 隐藏快速访问窗口并清除其中的内容 (搜索词、结果)
+func hideLauncher() {
+	if launcherWindow == nil {
+		return
+	}
+	launcherWindow.SetContent(widget.NewLabel(""))
+	launcherWindow.Hide()
+	launcherShown = false
+}
+
+// keepLauncherAbove 请求窗口管理器将快速访问窗口保持在其他窗口之上并激活 (仅 X11；Fyne 没有置顶接口)。
+func keepLauncherAbove() {
+	window := x11Window(launcherWindow)
+	if window == 0 {
+		return
+	}
+	go func() {
+		if err := x11.RaiseAbove(os.Getenv("DISPLAY"), window); err != nil {
+			fyne.LogError("快速访问窗口置顶失败", err)
+		}
+	}()
+}
+
+// newLauncherContent 按会话状态创建窗口内容，返回应获得焦点的输入框
+func newLauncherContent() (fyne.CanvasObject, fyne.Focusable) {
+	switch {
+	case currentUser == "":
+		btnOpen := widget.NewButtonWithIcon("打开主窗口", theme.HomeIcon(), func() {
+			hideLauncher()
+			showMainWindow()
+		})
+		return container.NewCenter(container.NewVBox(
+			widget.NewLabelWithStyle("尚未登录，请先在主窗口中登录", fyne.TextAlignCenter, fyne.TextStyle{}),
+			btnOpen,
+		)), nil
+	case currentKeyC == nil:
+		return newLauncherUnlock()
+	default:
+		return newLauncherSearch()
+	}
+}
+
+// newLauncherUnlock 会话已锁定时在快速访问窗口中解锁
+func newLauncherUnlock() (fyne.CanvasObject, fyne.Focusable) {
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "🔢 6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "🔑 主密码 (未启用可留空)"
+	status := widget.NewLabel("")
+	status.Importance = widget.DangerImportance
+
+	performUnlock := func() {
+		if entryOTP.Text == "" {
+			status.SetText("请输入验证码")
+			return
+		}
+		if err := unlockSession(entryOTP.Text, entryPass.Text); err != nil {
+			entryOTP.SetText("")
+			status.SetText(fmt.Sprintf("解锁失败: %v", err))
+			return
+		}
+		showLauncher()
+	}
+	entryOTP.OnSubmitted = func(string) { performUnlock() }
+	entryPass.OnSubmitted = func(string) { performUnlock() }
+
+	return container.NewPadded(container.NewVBox(
+		widget.NewLabelWithStyle(fmt.Sprintf("🔒 %s 的密码库已锁定", currentUser), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		entryOTP,
+		entryPass,
+		status,
+	)), entryOTP
+}
+
+// newLauncherSearch 搜索框和结果列表。
+// 核心逻辑:
+// 1. 与密码库界面相同的模糊搜索 (vault.Search)，只列出登录条目，按匹配程度和最近使用排序。
+// 2. ↑↓ 选择，Enter 复制密码，Ctrl+Enter 复制账号，Esc 关闭；复制后窗口自动隐藏。
+// 安全决策: 复制的内容按剪贴板设置清除，但最多保留 launcherMaxClearSeconds 秒 (设置为不清除时也是如此)。
+func newLauncherSearch() (fyne.CanvasObject, fyne.Focusable) {
+	var results []vault.VaultItem
+	selected := 0
+	status := widget.NewLabelWithStyle(launcherHint, fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	entry := newLauncherEntry()
+	entry.PlaceHolder = "🔍 搜索网站、账号、网址、标签…"
+
+	list := widget.NewList(
+		func() int { return len(results) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			title.Truncation = fyne.TextTruncateEllipsis
+			subtitle := widget.NewLabel("")
+			subtitle.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, widget.NewIcon(theme.AccountIcon()), nil, container.NewVBox(title, subtitle))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			text := obj.(*fyne.Container).Objects[0].(*fyne.Container)
+			text.Objects[0].(*widget.Label).SetText(results[id].Site)
+			text.Objects[1].(*widget.Label).SetText(results[id].Username)
+		},
+	)
+	var root fyne.CanvasObject
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		// 点击列表后焦点回到搜索框，继续用键盘操作
+		if launcherWindow.Content() == root {
+			launcherWindow.Canvas().Focus(entry)
+		}
+	}
+
+	var update func()
+	update = func() {
+		items, ok := cachedItems()
+		if !ok {
+			status.SetText("正在解密…")
+			loadItems("launcher", nil, func(err error) {
+				if err != nil {
+					status.SetText(fmt.Sprintf("读取失败: %v", err))
+					return
+				}
+				if launcherWindow.Content() == root {
+					update()
+				}
+			})
+			return
+		}
+		results = results[:0]
+		for _, r := range vault.Search(items, entry.Text, time.Now()) {
+			if len(results) == launcherMaxResults {
+				break
+			}
+			if !r.Item.IsNote() {
+				results = append(results, r.Item)
+			}
+		}
+		selected = 0
+		list.UnselectAll()
+		list.Refresh()
+		if len(results) > 0 {
+			list.Select(0)
+			list.ScrollToTop()
+		}
+		status.SetText(launcherHint)
+		if len(results) == 0 {
+			status.SetText("未找到匹配的登录条目")
+		}
+	}
+	entry.OnChanged = func(string) { update() }
+
+	copyField := func(username bool) {
+		if selected >= len(results) {
+			return
+		}
+		item := results[selected]
+		value, what := item.Password, "密码"
+		if username {
+			value, what = item.Username, "账号"
+		}
+		clearSeconds := settings.ClipboardClearSeconds
+		if clearSeconds <= 0 || clearSeconds > launcherMaxClearSeconds {
+			clearSeconds = launcherMaxClearSeconds
+		}
+		if _, err := putSecret(value, what, clearSeconds); err != nil {
+			status.SetText(fmt.Sprintf("复制失败: %v", err))
+			return
+		}
+		if !username {
+			logEvent(audit.EventItemCopy, item.Site+" (快速访问)")
+			markUsed(item)
+		}
+		hideLauncher()
+	}
+	entry.OnSubmitted = func(string) { copyField(false) }
+	entry.onCopyUsername = func() { copyField(true) }
+	entry.onMove = func(delta int) {
+		if len(results) == 0 {
+			return
+		}
+		list.Select(min(max(selected+delta, 0), len(results)-1))
+	}
+	entry.onEscape = hideLauncher
+
+	root = container.NewBorder(
+		container.NewVBox(entry, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), status),
+		nil, nil,
+		list,
+	)
+	update()
+	return root, entry
+}
+
+// launcherEntry 快速访问的搜索框，处理列表选择和复制账号的按键
+type launcherEntry struct {
+	widget.Entry
+	onMove         func(delta int)
+	onCopyUsername func()
+	onEscape       func()
+}
+
+func newLauncherEntry() *launcherEntry {
+	e := &launcherEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *launcherEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp:
+		e.onMove(-1)
+	case fyne.KeyDown:
+		e.onMove(1)
+	case fyne.KeyEscape:
+		e.onEscape()
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
+
+func (e *launcherEntry) TypedShortcut(s fyne.Shortcut) {
+	if cs, ok := s.(*desktop.CustomShortcut); ok && cs.Modifier == fyne.KeyModifierControl &&
+		(cs.KeyName == fyne.KeyReturn || cs.KeyName == fyne.KeyEnter) {
+		e.onCopyUsername()
+		return
+	}
+	e.Entry.TypedShortcut(s)
+}
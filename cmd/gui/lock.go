@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+	"key-box/internal/config"
+	"key-box/internal/lockwatch"
+	"key-box/internal/x11"
+)
+
+// idleCheckInterval 检查无操作超时的间隔
+const idleCheckInterval = 10 * time.Second
+
+// minimizeCheckInterval 本程序失去前台期间检查主窗口是否已最小化的间隔
+const minimizeCheckInterval = 500 * time.Millisecond
+
+var (
+	settings config.Settings
+
+	// lastActivity 最近一次用户操作的时间，只在界面线程读写
+	lastActivity time.Time
+
+	// lockWatchErr 系统休眠/锁屏信号监听失败的原因 (例如没有 D-Bus)
+	lockWatchErr error
+
+	// vaultSearchText 密码库界面的搜索词，锁定后解锁时恢复
+	vaultSearchText string
+
+	// vaultSelectedID 密码库界面选中的条目 (右侧显示其详情)，0 表示未选中，锁定后解锁时恢复
+	vaultSelectedID int
+
+	// minimizeWatchStop 关闭时结束最小化检查，nil 表示当前未在检查，只在界面线程读写
+	minimizeWatchStop chan struct{}
+)
+
+// lockReasonLabels 锁定原因的中文说明
+var lockReasonLabels = map[string]string{
+	"idle":                      "长时间无操作",
+	"minimize":                  "窗口最小化",
+	"manual":                    "手动锁定",
+	lockwatch.ReasonSleep:       "系统休眠",
+	lockwatch.ReasonSessionLock: "系统锁屏",
+	lockwatch.ReasonScreenSaver: "屏幕保护程序启动",
+}
+
+// initAutoLock 读取锁定设置并注册锁定触发源。
+// 核心逻辑:
+// 1. 无操作超时: 键盘和鼠标操作刷新活动时间，定时检查是否超时。
+// 2. 主窗口最小化: 失去前台 (Lifecycle ExitedForeground) 后定时查询窗口状态，只切换到其他窗口不锁定。
+// 3. 系统休眠和锁屏 (D-Bus logind / ScreenSaver 信号)。
+// 安全决策: 锁定时清零并丢弃 Key C，关闭所有对话框，只保留用户名和密码库界面的状态
+// (搜索词、筛选条件和选中的条目)。对话框 (回收站、历史版本、日志等) 可能显示明文，解锁后不重新打开。
+// 最小化以外的锁定同时清除剪贴板中的密码。
+func initAutoLock() {
+	s, err := config.LoadSettings()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取锁定设置失败，已使用默认设置: %v", err), myWindow)
+	}
+	settings = s
+	touchActivity()
+
+	if dc, ok := myWindow.Canvas().(desktop.Canvas); ok {
+		dc.SetOnKeyDown(func(*fyne.KeyEvent) {
+			touchActivity()
+		})
+	}
+
+	myApp.Lifecycle().SetOnExitedForeground(func() {
+		if minimizeWatchStop != nil || !settings.LockOnBackground || currentKeyC == nil {
+			return
+		}
+		window := x11Window(myWindow)
+		if window == 0 {
+			return
+		}
+		stop := make(chan struct{})
+		minimizeWatchStop = stop
+		go watchMinimize(window, stop)
+	})
+	myApp.Lifecycle().SetOnEnteredForeground(func() {
+		if minimizeWatchStop != nil {
+			close(minimizeWatchStop)
+			minimizeWatchStop = nil
+		}
+	})
+
+	go func() {
+		for range time.Tick(idleCheckInterval) {
+			fyne.Do(checkIdle)
+		}
+	}()
+
+	_, lockWatchErr = lockwatch.Watch(func(reason string) {
+		fyne.Do(func() {
+			if settings.LockOnSystemLock {
+				lockSession(reason)
+			}
+		})
+	})
+}
+
+// watchMinimize 在本程序失去前台期间定时查询主窗口是否已最小化 (X11 WM_STATE)，最小化时锁定。
+// 安全决策: 只切换到其他窗口 (例如到浏览器粘贴刚复制的密码) 不锁定；无法查询窗口状态时
+// (纯 Wayland 等) 不因最小化锁定，仍由无操作超时兜底。
+func watchMinimize(window uint32, stop chan struct{}) {
+	c, err := x11.Dial(os.Getenv("DISPLAY"))
+	if err != nil {
+		fyne.LogError("无法查询主窗口是否最小化", err)
+		return
+	}
+	defer c.Close()
+
+	ticker := time.NewTicker(minimizeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		iconic, err := c.IsIconic(window)
+		if err != nil {
+			fyne.LogError("无法查询主窗口是否最小化", err)
+			return
+		}
+		if iconic {
+			fyne.Do(func() {
+				// 期间重新回到前台时不再锁定
+				if minimizeWatchStop == stop && settings.LockOnBackground {
+					lockSession("minimize")
+				}
+			})
+			return
+		}
+	}
+}
+
+// x11Window 返回窗口的 X11 窗口 ID，不是 X11 窗口 (或尚未创建) 时返回 0。
+func x11Window(w fyne.Window) uint32 {
+	nw, ok := w.(driver.NativeWindow)
+	if !ok {
+		return 0
+	}
+	var window uint32
+	nw.RunNative(func(context any) {
+		if ctx, ok := context.(driver.X11WindowContext); ok {
+			window = uint32(ctx.WindowHandle)
+		}
+	})
+	return window
+}
+
+// touchActivity 记录一次用户操作
+func touchActivity() {
+	lastActivity = time.Now()
+}
+
+// checkIdle 无操作超过设定时间时锁定
+func checkIdle() {
+	if currentKeyC == nil || settings.AutoLockMinutes <= 0 {
+		return
+	}
+	if time.Since(lastActivity) >= time.Duration(settings.AutoLockMinutes)*time.Minute {
+		lockSession("idle")
+	}
+}
+
+// lockSession 锁定当前会话: 清零 Key C 并显示解锁界面
+func lockSession(reason string) {
+	if currentUser == "" || currentKeyC == nil {
+		return
+	}
+	logEvent(audit.EventLock, reason)
+
+	wipe(currentKeyC)
+	currentKeyC = nil
+	clearItemCache()
+	// 最小化时用户多半正要粘贴刚复制的密码，剪贴板留给自动清除计时器处理
+	if reason != "minimize" {
+		clearClipboardSecret()
+	}
+	hideLauncher()
+	updateTray()
+
+	// 关闭所有对话框 (其中可能显示着明文密码)
+	overlays := myWindow.Canvas().Overlays()
+	for _, o := range append([]fyne.CanvasObject(nil), overlays.List()...) {
+		overlays.Remove(o)
+	}
+
+	showLockScreen(reason)
+}
+
+// showLockScreen 显示解锁界面，输入 OTP (及主密码) 后返回锁定前的密码库界面
+func showLockScreen(reason string) {
+	label, ok := lockReasonLabels[reason]
+	if !ok {
+		label = reason
+	}
+
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "🔢 6位 OTP 验证码"
+
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "🔑 主密码 (未启用可留空)"
+
+	performUnlock := func() {
+		if entryOTP.Text == "" {
+			dialog.ShowError(fmt.Errorf("请输入验证码"), myWindow)
+			return
+		}
+		if err := unlockSession(entryOTP.Text, entryPass.Text); err != nil {
+			entryOTP.SetText("")
+			dialog.ShowError(fmt.Errorf("解锁失败: %v", err), myWindow)
+		}
+	}
+	entryOTP.OnSubmitted = func(string) { performUnlock() }
+	entryPass.OnSubmitted = func(string) { performUnlock() }
+
+	btnUnlock := widget.NewButtonWithIcon("解锁", theme.ConfirmIcon(), performUnlock)
+	btnUnlock.Importance = widget.HighImportance
+
+	btnSwitch := widget.NewButtonWithIcon("切换用户", theme.LogoutIcon(), func() {
+		logout()
+	})
+
+	form := container.NewVBox(
+		widget.NewLabelWithStyle("🔒 密码库已锁定", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(fmt.Sprintf("用户: %s", currentUser), fyne.TextAlignCenter, fyne.TextStyle{}),
+		widget.NewLabelWithStyle(fmt.Sprintf("锁定原因: %s", label), fyne.TextAlignCenter, fyne.TextStyle{Italic: true}),
+		widget.NewSeparator(),
+		entryOTP,
+		entryPass,
+		btnUnlock,
+		widget.NewSeparator(),
+		container.NewHBox(layout.NewSpacer(), btnSwitch, layout.NewSpacer()),
+	)
+
+	myWindow.SetContent(container.NewVBox(
+		layout.NewSpacer(),
+		container.NewPadded(form),
+		layout.NewSpacer(),
+	))
+	myWindow.Canvas().Focus(entryOTP)
+}
+
+// unlockSession 验证 OTP (及主密码) 解锁当前用户的会话，成功后主窗口返回密码库界面
+// (恢复搜索词、筛选条件和选中的条目)。
+func unlockSession(otp, passphrase string) error {
+	keyC, err := authService.Login(currentUser, otp, passphrase)
+	if err != nil {
+		return err
+	}
+	currentKeyC = keyC
+	touchActivity()
+	showVaultScreen()
+	return nil
+}
+
+// newAutoLockSettingsBox 账户安全对话框中的自动锁定设置，修改后立即保存
+func newAutoLockSettingsBox() fyne.CanvasObject {
+	selectIdle := newPresetSelect([]int{0, 1, 5, 15, 30, 60}, "分钟", settings.AutoLockMinutes, func(v int) {
+		settings.AutoLockMinutes = v
+		touchActivity()
+		saveSettings()
+	})
+
+	checkBackground := widget.NewCheck("窗口最小化时锁定 (切换到其他窗口不锁定)", func(on bool) {
+		settings.LockOnBackground = on
+		saveSettings()
+	})
+	checkBackground.Checked = settings.LockOnBackground
+
+	systemLabel := "系统休眠或锁屏时锁定"
+	if lockWatchErr != nil {
+		systemLabel += " (未连接到 D-Bus，不可用)"
+	}
+	checkSystem := widget.NewCheck(systemLabel, func(on bool) {
+		settings.LockOnSystemLock = on
+		saveSettings()
+	})
+	checkSystem.Checked = settings.LockOnSystemLock
+
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabel("无操作自动锁定:"), selectIdle),
+		checkBackground,
+		checkSystem,
+	)
+}
+
+// newPresetSelect 从预设值中选择一个整数 (0 显示为「从不」)。
+// 配置文件中的非预设值会追加为额外选项。
+func newPresetSelect(values []int, unit string, current int, onChanged func(int)) *widget.Select {
+	values = append([]int(nil), values...)
+	if !slices.Contains(values, current) {
+		values = append(values, current)
+	}
+
+	options := make([]string, len(values))
+	for i, v := range values {
+		options[i] = "从不"
+		if v > 0 {
+			options[i] = fmt.Sprintf("%d %s", v, unit)
+		}
+	}
+
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(slices.Index(values, current))
+	sel.OnChanged = func(string) {
+		onChanged(values[sel.SelectedIndex()])
+	}
+	return sel
+}
+
+// saveSettings 保存偏好设置，失败时提示
+func saveSettings() {
+	if err := config.SaveSettings(settings); err != nil {
+		dialog.ShowError(fmt.Errorf("保存设置失败: %v", err), myWindow)
+	}
+}
+
+// activityArea 包裹界面内容，鼠标在其上移动时刷新活动时间。
+// 按钮、输入框等自身处理鼠标悬停的控件不会转发事件，
+// 它们的操作通过键盘事件和审计事件 (logEvent) 刷新活动时间。
+type activityArea struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+}
+
+func newActivityArea(content fyne.CanvasObject) *activityArea {
+	a := &activityArea{content: content}
+	a.ExtendBaseWidget(a)
+	return a
+}
+
+func (a *activityArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.content)
+}
+
+func (a *activityArea) MouseIn(*desktop.MouseEvent) {
+	touchActivity()
+}
+
+func (a *activityArea) MouseMoved(*desktop.MouseEvent) {
+	touchActivity()
+}
+
+func (a *activityArea) MouseOut() {}
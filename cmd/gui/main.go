@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -12,18 +13,17 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"image/color"
 
+	"key-box/internal/audit"
 	"key-box/internal/auth"
 	"key-box/internal/config"
+	"key-box/internal/crypto"
 	"key-box/internal/db"
 	"key-box/internal/vault"
 )
@@ -33,6 +33,7 @@ var (
 	myWindow     fyne.Window
 	authService  *auth.Service
 	vaultManager *vault.Manager
+	auditLog     *audit.Logger
 
 	// State
 	currentUser string
@@ -40,6 +41,9 @@ var (
 
 	// 标志：登录后是否自动打开恢复对话框
 	shouldShowRestoreAfterLogin bool
+
+	// Root Key 口令 (仅 passphrase 提供者使用，启动时输入)
+	rootKeyPassphrase string
 )
 
 func main() {
@@ -50,6 +54,13 @@ func main() {
 
 	// 1. Init Config & DB
 	checkEnvAndInit()
+	initClipboard()
+	initAutoLock()
+	initLauncher()
+	initTray()
+	if vaultManager != nil {
+		vaultManager.HistoryLimit = settings.HistoryLimit
+	}
 
 	// 2. Show Main Menu (Login/Register)
 	showMainMenu()
@@ -83,22 +94,53 @@ func checkEnvAndInit() {
 		return
 	}
 
-	authService = auth.NewService(database)
-	vaultManager = vault.NewManager(database)
+	rootKeys, err := crypto.NewRootKeyProvider(config.GetRootKeyProvider(), func() (string, error) {
+		if rootKeyPassphrase == "" {
+			return "", fmt.Errorf("未输入 Root Key 口令，请重启程序后输入")
+		}
+		return rootKeyPassphrase, nil
+	})
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Root Key 配置错误: %v", err), myWindow)
+		return
+	}
 
-	if autoSalt != "" {
-		// Salt 已自动保存到配置文件 ~/.key-box.config
-		msg := "已生成加密 Salt 并自动保存到配置文件。\n\n配置文件路径: ~/.key-box.config\n\n首次使用完成。"
-		content := container.NewVBox(
-			widget.NewLabel(msg),
-		)
+	authService = auth.NewService(database, rootKeys)
+	vaultManager = vault.NewManager(database)
+	auditLog = audit.NewLogger(database)
 
+	needPassphrase := rootKeys.Name() == crypto.RootKeyProviderPassphrase
+	if autoSalt != "" || needPassphrase {
 		fyne.CurrentApp().Lifecycle().SetOnStarted(func() {
-			dialog.ShowCustom("初始化完成", "我知道了", content, myWindow)
+			if needPassphrase {
+				showRootKeyPassphraseDialog()
+			}
+			if autoSalt != "" {
+				// Salt 已自动保存到配置文件 ~/.key-box.config
+				msg := "已生成加密 Salt 并自动保存到配置文件。\n\n配置文件路径: ~/.key-box.config\n\n首次使用完成。"
+				content := container.NewVBox(
+					widget.NewLabel(msg),
+				)
+				dialog.ShowCustom("初始化完成", "我知道了", content, myWindow)
+			}
 		})
 	}
 }
 
+// showRootKeyPassphraseDialog 启动时索取 Root Key 口令 (passphrase 提供者)
+func showRootKeyPassphraseDialog() {
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "Root Key 口令"
+
+	dialog.ShowForm("解锁 Key-Box", "确定", "取消", []*widget.FormItem{
+		widget.NewFormItem("口令", entryPass),
+	}, func(confirm bool) {
+		if confirm {
+			rootKeyPassphrase = entryPass.Text
+		}
+	}, myWindow)
+}
+
 func showMainMenu() {
 	// 标题区域
 	titleLabel := widget.NewLabelWithStyle("🔐 Key-Box", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
@@ -143,6 +185,9 @@ func createLoginContent() fyne.CanvasObject {
 	entryOTP.PlaceHolder = "🔢 6位 OTP 验证码"
 	entryOTP.Resize(fyne.NewSize(250, 40))
 
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "🔑 主密码 (未启用可留空)"
+
 	// 登录处理函数
 	performLogin := func() {
 		user := entryUser.Text
@@ -153,7 +198,7 @@ func createLoginContent() fyne.CanvasObject {
 			return
 		}
 
-		keyC, err := authService.Login(user, otp)
+		keyC, err := authService.Login(user, otp, entryPass.Text)
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("登录失败: %v", err), myWindow)
 			return
@@ -162,6 +207,8 @@ func createLoginContent() fyne.CanvasObject {
 		// Login Success
 		currentUser = user
 		currentKeyC = keyC
+		touchActivity()
+		purgeExpiredTrash()
 
 		// 检查是否需要自动打开恢复对话框
 		if shouldShowRestoreAfterLogin {
@@ -177,10 +224,13 @@ func createLoginContent() fyne.CanvasObject {
 		}
 	}
 
-	// 验证码输入框回车事件 - 触发登录
+	// 验证码/主密码输入框回车事件 - 触发登录
 	entryOTP.OnSubmitted = func(string) {
 		performLogin()
 	}
+	entryPass.OnSubmitted = func(string) {
+		performLogin()
+	}
 
 	btnLogin := widget.NewButton("登录", performLogin)
 	btnLogin.Importance = widget.HighImportance
@@ -203,6 +253,7 @@ func createLoginContent() fyne.CanvasObject {
 		widget.NewSeparator(),
 		entryUser,
 		entryOTP,
+		entryPass,
 		btnLogin,
 		widget.NewSeparator(),
 		container.NewHBox(layout.NewSpacer(), btnRegister, btnRestore, btnForgot, layout.NewSpacer()),
@@ -219,18 +270,18 @@ func showRegisterDialog() {
 	entryUser := widget.NewEntry()
 	entryUser.PlaceHolder = "用户名"
 
-	entryQ1 := widget.NewEntry()
-	entryQ1.PlaceHolder = "密保问题 1"
+	entryQ1 := widget.NewSelect(auth.SecurityQuestionPool, nil)
+	entryQ1.PlaceHolder = "选择密保问题 1"
 	entryA1 := widget.NewEntry()
 	entryA1.PlaceHolder = "答案 1"
 
-	entryQ2 := widget.NewEntry()
-	entryQ2.PlaceHolder = "密保问题 2"
+	entryQ2 := widget.NewSelect(auth.SecurityQuestionPool, nil)
+	entryQ2.PlaceHolder = "选择密保问题 2"
 	entryA2 := widget.NewEntry()
 	entryA2.PlaceHolder = "答案 2"
 
-	entryQ3 := widget.NewEntry()
-	entryQ3.PlaceHolder = "密保问题 3"
+	entryQ3 := widget.NewSelect(auth.SecurityQuestionPool, nil)
+	entryQ3.PlaceHolder = "选择密保问题 3"
 	entryA3 := widget.NewEntry()
 	entryA3.PlaceHolder = "答案 3"
 
@@ -251,7 +302,7 @@ func showRegisterDialog() {
 
 		res, err := authService.Register(
 			entryUser.Text,
-			entryQ1.Text, entryQ2.Text, entryQ3.Text,
+			entryQ1.Selected, entryQ2.Selected, entryQ3.Selected,
 			entryA1.Text, entryA2.Text, entryA3.Text,
 		)
 		if err != nil {
@@ -266,8 +317,7 @@ func showRegisterDialog() {
 		keyBEntry := widget.NewEntryWithData(bindingString(res.SecretKeyBBase32))
 
 		btnCopy := widget.NewButton("复制到剪贴板", func() {
-			myWindow.Clipboard().SetContent(res.SecretKeyBBase32)
-			dialog.ShowInformation("已复制", "Key B 已复制到剪贴板", myWindow)
+			copySecret(res.SecretKeyBBase32, "Key B ")
 		})
 		btnCopy.Importance = widget.HighImportance
 
@@ -296,9 +346,11 @@ func showRegisterDialog() {
 				keyBEntry,
 				btnCopy,
 				widget.NewSeparator(),
+				newRecoveryCodesBox(res.RecoveryCodes),
+				widget.NewSeparator(),
 				instructionText,
 			), myWindow)
-		dSuccess.Resize(fyne.NewSize(500, 450))
+		dSuccess.Resize(fyne.NewSize(500, 600))
 		dSuccess.Show()
 	})
 	btnReg.Importance = widget.HighImportance
@@ -364,46 +416,7 @@ func showResetDialog() {
 
 		d.Hide()
 
-		// 使用自定义对话框，包含可选中复制的 Entry
-		keyBEntry := widget.NewEntryWithData(bindingString(res.SecretKeyBBase32))
-
-		btnCopy := widget.NewButton("复制到剪贴板", func() {
-			myWindow.Clipboard().SetContent(res.SecretKeyBBase32)
-			dialog.ShowInformation("已复制", "Key B 已复制到剪贴板", myWindow)
-		})
-		btnCopy.Importance = widget.HighImportance
-
-		instructionText := widget.NewMultiLineEntry()
-		instructionText.SetText(
-			"如何使用新的 Key B 登录：\n" +
-				"\n" +
-				"1. 在 TOTP 应用中删除旧的凭证\n" +
-				"\n" +
-				"2. 添加新的 Key B 到 TOTP 应用\n" +
-				"   推荐应用：Google Authenticator、Microsoft Authenticator\n" +
-				"   1Password、Authy 等\n" +
-				"\n" +
-				"3. TOTP 应用会生成新的 6 位验证码（每 30 秒刷新）\n" +
-				"\n" +
-				"4. 使用新的验证码登录\n" +
-				"\n" +
-				"⚠️ 重要：旧的 Key B 已失效！\n" +
-				"   请务必保存新的 Key B，丢失后只能再次重置",
-		)
-		instructionText.Wrapping = fyne.TextWrapWord
-
-		dSuccess := dialog.NewCustom("重置成功", "关闭",
-			container.NewVBox(
-				widget.NewLabelWithStyle("✅ 密码重置成功！", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-				widget.NewSeparator(),
-				widget.NewLabel("您的新登录凭证 (Key B):"),
-				keyBEntry,
-				btnCopy,
-				widget.NewSeparator(),
-				instructionText,
-			), myWindow)
-		dSuccess.Resize(fyne.NewSize(500, 450))
-		dSuccess.Show()
+		showResetSuccessDialog(res)
 	})
 	btnReset.Importance = widget.HighImportance
 
@@ -416,6 +429,10 @@ func showResetDialog() {
 		labelQ3, entryA3,
 		layout.NewSpacer(),
 		btnReset,
+		widget.NewButton("没有密保答案？使用恢复码", func() {
+			d.Hide()
+			showRedeemRecoveryCodeDialog()
+		}),
 	))
 	content.SetMinSize(fyne.NewSize(400, 400))
 
@@ -424,6 +441,52 @@ func showResetDialog() {
 	d.Show()
 }
 
+// showResetSuccessDialog 显示重置后的新 Key B (密保问题和恢复码重置共用)
+func showResetSuccessDialog(res *auth.RegisterResult) {
+	// 使用自定义对话框，包含可选中复制的 Entry
+	keyBEntry := widget.NewEntryWithData(bindingString(res.SecretKeyBBase32))
+
+	btnCopy := widget.NewButton("复制到剪贴板", func() {
+		copySecret(res.SecretKeyBBase32, "Key B ")
+	})
+	btnCopy.Importance = widget.HighImportance
+
+	instructionText := widget.NewMultiLineEntry()
+	instructionText.SetText(
+		"如何使用新的 Key B 登录：\n" +
+			"\n" +
+			"1. 在 TOTP 应用中删除旧的凭证\n" +
+			"\n" +
+			"2. 添加新的 Key B 到 TOTP 应用\n" +
+			"   推荐应用：Google Authenticator、Microsoft Authenticator\n" +
+			"   1Password、Authy 等\n" +
+			"\n" +
+			"3. TOTP 应用会生成新的 6 位验证码（每 30 秒刷新）\n" +
+			"\n" +
+			"4. 使用新的验证码登录\n" +
+			"\n" +
+			"如之前启用了主密码，重置后主密码已关闭，\n" +
+			"   登录后可在「安全」中重新启用\n" +
+			"\n" +
+			"⚠️ 重要：旧的 Key B 已失效！\n" +
+			"   请务必保存新的 Key B，丢失后只能再次重置",
+	)
+	instructionText.Wrapping = fyne.TextWrapWord
+
+	dSuccess := dialog.NewCustom("重置成功", "关闭",
+		container.NewVBox(
+			widget.NewLabelWithStyle("✅ 密码重置成功！", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewSeparator(),
+			widget.NewLabel("您的新登录凭证 (Key B):"),
+			keyBEntry,
+			btnCopy,
+			widget.NewSeparator(),
+			instructionText,
+		), myWindow)
+	dSuccess.Resize(fyne.NewSize(500, 450))
+	dSuccess.Show()
+}
+
 // truncateText 截断文本，如果超出最大长度则添加省略号
 func truncateText(text string, maxLen int) string {
 	runes := []rune(text)
@@ -433,109 +496,27 @@ func truncateText(text string, maxLen int) string {
 	return string(runes[:maxLen]) + "..."
 }
 
-// createFixedWidthTextCell 创建固定宽度的文本单元格，支持点击查看完整内容
-func createFixedWidthTextCell(fullText string, maxChars int, width float32, style fyne.TextStyle) fyne.CanvasObject {
-	displayText := truncateText(fullText, maxChars)
-	isTruncated := len([]rune(fullText)) > maxChars
-
-	// 如果文本被截断，在末尾添加点击提示
-	if isTruncated {
-		displayText = truncateText(fullText, maxChars-2) + "…🔍" // 使用省略号和放大镜图标
-	}
-
-	label := widget.NewLabelWithStyle("  "+displayText, fyne.TextAlignLeading, style)
-	label.Truncation = fyne.TextTruncateEllipsis
-
-	// 创建固定宽度容器 - 使用 Max 容器限制最大宽度
-	spacer := canvas.NewRectangle(color.Transparent)
-	spacer.SetMinSize(fyne.NewSize(width, 1))
-
-	// 创建一个固定大小的容器来确保不会被撑大
-	fixedContainer := container.NewMax(spacer, label)
-
-	// 如果文本被截断，添加点击查看功能
-	if isTruncated {
-		// 创建带悬停效果的可点击区域
-		clickableArea := &tappableContainer{
-			content:   fixedContainer,
-			fullText:  fullText,
-			label:     label,
-			baseText:  displayText,
-			isHovered: false,
-		}
-		clickableArea.ExtendBaseWidget(clickableArea)
-		return clickableArea
-	}
-
-	return fixedContainer
-}
-
-// tappableContainer 自定义可点击容器，带悬停效果
-type tappableContainer struct {
-	widget.BaseWidget
-	content   fyne.CanvasObject
-	fullText  string
-	label     *widget.Label
-	baseText  string
-	isHovered bool
-}
-
-func (t *tappableContainer) CreateRenderer() fyne.WidgetRenderer {
-	return &tappableRenderer{
-		container: t,
-		objects:   []fyne.CanvasObject{t.content},
-	}
-}
-
-func (t *tappableContainer) Tapped(*fyne.PointEvent) {
-	dialog.ShowInformation("完整内容", t.fullText, myWindow)
-}
-
-func (t *tappableContainer) MouseIn(*fyne.PointEvent) {
-	t.isHovered = true
-	// 可以在这里添加视觉反馈，比如改变标签颜色
-	t.Refresh()
-}
-
-func (t *tappableContainer) MouseOut() {
-	t.isHovered = false
-	t.Refresh()
-}
-
-func (t *tappableContainer) MouseMoved(*fyne.PointEvent) {
-	// 可选：处理鼠标移动事件
-}
-
-func (t *tappableContainer) Cursor() desktop.Cursor {
-	return desktop.PointerCursor
-}
-
-type tappableRenderer struct {
-	container *tappableContainer
-	objects   []fyne.CanvasObject
-}
-
-func (r *tappableRenderer) Layout(size fyne.Size) {
-	r.objects[0].Resize(size)
-}
-
-func (r *tappableRenderer) MinSize() fyne.Size {
-	return r.objects[0].MinSize()
-}
-
-func (r *tappableRenderer) Refresh() {
-	r.objects[0].Refresh()
-}
-
-func (r *tappableRenderer) Objects() []fyne.CanvasObject {
-	return r.objects
+// logout 清除会话密钥并返回登录界面
+func logout() {
+	currentUser = ""
+	wipe(currentKeyC)
+	currentKeyC = nil
+	vaultSearchText = ""
+	vaultFilter = vault.ItemFilter{}
+	vaultSelectedID = 0
+	clearItemCache()
+	clearClipboardSecret()
+	hideLauncher()
+	updateTray()
+	myWindow.Resize(fyne.NewSize(600, 500))
+	showMainMenu()
 }
 
-func (r *tappableRenderer) Destroy() {}
-
 func showVaultScreen() {
+	updateTray()
+
 	// 调整窗口大小
-	myWindow.Resize(fyne.NewSize(800, 600))
+	myWindow.Resize(fyne.NewSize(1120, 620))
 
 	// Vault Toolbar
 	btnAdd := widget.NewButtonWithIcon("添加", theme.ContentAddIcon(), func() {
@@ -550,207 +531,140 @@ func showVaultScreen() {
 		showRestoreDialog()
 	})
 
+	btnSecurity := widget.NewButtonWithIcon("安全", theme.SettingsIcon(), func() {
+		showSecurityDialog()
+	})
+
+	btnAudit := widget.NewButtonWithIcon("日志", theme.HistoryIcon(), func() {
+		showAuditDialog()
+	})
+
+	var refreshList func()
+
+	btnTrash := widget.NewButtonWithIcon("回收站", theme.DeleteIcon(), func() {
+		showTrashDialog(refreshList)
+	})
+
+	btnHealth := widget.NewButtonWithIcon("体检", theme.WarningIcon(), func() {
+		showHealthDialog(refreshList)
+	})
+
+	btnLock := widget.NewButtonWithIcon("锁定", theme.VisibilityOffIcon(), func() {
+		lockSession("manual")
+	})
+
 	btnLogout := widget.NewButtonWithIcon("退出", theme.LogoutIcon(), func() {
-		currentUser = ""
-		currentKeyC = nil
-		myWindow.Resize(fyne.NewSize(600, 500))
-		showMainMenu()
+		logout()
 	})
 
 	// 搜索框
 	searchEntry := widget.NewEntry()
-	searchEntry.PlaceHolder = "🔍 搜索网站或账号..."
-
-	// Content List
-	listContainer := container.NewVBox()
-
-	var refreshList func()
+	searchEntry.PlaceHolder = "🔍 搜索 (支持拼写错误；可用 user: tag: url: folder: note: is:fav 限定)"
+	searchEntry.Text = vaultSearchText
+
+	// 左侧条目列表 (widget.List 只为可见的行创建控件)，右侧为选中条目的详情
+	var shownItems []vault.VaultItem
+	detail := container.NewStack(newDetailPlaceholder())
+	summary := widget.NewLabel("")
+	emptyLabel := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	loadProgress := widget.NewProgressBar()
+	loadProgress.Hide()
+	sidebar := newVaultSidebar(func() { refreshList() })
+
+	itemList := widget.NewList(
+		func() int { return len(shownItems) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			title.Truncation = fyne.TextTruncateEllipsis
+			subtitle := widget.NewLabel("")
+			subtitle.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, widget.NewIcon(nil), widget.NewLabel(""), container.NewVBox(title, subtitle))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			item := shownItems[id]
+			row := obj.(*fyne.Container)
+			text := row.Objects[0].(*fyne.Container)
+			text.Objects[0].(*widget.Label).SetText(item.Site)
+			icon, subtitle := theme.AccountIcon(), item.Username
+			if item.IsNote() {
+				icon, subtitle = theme.DocumentIcon(), "安全笔记"
+			}
+			text.Objects[1].(*widget.Label).SetText(subtitle)
+			row.Objects[1].(*widget.Icon).SetResource(icon)
+			star := ""
+			if item.Favorite {
+				star = "★"
+			}
+			row.Objects[2].(*widget.Label).SetText(star)
+		},
+	)
+	itemList.OnSelected = func(id widget.ListItemID) {
+		item := shownItems[id]
+		vaultSelectedID = item.ID
+		detail.Objects = []fyne.CanvasObject{newItemDetail(item, refreshList)}
+		detail.Refresh()
+	}
 
 	refreshList = func() {
 		searchText := searchEntry.Text
-		listContainer.Objects = nil
-
-		// 标题区域
-		titleContainer := container.NewBorder(
-			nil, nil, nil, nil,
-			container.NewVBox(
-				widget.NewLabelWithStyle("🔐 密码库", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-				widget.NewLabel(fmt.Sprintf("当前用户: %s", currentUser)),
-			),
-		)
-		listContainer.Add(titleContainer)
-		listContainer.Add(widget.NewSeparator())
 
-		items, err := vaultManager.ListItems(currentUser, currentKeyC)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("读取失败: %v", err), myWindow)
+		// 缓存无效时在后台解密，完成后再次刷新；条目较多时显示进度
+		items, ok := cachedItems()
+		if !ok {
+			loadItems("vault", func(done, total int) {
+				if done < total {
+					loadProgress.Max = float64(total)
+					loadProgress.SetValue(float64(done))
+					loadProgress.Show()
+				}
+			}, func(err error) {
+				loadProgress.Hide()
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("读取失败: %v", err), myWindow)
+					return
+				}
+				refreshList()
+			})
 			return
 		}
-
-		// 过滤搜索结果
-		var filteredItems []vault.VaultItem
-		if searchText == "" {
-			filteredItems = items
-		} else {
-			searchLower := strings.ToLower(searchText)
-			for _, item := range items {
-				if strings.Contains(strings.ToLower(item.Site), searchLower) ||
-					strings.Contains(strings.ToLower(item.Username), searchLower) {
-					filteredItems = append(filteredItems, item)
-				}
-			}
+		sidebar.update(items)
+		items = vaultFilter.Apply(items)
+
+		// 模糊搜索，按匹配程度和最近使用排序 (没有搜索词时按网站名排序)
+		results := vault.Search(items, searchText, time.Now())
+		shownItems = make([]vault.VaultItem, len(results))
+		for i, r := range results {
+			shownItems[i] = r.Item
 		}
+		summary.SetText(fmt.Sprintf("当前用户: %s  ·  %d 个条目", currentUser, len(shownItems)))
 
 		// 空状态
-		if len(filteredItems) == 0 {
-			emptyText := "暂无密码记录"
-			if searchText != "" {
-				emptyText = "未找到匹配的密码记录"
+		emptyLabel.Hide()
+		if len(shownItems) == 0 {
+			emptyLabel.SetText("暂无记录，点击「添加」按钮开始使用")
+			if searchText != "" || vaultFilter != (vault.ItemFilter{}) {
+				emptyLabel.SetText("未找到匹配的记录")
 			}
-			listContainer.Add(container.NewCenter(
-				container.NewVBox(
-					widget.NewLabelWithStyle(emptyText, fyne.TextAlignCenter, fyne.TextStyle{Italic: true}),
-					widget.NewLabelWithStyle("点击「添加」按钮开始使用", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}),
-				),
-			))
-		} else {
-			// 添加表头 - 使用透明占位符控制列宽
-			headerBg := canvas.NewRectangle(color.RGBA{R: 200, G: 200, B: 200, A: 255})
-
-			// 定义列宽
-			col1Width := float32(100) // 网站列（调小）
-			col2Width := float32(160) // 账号列
-			col3Width := float32(320) // 密码列（加宽）
-
-			// 创建各列标题，使用透明背景矩形控制宽度
-			col1Spacer := canvas.NewRectangle(color.Transparent)
-			col1Spacer.SetMinSize(fyne.NewSize(col1Width, 1))
-			col1Label := widget.NewLabelWithStyle("  网站", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-			col1Box := container.NewStack(col1Spacer, col1Label)
-
-			col2Spacer := canvas.NewRectangle(color.Transparent)
-			col2Spacer.SetMinSize(fyne.NewSize(col2Width, 1))
-			col2Label := widget.NewLabelWithStyle("  账号", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-			col2Box := container.NewStack(col2Spacer, col2Label)
-
-			col3Spacer := canvas.NewRectangle(color.Transparent)
-			col3Spacer.SetMinSize(fyne.NewSize(col3Width, 1))
-			col3Label := widget.NewLabelWithStyle("  密码", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-			col3Box := container.NewStack(col3Spacer, col3Label)
-
-			col4Label := widget.NewLabelWithStyle("  操作", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-
-			headerContent := container.NewStack(
-				headerBg,
-				container.NewHBox(
-					col1Box,
-					col2Box,
-					col3Box,
-					col4Label,
-				),
-			)
-			listContainer.Add(headerContent)
-			listContainer.Add(widget.NewSeparator())
-
-			// 密码卡片列表
-			for _, item := range filteredItems {
-				item := item // Capture for closure
-
-				// 密码显示/隐藏状态
-				passwordVisible := false
-				passEntry := widget.NewPasswordEntry()
-				passEntry.SetText(item.Password)
-				passEntry.Disable()
-
-				// 密码显示切换按钮
-				var btnTogglePass *widget.Button
-				btnTogglePass = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
-					if passwordVisible {
-						passEntry.Password = true
-						btnTogglePass.SetIcon(theme.VisibilityIcon())
-					} else {
-						passEntry.Password = false
-						btnTogglePass.SetIcon(theme.VisibilityOffIcon())
-					}
-					passwordVisible = !passwordVisible
-					passEntry.Refresh()
-				})
-
-				// 给密码框添加深色背景和固定宽度
-				passBg := canvas.NewRectangle(color.RGBA{R: 35, G: 35, B: 35, A: 255})
-				passBg.CornerRadius = 4
-
-				// 使用透明占位符控制密码框最小宽度
-				passSpacer := canvas.NewRectangle(color.Transparent)
-				passSpacer.SetMinSize(fyne.NewSize(250, 1)) // 密码框宽度设为 250px
-				passWithBg := container.NewStack(passSpacer, passBg, passEntry)
-
-				// 密码框和切换按钮组合
-				passColumn := container.NewHBox(
-					widget.NewLabel("  "), // 与表头对齐
-					passWithBg,
-					btnTogglePass,
-				)
-
-				// 操作按钮组 - 单独放在一个 HBox 中
-				actionButtons := container.NewHBox(
-					widget.NewButtonWithIcon("复制", theme.ContentCopyIcon(), func() {
-						myWindow.Clipboard().SetContent(item.Password)
-						dialog.ShowInformation("已复制", "密码已复制到剪贴板", myWindow)
-					}),
-					widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
-						showEditVaultItemDialog(item, refreshList)
-					}),
-					widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
-						dialog.ShowCustomConfirm("确认删除", "删除", "取消",
-							widget.NewLabel(fmt.Sprintf("确定要删除「%s」的密码吗？", item.Site)),
-							func(confirm bool) {
-								if confirm {
-									err := vaultManager.DeleteItem(item.ID)
-									if err != nil {
-										dialog.ShowError(fmt.Errorf("删除失败: %v", err), myWindow)
-									} else {
-										dialog.ShowInformation("成功", "密码已删除", myWindow)
-										refreshList()
-									}
-								}
-							}, myWindow)
-					}),
-				)
-
-				// 使用与表头完全相同的列宽和方法
-				col1Width := float32(100) // 网站列（调小）
-				col2Width := float32(160) // 账号列
-				col3Width := float32(320) // 密码列（加宽）
-
-				// 第一列：网站 - 固定宽度，超长文本可点击查看
-				siteCell := createFixedWidthTextCell(item.Site, 8, col1Width, fyne.TextStyle{Bold: true})
-
-				// 第二列：账号 - 固定宽度，超长文本可点击查看
-				usernameCell := createFixedWidthTextCell(item.Username, 12, col2Width, fyne.TextStyle{})
-
-				// 第三列：密码 - 固定宽度容器
-				col3Spacer := canvas.NewRectangle(color.Transparent)
-				col3Spacer.SetMinSize(fyne.NewSize(col3Width, 1))
-				passBox := container.NewStack(col3Spacer, passColumn)
-
-				cardContent := container.NewHBox(
-					siteCell,
-					usernameCell,
-					passBox,
-					actionButtons,
-				)
+			emptyLabel.Show()
+		}
 
-				// 添加卡片
-				listContainer.Add(cardContent)
-				listContainer.Add(widget.NewSeparator())
+		// 保持选中的条目 (修改后或解锁后显示新的内容)，条目被删除或筛选掉时清空详情
+		itemList.UnselectAll()
+		itemList.Refresh()
+		for i, item := range shownItems {
+			if item.ID == vaultSelectedID {
+				itemList.Select(i)
+				return
 			}
 		}
-		listContainer.Refresh()
+		vaultSelectedID = 0
+		detail.Objects = []fyne.CanvasObject{newDetailPlaceholder()}
+		detail.Refresh()
 	}
 
 	// 搜索框实时搜索
-	searchEntry.OnChanged = func(string) {
+	searchEntry.OnChanged = func(text string) {
+		vaultSearchText = text
 		refreshList()
 	}
 
@@ -764,12 +678,30 @@ func showVaultScreen() {
 			btnAdd,
 			btnBackup,
 			btnRestore,
+			btnSecurity,
+			btnAudit,
+			btnHealth,
+			btnTrash,
 			layout.NewSpacer(),
+			btnLock,
 			btnLogout,
 		),
 	)
 
-	// 主布局
+	// 主布局: 左侧筛选栏，中间条目列表，右侧详情
+	listPanel := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle("🔐 密码库", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			summary,
+			widget.NewSeparator(),
+		),
+		loadProgress, nil, nil,
+		container.NewStack(itemList, container.NewCenter(emptyLabel)),
+	)
+	listSplit := container.NewHSplit(listPanel, container.NewPadded(detail))
+	listSplit.Offset = 0.4
+	split := container.NewHSplit(newSidebarPanel(sidebar), listSplit)
+	split.Offset = 0.16
 	content := container.NewBorder(
 		container.NewVBox(
 			toolbar,
@@ -778,10 +710,10 @@ func showVaultScreen() {
 			widget.NewSeparator(),
 		),
 		nil, nil, nil,
-		container.NewVScroll(listContainer),
+		split,
 	)
 
-	myWindow.SetContent(content)
+	myWindow.SetContent(newActivityArea(content))
 }
 
 func showAddVaultItemDialog() {
@@ -792,19 +724,63 @@ func showAddVaultItemDialog() {
 	entryPass := widget.NewPasswordEntry()
 	entryPass.PlaceHolder = "密码"
 
-	dialog.ShowCustomConfirm("添加密码", "保存", "取消", container.NewVBox(
-		entrySite, entryUser, entryPass,
+	// 默认放入当前筛选的文件夹/标签
+	var preset vault.VaultItem
+	preset.Folder = vaultFilter.Folder
+	preset.Favorite = vaultFilter.Favorites
+	if vaultFilter.Tag != "" {
+		preset.Tags = []string{vaultFilter.Tag}
+	}
+	organize := newOrganizeFields(preset)
+	uris := newURIEditor(nil)
+	entryNotes := newNotesEntry("")
+
+	// 安全笔记只有标题和笔记，隐藏登录相关的字段
+	loginFields := container.NewVBox(entryUser, newPasswordField(entryPass),
+		newStrengthMeter(entryPass, entrySite, entryUser),
+		uris.object())
+	itemType := vault.TypeLogin
+	typeRadio := widget.NewRadioGroup([]string{"登录", "安全笔记"}, func(selected string) {
+		if selected == "安全笔记" {
+			itemType = vault.TypeNote
+			entrySite.SetPlaceHolder("标题")
+			loginFields.Hide()
+		} else {
+			itemType = vault.TypeLogin
+			entrySite.SetPlaceHolder("网站/应用")
+			loginFields.Show()
+		}
+	})
+	typeRadio.Horizontal = true
+	typeRadio.Required = true
+	typeRadio.SetSelected("登录")
+
+	dialog.ShowCustomConfirm("添加条目", "保存", "取消", container.NewVBox(
+		typeRadio,
+		entrySite, loginFields, entryNotes,
+		organize.folder, organize.tags, organize.favorite,
 	), func(confirm bool) {
 		if confirm {
-			if entrySite.Text == "" || entryPass.Text == "" {
-				dialog.ShowError(fmt.Errorf("网站和密码不能为空"), myWindow)
-				return
+			item := vault.VaultItem{Type: itemType, Site: entrySite.Text, Notes: entryNotes.Text}
+			if itemType == vault.TypeNote {
+				if entrySite.Text == "" || strings.TrimSpace(entryNotes.Text) == "" {
+					dialog.ShowError(fmt.Errorf("标题和笔记不能为空"), myWindow)
+					return
+				}
+			} else {
+				if entrySite.Text == "" || entryPass.Text == "" {
+					dialog.ShowError(fmt.Errorf("网站和密码不能为空"), myWindow)
+					return
+				}
+				item.Username, item.Password, item.URIs = entryUser.Text, entryPass.Text, uris.values()
 			}
-			err := vaultManager.AddItem(currentUser, currentKeyC, entrySite.Text, entryUser.Text, entryPass.Text)
+			organize.apply(&item)
+			err := vaultManager.AddItem(currentUser, currentKeyC, item)
 			if err != nil {
 				dialog.ShowError(fmt.Errorf("添加失败: %v", err), myWindow)
 			} else {
-				dialog.ShowInformation("成功", "密码已添加", myWindow)
+				logEvent(audit.EventItemAdd, entrySite.Text)
+				dialog.ShowInformation("成功", "已添加", myWindow)
 				showVaultScreen() // Rebuilds the UI which refreshes list
 			}
 		}
@@ -821,24 +797,62 @@ func showEditVaultItemDialog(item vault.VaultItem, refreshCallback func()) {
 	entryPass := widget.NewPasswordEntry()
 	entryPass.SetText(item.Password)
 
-	dialog.ShowCustomConfirm("编辑密码", "保存", "取消", container.NewVBox(
-		widget.NewLabel("网站/应用:"),
-		entrySite,
+	organize := newOrganizeFields(item)
+	uris := newURIEditor(item.URIs)
+	entryNotes := newNotesEntry(item.Notes)
+
+	title, siteLabel := "编辑密码", "网站/应用:"
+	loginFields := container.NewVBox(
 		widget.NewLabel("用户名/邮箱:"),
 		entryUser,
 		widget.NewLabel("密码:"),
-		entryPass,
+		newPasswordField(entryPass),
+		newStrengthMeter(entryPass, entrySite, entryUser),
+		widget.NewLabel("网址:"),
+		uris.object(),
+	)
+	if item.IsNote() {
+		title, siteLabel = "编辑安全笔记", "标题:"
+		loginFields.Hide()
+	}
+
+	dialog.ShowCustomConfirm(title, "保存", "取消", container.NewVBox(
+		widget.NewLabel(siteLabel),
+		entrySite,
+		loginFields,
+		widget.NewLabel("笔记:"),
+		entryNotes,
+		widget.NewLabel("文件夹 / 标签:"),
+		organize.folder,
+		organize.tags,
+		organize.favorite,
 	), func(confirm bool) {
 		if confirm {
-			if entrySite.Text == "" || entryPass.Text == "" {
+			if item.IsNote() {
+				if entrySite.Text == "" || strings.TrimSpace(entryNotes.Text) == "" {
+					dialog.ShowError(fmt.Errorf("标题和笔记不能为空"), myWindow)
+					return
+				}
+			} else if entrySite.Text == "" || entryPass.Text == "" {
 				dialog.ShowError(fmt.Errorf("网站和密码不能为空"), myWindow)
 				return
 			}
-			err := vaultManager.UpdateItem(currentKeyC, item.ID, entrySite.Text, entryUser.Text, entryPass.Text)
+			// 在原条目上修改，保留对话框中没有的字段
+			updated := item
+			updated.Site = entrySite.Text
+			updated.Notes = entryNotes.Text
+			if !item.IsNote() {
+				updated.Username = entryUser.Text
+				updated.Password = entryPass.Text
+				updated.URIs = uris.values()
+			}
+			organize.apply(&updated)
+			err := vaultManager.UpdateItem(currentKeyC, updated)
 			if err != nil {
 				dialog.ShowError(fmt.Errorf("更新失败: %v", err), myWindow)
 			} else {
-				dialog.ShowInformation("成功", "密码已更新", myWindow)
+				logEvent(audit.EventItemEdit, entrySite.Text)
+				dialog.ShowInformation("成功", "已更新", myWindow)
 				refreshCallback()
 			}
 		}
@@ -858,9 +872,10 @@ func showBackupDialog() {
 		widget.NewLabel("📦 备份说明"),
 		widget.NewSeparator(),
 		widget.NewLabel("• 将导出您的账户和所有密码数据"),
-		widget.NewLabel("• 包含用户信息和加密的密码数据"),
+		widget.NewLabel("• 包含用户信息、恢复码和加密的密码数据"),
 		widget.NewLabel("• 密码数据保持加密状态（使用 Key C）"),
 		widget.NewLabel("• 可用于账户迁移和灾难恢复"),
+		widget.NewLabel(backupExcludedNote),
 		widget.NewSeparator(),
 		widget.NewLabel("✅ 密码已加密，但备份文件包含完整账户信息"),
 		widget.NewLabel("⚠️ 请妥善保管备份文件"),
@@ -873,6 +888,9 @@ func showBackupDialog() {
 	}, myWindow)
 }
 
+// backupExcludedNote 备份不包含的数据，在备份和恢复对话框中提示
+const backupExcludedNote = "⚠️ 不包含: 条目历史版本、附件、回收站中的条目"
+
 // BackupUserInfo 备份用户信息
 type BackupUserInfo struct {
 	Username  string `json:"username"`
@@ -883,6 +901,23 @@ type BackupUserInfo struct {
 	EncM      string `json:"enc_m"`
 	EncB      string `json:"enc_b"`
 	EncC      string `json:"enc_c"`
+	PassSalt  string `json:"pass_salt,omitempty"`
+	EncR      string `json:"enc_r,omitempty"`
+	EncMB     string `json:"enc_mb,omitempty"`
+	EncA      string `json:"enc_a,omitempty"`
+	EncW      string `json:"enc_w,omitempty"`
+	EncMW     string `json:"enc_mw,omitempty"`
+	// EncQuestions 加密的密保问题 (新版本用户的 Question1-3 为空)
+	EncQuestions string `json:"enc_questions,omitempty"`
+	// RecoveryCodes 恢复码记录 (各自加密的 Key W)，缺少时恢复后已打印的恢复码失效
+	RecoveryCodes []BackupRecoveryCode `json:"recovery_codes,omitempty"`
+}
+
+// BackupRecoveryCode 备份的恢复码记录 - 恢复码本身不存储
+type BackupRecoveryCode struct {
+	Salt   string `json:"salt"`
+	EncW   string `json:"enc_w"`
+	UsedAt string `json:"used_at,omitempty"` // RFC 3339，未使用时为空
 }
 
 // BackupData 备份数据结构 - 存储加密的密码数据
@@ -909,6 +944,12 @@ func performBackup() {
 		return
 	}
 
+	codes, err := authService.GetRecoveryCodes(currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取恢复码失败: %v", err), myWindow)
+		return
+	}
+
 	// 直接从数据库获取加密数据
 	dbItems, err := vaultManager.GetEncryptedItems(currentUser)
 	if err != nil {
@@ -921,18 +962,36 @@ func performBackup() {
 		Version:  "2.0", // 版本号升级，包含用户信息
 		ExportAt: time.Now().Format("2006-01-02 15:04:05"),
 		User: BackupUserInfo{
-			Username:  user.Username,
-			Salt:      hex.EncodeToString(user.Salt),
-			Question1: user.Question1,
-			Question2: user.Question2,
-			Question3: user.Question3,
-			EncM:      hex.EncodeToString(user.EncM),
-			EncB:      hex.EncodeToString(user.EncB),
-			EncC:      hex.EncodeToString(user.EncC),
+			Username:     user.Username,
+			Salt:         hex.EncodeToString(user.Salt),
+			Question1:    user.Question1,
+			Question2:    user.Question2,
+			Question3:    user.Question3,
+			EncM:         hex.EncodeToString(user.EncM),
+			EncB:         hex.EncodeToString(user.EncB),
+			EncC:         hex.EncodeToString(user.EncC),
+			PassSalt:     hex.EncodeToString(user.PassSalt),
+			EncR:         hex.EncodeToString(user.EncR),
+			EncMB:        hex.EncodeToString(user.EncMB),
+			EncA:         hex.EncodeToString(user.EncA),
+			EncW:         hex.EncodeToString(user.EncW),
+			EncMW:        hex.EncodeToString(user.EncMW),
+			EncQuestions: hex.EncodeToString(user.EncQuestions),
 		},
 		Items: make([]BackupItemEncrypted, 0, len(dbItems)),
 	}
 
+	for _, c := range codes {
+		rc := BackupRecoveryCode{
+			Salt: hex.EncodeToString(c.Salt),
+			EncW: hex.EncodeToString(c.EncW),
+		}
+		if c.UsedAt.Valid {
+			rc.UsedAt = c.UsedAt.Time.Format(time.RFC3339)
+		}
+		backup.User.RecoveryCodes = append(backup.User.RecoveryCodes, rc)
+	}
+
 	for _, item := range dbItems {
 		backup.Items = append(backup.Items, BackupItemEncrypted{
 			Site:    item.Site,
@@ -965,6 +1024,7 @@ func performBackup() {
 			return
 		}
 
+		logEvent(audit.EventBackup, fmt.Sprintf("%d items exported", len(dbItems)))
 		dialog.ShowInformation("备份成功",
 			fmt.Sprintf("已导出账户和 %d 条密码记录！\n\n✅ 密码已加密，可用于账户迁移和恢复", len(dbItems)),
 			myWindow)
@@ -984,6 +1044,7 @@ func showRestoreDialogBeforeLogin() {
 		widget.NewLabel("• 备份文件包含用户信息和加密的密码"),
 		widget.NewLabel("• 将创建或覆盖同名账户"),
 		widget.NewLabel("• 恢复后可直接使用原 TOTP 登录"),
+		widget.NewLabel(backupExcludedNote),
 		widget.NewSeparator(),
 		widget.NewLabel("⚠️ 如果账户已存在，数据将被覆盖！"),
 	)
@@ -1029,14 +1090,21 @@ func performRestoreWithoutLogin() {
 
 		// 恢复用户信息
 		user := &db.User{
-			Username:  backup.User.Username,
-			Salt:      mustDecodeHex(backup.User.Salt),
-			Question1: backup.User.Question1,
-			Question2: backup.User.Question2,
-			Question3: backup.User.Question3,
-			EncM:      mustDecodeHex(backup.User.EncM),
-			EncB:      mustDecodeHex(backup.User.EncB),
-			EncC:      mustDecodeHex(backup.User.EncC),
+			Username:     backup.User.Username,
+			Salt:         mustDecodeHex(backup.User.Salt),
+			Question1:    backup.User.Question1,
+			Question2:    backup.User.Question2,
+			Question3:    backup.User.Question3,
+			EncM:         mustDecodeHex(backup.User.EncM),
+			EncB:         mustDecodeHex(backup.User.EncB),
+			EncC:         mustDecodeHex(backup.User.EncC),
+			PassSalt:     mustDecodeHex(backup.User.PassSalt),
+			EncR:         mustDecodeHex(backup.User.EncR),
+			EncMB:        mustDecodeHex(backup.User.EncMB),
+			EncA:         mustDecodeHex(backup.User.EncA),
+			EncW:         mustDecodeHex(backup.User.EncW),
+			EncMW:        mustDecodeHex(backup.User.EncMW),
+			EncQuestions: mustDecodeHex(backup.User.EncQuestions),
 		}
 
 		// 检查用户是否存在
@@ -1052,12 +1120,12 @@ func performRestoreWithoutLogin() {
 						authService.DeleteUser(backup.User.Username)
 						vaultManager.DeleteAllItems(backup.User.Username)
 						// 继续恢复
-						continueRestore(user, backup.Items)
+						continueRestore(user, backup.User.RecoveryCodes, backup.Items)
 					}
 				}, myWindow)
 		} else {
 			// 用户不存在，直接恢复
-			continueRestore(user, backup.Items)
+			continueRestore(user, backup.User.RecoveryCodes, backup.Items)
 		}
 	}, myWindow)
 
@@ -1071,9 +1139,18 @@ func mustDecodeHex(s string) []byte {
 }
 
 // continueRestore 继续恢复流程
-func continueRestore(user *db.User, items []BackupItemEncrypted) {
-	// 创建用户
-	if err := authService.RestoreUser(user); err != nil {
+func continueRestore(user *db.User, backupCodes []BackupRecoveryCode, items []BackupItemEncrypted) {
+	codes := make([]db.RecoveryCode, 0, len(backupCodes))
+	for _, c := range backupCodes {
+		rc := db.RecoveryCode{Salt: mustDecodeHex(c.Salt), EncW: mustDecodeHex(c.EncW)}
+		if t, err := time.Parse(time.RFC3339, c.UsedAt); err == nil {
+			rc.UsedAt = sql.NullTime{Time: t, Valid: true}
+		}
+		codes = append(codes, rc)
+	}
+
+	// 创建用户及恢复码记录
+	if err := authService.RestoreUser(user, codes); err != nil {
 		dialog.ShowError(fmt.Errorf("恢复用户失败: %v", err), myWindow)
 		return
 	}
@@ -1091,14 +1168,24 @@ func continueRestore(user *db.User, items []BackupItemEncrypted) {
 		}
 	}
 
+	// 未登录，没有 Key C，暂存事件待下次登录并入审计日志
+	auditLog.RecordPending(user.Username, audit.EventRestore,
+		fmt.Sprintf("account restored from backup, %d items imported, %d failed", successCount, failCount))
+
+	// 旧版本备份不含恢复码记录，已打印的恢复码在恢复后失效
+	var note string
+	if len(user.EncW) > 0 && len(codes) == 0 {
+		note = "\n\n⚠️ 备份中没有恢复码，原有恢复码已失效，请登录后在「账户安全」中重新生成"
+	}
+
 	// 显示结果
 	if failCount > 0 {
 		dialog.ShowInformation("恢复完成",
-			fmt.Sprintf("账户: %s\n成功导入: %d 条\n失败: %d 条\n\n请使用原 TOTP 登录", user.Username, successCount, failCount),
+			fmt.Sprintf("账户: %s\n成功导入: %d 条\n失败: %d 条\n\n请使用原 TOTP 登录%s", user.Username, successCount, failCount, note),
 			myWindow)
 	} else {
 		dialog.ShowInformation("恢复成功",
-			fmt.Sprintf("账户 '%s' 恢复成功！\n成功导入 %d 条密码记录\n\n请使用原 TOTP 登录", user.Username, successCount),
+			fmt.Sprintf("账户 '%s' 恢复成功！\n成功导入 %d 条密码记录\n\n请使用原 TOTP 登录%s", user.Username, successCount, note),
 			myWindow)
 	}
 }
@@ -1112,6 +1199,7 @@ func showRestoreDialog() {
 		widget.NewLabel("• 备份文件中的密码已加密"),
 		widget.NewLabel("• 数据将追加到当前账户中"),
 		widget.NewLabel("• 不会覆盖或删除现有数据"),
+		widget.NewLabel(backupExcludedNote),
 		widget.NewSeparator(),
 		widget.NewLabel("点击「确认」后选择备份文件进行恢复"),
 	)
@@ -1169,6 +1257,8 @@ func performRestore() {
 			}
 		}
 
+		logEvent(audit.EventRestore, fmt.Sprintf("%d items imported, %d failed", successCount, failCount))
+
 		// 显示结果
 		if failCount > 0 {
 			dialog.ShowInformation("恢复完成",
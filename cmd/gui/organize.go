@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/vault"
+)
+
+// 筛选栏节点 ID: 固定节点，以及带前缀的文件夹路径和标签
+const (
+	sidebarAll       = "all"
+	sidebarFavorites = "favorites"
+	sidebarFolders   = "folders"
+	sidebarTags      = "tags"
+	folderUIDPrefix  = "folder:"
+	tagUIDPrefix     = "tag:"
+)
+
+var (
+	// vaultFilter 当前的文件夹/标签/收藏筛选，与搜索词一样在锁定后解锁时恢复
+	vaultFilter vault.ItemFilter
+
+	// vaultFolders 密码库中已有的文件夹，供添加/编辑对话框选择
+	vaultFolders []string
+)
+
+// vaultSidebar 密码库左侧的筛选栏: 全部、收藏、文件夹树和标签。
+type vaultSidebar struct {
+	tree   *widget.Tree
+	tags   []string
+	counts map[string]int // 节点 ID -> 条目数
+}
+
+// newVaultSidebar 创建筛选栏，选择节点后更新 vaultFilter 并调用 onChanged。
+func newVaultSidebar(onChanged func()) *vaultSidebar {
+	s := &vaultSidebar{counts: map[string]int{}}
+	s.tree = widget.NewTree(s.childUIDs, s.isBranch,
+		func(bool) fyne.CanvasObject { return widget.NewLabel("") },
+		func(uid widget.TreeNodeID, _ bool, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(s.label(uid))
+		})
+	s.tree.OpenBranch(sidebarFolders)
+	s.tree.OpenBranch(sidebarTags)
+	s.tree.OnSelected = func(uid widget.TreeNodeID) {
+		if uid == sidebarFolders || uid == sidebarTags {
+			// 分组标题只用于展开/折叠
+			s.tree.ToggleBranch(uid)
+			s.tree.Select(filterUID(vaultFilter))
+			return
+		}
+		f := filterFromUID(uid)
+		if f == vaultFilter {
+			return
+		}
+		vaultFilter = f
+		onChanged()
+	}
+	return s
+}
+
+// update 根据当前条目重建文件夹树和标签列表。
+// 筛选的文件夹或标签已不存在时 (例如被改名) 回到全部条目。
+func (s *vaultSidebar) update(items []vault.VaultItem) {
+	vaultFolders = vault.Folders(items)
+	tags, tagCounts := vault.Tags(items)
+	s.tags = tags
+
+	s.counts = map[string]int{sidebarAll: len(items)}
+	for _, item := range items {
+		if item.Favorite {
+			s.counts[sidebarFavorites]++
+		}
+	}
+	for _, folder := range vaultFolders {
+		s.counts[folderUIDPrefix+folder] = len(vault.ItemFilter{Folder: folder}.Apply(items))
+	}
+	for _, tag := range tags {
+		s.counts[tagUIDPrefix+tag] = tagCounts[tag]
+	}
+
+	if _, ok := s.counts[filterUID(vaultFilter)]; !ok && !vaultFilter.Favorites {
+		vaultFilter = vault.ItemFilter{}
+	}
+	s.tree.Refresh()
+	s.tree.Select(filterUID(vaultFilter))
+}
+
+func (s *vaultSidebar) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	switch {
+	case uid == "":
+		ids := []widget.TreeNodeID{sidebarAll, sidebarFavorites}
+		if len(vaultFolders) > 0 {
+			ids = append(ids, sidebarFolders)
+		}
+		if len(s.tags) > 0 {
+			ids = append(ids, sidebarTags)
+		}
+		return ids
+	case uid == sidebarFolders:
+		return subfolderUIDs("")
+	case strings.HasPrefix(uid, folderUIDPrefix):
+		return subfolderUIDs(strings.TrimPrefix(uid, folderUIDPrefix))
+	case uid == sidebarTags:
+		ids := make([]widget.TreeNodeID, len(s.tags))
+		for i, tag := range s.tags {
+			ids[i] = tagUIDPrefix + tag
+		}
+		return ids
+	}
+	return nil
+}
+
+func (s *vaultSidebar) isBranch(uid widget.TreeNodeID) bool {
+	return uid == "" || len(s.childUIDs(uid)) > 0
+}
+
+func (s *vaultSidebar) label(uid widget.TreeNodeID) string {
+	switch {
+	case uid == sidebarAll:
+		return fmt.Sprintf("📋 全部 (%d)", s.counts[uid])
+	case uid == sidebarFavorites:
+		return fmt.Sprintf("★ 收藏 (%d)", s.counts[uid])
+	case uid == sidebarFolders:
+		return "📁 文件夹"
+	case uid == sidebarTags:
+		return "🏷 标签"
+	case strings.HasPrefix(uid, folderUIDPrefix):
+		path := strings.TrimPrefix(uid, folderUIDPrefix)
+		name := path[strings.LastIndex(path, vault.FolderSeparator)+1:]
+		return fmt.Sprintf("%s (%d)", name, s.counts[uid])
+	case strings.HasPrefix(uid, tagUIDPrefix):
+		return fmt.Sprintf("#%s (%d)", strings.TrimPrefix(uid, tagUIDPrefix), s.counts[uid])
+	}
+	return uid
+}
+
+// subfolderUIDs 返回 parent 的直接子文件夹 (parent 为空时返回顶层文件夹)。
+func subfolderUIDs(parent string) []widget.TreeNodeID {
+	prefix := ""
+	if parent != "" {
+		prefix = parent + vault.FolderSeparator
+	}
+	var ids []widget.TreeNodeID
+	for _, folder := range vaultFolders {
+		rest, ok := strings.CutPrefix(folder, prefix)
+		if ok && rest != "" && !strings.Contains(rest, vault.FolderSeparator) {
+			ids = append(ids, folderUIDPrefix+folder)
+		}
+	}
+	return ids
+}
+
+func filterUID(f vault.ItemFilter) widget.TreeNodeID {
+	switch {
+	case f.Favorites:
+		return sidebarFavorites
+	case f.Folder != "":
+		return folderUIDPrefix + f.Folder
+	case f.Tag != "":
+		return tagUIDPrefix + f.Tag
+	}
+	return sidebarAll
+}
+
+func filterFromUID(uid widget.TreeNodeID) vault.ItemFilter {
+	switch {
+	case uid == sidebarFavorites:
+		return vault.ItemFilter{Favorites: true}
+	case strings.HasPrefix(uid, folderUIDPrefix):
+		return vault.ItemFilter{Folder: strings.TrimPrefix(uid, folderUIDPrefix)}
+	case strings.HasPrefix(uid, tagUIDPrefix):
+		return vault.ItemFilter{Tag: strings.TrimPrefix(uid, tagUIDPrefix)}
+	}
+	return vault.ItemFilter{}
+}
+
+// organizeFields 添加/编辑对话框中的文件夹、标签和收藏输入。
+type organizeFields struct {
+	folder   *widget.SelectEntry
+	tags     *widget.Entry
+	favorite *widget.Check
+}
+
+func newOrganizeFields(item vault.VaultItem) *organizeFields {
+	f := &organizeFields{
+		folder:   widget.NewSelectEntry(vaultFolders),
+		tags:     widget.NewEntry(),
+		favorite: widget.NewCheck("收藏", nil),
+	}
+	f.folder.PlaceHolder = "文件夹，如 工作/服务器 (可留空)"
+	f.folder.SetText(item.Folder)
+	f.tags.PlaceHolder = "标签，逗号分隔 (可留空)"
+	f.tags.SetText(strings.Join(item.Tags, ", "))
+	f.favorite.SetChecked(item.Favorite)
+	return f
+}
+
+// apply 将输入写回条目。
+func (f *organizeFields) apply(item *vault.VaultItem) {
+	item.Folder = vault.NormalizeFolder(f.folder.Text)
+	item.Tags = vault.ParseTags(f.tags.Text)
+	item.Favorite = f.favorite.Checked
+}
+
+// newFavoriteButton 详情栏中的收藏切换按钮。
+func newFavoriteButton(item vault.VaultItem, refreshCallback func()) *widget.Button {
+	text := "☆"
+	if item.Favorite {
+		text = "★"
+	}
+	return widget.NewButton(text, func() {
+		if err := vaultManager.SetFavorite(currentKeyC, item, !item.Favorite); err != nil {
+			dialog.ShowError(fmt.Errorf("更新失败: %v", err), myWindow)
+			return
+		}
+		refreshCallback()
+	})
+}
+
+// newSidebarPanel 筛选栏及其标题。
+func newSidebarPanel(s *vaultSidebar) fyne.CanvasObject {
+	return container.NewBorder(
+		widget.NewLabelWithStyle("分类", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		nil, nil, nil,
+		s.tree,
+	)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newRecoveryCodesBox 展示一次性恢复码，并提供复制按钮
+func newRecoveryCodesBox(codes []string) fyne.CanvasObject {
+	text := strings.Join(codes, "\n")
+
+	codesEntry := widget.NewMultiLineEntry()
+	codesEntry.SetText(text)
+	codesEntry.SetMinRowsVisible(len(codes))
+	codesEntry.TextStyle = fyne.TextStyle{Monospace: true}
+
+	btnCopy := widget.NewButton("复制恢复码", func() {
+		copySecret(text, "恢复码")
+	})
+
+	return container.NewVBox(
+		widget.NewLabel("一次性恢复码 (每个只能使用一次，可代替密保答案重置 Key B):"),
+		codesEntry,
+		btnCopy,
+	)
+}
+
+// showRedeemRecoveryCodeDialog 使用恢复码代替密保答案重置 Key B
+func showRedeemRecoveryCodeDialog() {
+	entryUser := widget.NewEntry()
+	entryUser.PlaceHolder = "用户名"
+	entryCode := widget.NewEntry()
+	entryCode.PlaceHolder = "XXXXX-XXXXX"
+
+	dialog.ShowForm("使用恢复码重置", "重置", "取消", []*widget.FormItem{
+		widget.NewFormItem("用户名", entryUser),
+		widget.NewFormItem("恢复码", entryCode),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		res, err := authService.RedeemRecoveryCode(entryUser.Text, entryCode.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("重置失败: %v", err), myWindow)
+			return
+		}
+		showResetSuccessDialog(res)
+	}, myWindow)
+}
+
+// showRegenerateRecoveryCodesDialog 重新生成恢复码，旧恢复码全部作废
+func showRegenerateRecoveryCodesDialog() {
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "未启用主密码请留空"
+
+	dialog.ShowForm("重新生成恢复码", "生成", "取消", []*widget.FormItem{
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("主密码", entryPass),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		codes, err := authService.RegenerateRecoveryCodes(currentUser, entryOTP.Text, entryPass.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("生成失败: %v", err), myWindow)
+			return
+		}
+
+		d := dialog.NewCustom("新的恢复码", "关闭", container.NewVBox(
+			widget.NewLabelWithStyle("旧的恢复码已全部作废", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			newRecoveryCodesBox(codes),
+		), myWindow)
+		d.Resize(fyne.NewSize(450, 450))
+		d.Show()
+	}, myWindow)
+}
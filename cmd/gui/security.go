@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/auth"
+	"key-box/internal/db"
+)
+
+// showSecurityDialog 显示账户安全设置 (主密码、自动锁定、剪贴板、历史版本、回收站、恢复码、密保问题、用户名、注销账户)
+func showSecurityDialog() {
+	enabled, err := authService.PassphraseEnabled(currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取账户信息失败: %v", err), myWindow)
+		return
+	}
+
+	remaining, err := authService.RemainingRecoveryCodes(currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取恢复码失败: %v", err), myWindow)
+		return
+	}
+
+	var d dialog.Dialog
+
+	status := "主密码: 未启用（登录仅需 OTP 验证码）"
+	if enabled {
+		status = "主密码: 已启用（登录需要 OTP 验证码 + 主密码）"
+	}
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle("🔑 主密码", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel(status),
+		widget.NewLabel("主密码经 Argon2id 拉伸后与 Key B 共同保护数据密钥，\n仅泄露 TOTP 种子不足以解锁密码库。"),
+		widget.NewSeparator(),
+	)
+
+	if enabled {
+		content.Add(widget.NewButtonWithIcon("修改主密码", theme.DocumentCreateIcon(), func() {
+			d.Hide()
+			showChangePassphraseDialog()
+		}))
+		content.Add(widget.NewButtonWithIcon("关闭主密码", theme.CancelIcon(), func() {
+			d.Hide()
+			showDisablePassphraseDialog()
+		}))
+	} else {
+		btnEnable := widget.NewButtonWithIcon("启用主密码", theme.ConfirmIcon(), func() {
+			d.Hide()
+			showEnablePassphraseDialog()
+		})
+		btnEnable.Importance = widget.HighImportance
+		content.Add(btnEnable)
+	}
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("🔒 自动锁定", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newAutoLockSettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("📋 剪贴板", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newClipboardSettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("⚡ 快速访问", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newLauncherSettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("🖥 系统托盘", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newTraySettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("🕘 历史版本", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newHistorySettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("🗑 回收站", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(newTrashSettingsBox())
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("🧾 恢复码", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(widget.NewLabel(fmt.Sprintf("剩余可用恢复码: %d / %d 个", remaining, auth.RecoveryCodeCount)))
+	content.Add(widget.NewButtonWithIcon("重新生成恢复码", theme.ViewRefreshIcon(), func() {
+		d.Hide()
+		showRegenerateRecoveryCodesDialog()
+	}))
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("❓ 密保问题", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(widget.NewButtonWithIcon("修改密保问题", theme.DocumentCreateIcon(), func() {
+		d.Hide()
+		showChangeSecurityQuestionsDialog()
+	}))
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("👤 用户名", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	content.Add(widget.NewButtonWithIcon("修改用户名", theme.AccountIcon(), func() {
+		d.Hide()
+		showRenameUserDialog()
+	}))
+
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("⚠️ 危险操作", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	btnDelete := widget.NewButtonWithIcon("注销账户", theme.DeleteIcon(), func() {
+		d.Hide()
+		showDeleteAccountDialog()
+	})
+	btnDelete.Importance = widget.DangerImportance
+	content.Add(btnDelete)
+
+	d = dialog.NewCustom("账户安全", "关闭", container.NewVScroll(content), myWindow)
+	d.Resize(fyne.NewSize(450, 640))
+	d.Show()
+}
+
+func showEnablePassphraseDialog() {
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryNew := widget.NewPasswordEntry()
+	entryNew.PlaceHolder = fmt.Sprintf("至少 %d 位", auth.MinPassphraseLength)
+	entryConfirm := widget.NewPasswordEntry()
+
+	dialog.ShowForm("启用主密码", "启用", "取消", []*widget.FormItem{
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("新主密码", entryNew),
+		widget.NewFormItem("确认主密码", entryConfirm),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if entryNew.Text != entryConfirm.Text {
+			dialog.ShowError(fmt.Errorf("两次输入的主密码不一致"), myWindow)
+			return
+		}
+		if err := authService.EnablePassphrase(currentUser, entryOTP.Text, entryNew.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("启用失败: %v", err), myWindow)
+			return
+		}
+		dialog.ShowInformation("成功", "主密码已启用，下次登录需同时输入 OTP 验证码和主密码。\n\n忘记主密码时可通过密保问题重置。", myWindow)
+	}, myWindow)
+}
+
+func showChangePassphraseDialog() {
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryOld := widget.NewPasswordEntry()
+	entryNew := widget.NewPasswordEntry()
+	entryNew.PlaceHolder = fmt.Sprintf("至少 %d 位", auth.MinPassphraseLength)
+	entryConfirm := widget.NewPasswordEntry()
+
+	dialog.ShowForm("修改主密码", "修改", "取消", []*widget.FormItem{
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("当前主密码", entryOld),
+		widget.NewFormItem("新主密码", entryNew),
+		widget.NewFormItem("确认主密码", entryConfirm),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if entryNew.Text != entryConfirm.Text {
+			dialog.ShowError(fmt.Errorf("两次输入的主密码不一致"), myWindow)
+			return
+		}
+		if err := authService.ChangePassphrase(currentUser, entryOTP.Text, entryOld.Text, entryNew.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("修改失败: %v", err), myWindow)
+			return
+		}
+		dialog.ShowInformation("成功", "主密码已修改", myWindow)
+	}, myWindow)
+}
+
+func showDisablePassphraseDialog() {
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+
+	dialog.ShowForm("关闭主密码", "关闭", "取消", []*widget.FormItem{
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("当前主密码", entryPass),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if err := authService.DisablePassphrase(currentUser, entryOTP.Text, entryPass.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("关闭失败: %v", err), myWindow)
+			return
+		}
+		dialog.ShowInformation("成功", "主密码已关闭，登录仅需 OTP 验证码", myWindow)
+	}, myWindow)
+}
+
+// showChangeSecurityQuestionsDialog 修改密保问题，可用当前答案或 OTP 验证身份
+func showChangeSecurityQuestionsDialog() {
+	qs, err := authService.GetSecurityQuestions(currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取密保问题失败: %v", err), myWindow)
+		return
+	}
+
+	// 身份验证: 当前答案
+	oldAnswers := make([]*widget.Entry, 3)
+	answersBox := container.NewVBox()
+	for i, q := range qs {
+		oldAnswers[i] = widget.NewEntry()
+		oldAnswers[i].PlaceHolder = fmt.Sprintf("当前答案 %d", i+1)
+		answersBox.Add(widget.NewLabel(fmt.Sprintf("问题 %d: %s", i+1, q)))
+		answersBox.Add(oldAnswers[i])
+	}
+
+	// 身份验证: OTP (+ 主密码)
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "主密码 (未启用请留空)"
+	otpBox := container.NewVBox(entryOTP, entryPass)
+	otpBox.Hide()
+
+	const byAnswers, byOTP = "当前密保答案", "OTP 验证码"
+	mode := widget.NewRadioGroup([]string{byAnswers, byOTP}, func(selected string) {
+		if selected == byOTP {
+			answersBox.Hide()
+			otpBox.Show()
+		} else {
+			otpBox.Hide()
+			answersBox.Show()
+		}
+	})
+	mode.Horizontal = true
+	mode.SetSelected(byAnswers)
+
+	// 新问题和答案
+	newQuestions := make([]*widget.Select, 3)
+	newAnswers := make([]*widget.Entry, 3)
+	newBox := container.NewVBox()
+	for i := range newQuestions {
+		newQuestions[i] = widget.NewSelect(auth.SecurityQuestionPool, nil)
+		newQuestions[i].PlaceHolder = fmt.Sprintf("选择新密保问题 %d", i+1)
+		newAnswers[i] = widget.NewEntry()
+		newAnswers[i].PlaceHolder = fmt.Sprintf("新答案 %d", i+1)
+		newBox.Add(newQuestions[i])
+		newBox.Add(newAnswers[i])
+	}
+
+	var d dialog.Dialog
+
+	btnSubmit := widget.NewButton("提交修改", func() {
+		var proof auth.SecurityProof
+		if mode.Selected == byOTP {
+			proof.Code = entryOTP.Text
+			proof.Passphrase = entryPass.Text
+		} else {
+			for _, e := range oldAnswers {
+				proof.Answers = append(proof.Answers, e.Text)
+			}
+		}
+		questions := make([]string, 3)
+		answers := make([]string, 3)
+		for i := range questions {
+			questions[i] = newQuestions[i].Selected
+			answers[i] = newAnswers[i].Text
+		}
+
+		if err := authService.ChangeSecurityQuestions(currentUser, proof, questions, answers); err != nil {
+			dialog.ShowError(fmt.Errorf("修改失败: %v", err), myWindow)
+			return
+		}
+		d.Hide()
+		dialog.ShowInformation("成功", "密保问题已修改，旧答案已失效。\n\nKey B、主密码和恢复码保持不变。", myWindow)
+	})
+	btnSubmit.Importance = widget.HighImportance
+
+	content := container.NewVScroll(container.NewVBox(
+		widget.NewLabel("身份验证方式:"),
+		mode,
+		answersBox,
+		otpBox,
+		widget.NewSeparator(),
+		newBox,
+		btnSubmit,
+	))
+	content.SetMinSize(fyne.NewSize(400, 400))
+
+	d = dialog.NewCustom("修改密保问题", "取消", content, myWindow)
+	d.Resize(fyne.NewSize(500, 550))
+	d.Show()
+}
+
+// showRenameUserDialog 修改用户名，成功后显示新的 Key B 和 otpauth URI
+func showRenameUserDialog() {
+	entryName := widget.NewEntry()
+	entryName.PlaceHolder = "新用户名"
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "未启用主密码请留空"
+
+	note := widget.NewLabel("修改用户名后将生成新的 Key B，需要在 TOTP 应用中重新绑定。")
+	note.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewForm("修改用户名", "修改", "取消", []*widget.FormItem{
+		widget.NewFormItem("", note),
+		widget.NewFormItem("新用户名", entryName),
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("主密码", entryPass),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		newName := strings.TrimSpace(entryName.Text)
+		res, err := authService.RenameUser(currentUser, newName, entryOTP.Text, entryPass.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("修改失败: %v", err), myWindow)
+			return
+		}
+		currentUser = newName
+		showVaultScreen()
+
+		keyBEntry := widget.NewEntryWithData(bindingString(res.SecretKeyBBase32))
+		uriEntry := widget.NewMultiLineEntry()
+		uriEntry.SetText(res.OTPAuthURI)
+		uriEntry.Wrapping = fyne.TextWrapBreak
+
+		btnCopyKey := widget.NewButton("复制 Key B", func() {
+			copySecret(res.SecretKeyBBase32, "Key B ")
+		})
+		btnCopyKey.Importance = widget.HighImportance
+		btnCopyURI := widget.NewButton("复制 otpauth URI", func() {
+			copySecret(res.OTPAuthURI, "otpauth URI ")
+		})
+
+		dSuccess := dialog.NewCustom("用户名已修改", "关闭", container.NewVBox(
+			widget.NewLabelWithStyle(fmt.Sprintf("✅ 用户名已修改为 '%s'", newName), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewSeparator(),
+			widget.NewLabel("您的新登录凭证 (Key B):"),
+			keyBEntry,
+			btnCopyKey,
+			widget.NewLabel("otpauth URI (可生成二维码供 TOTP 应用扫描):"),
+			uriEntry,
+			btnCopyURI,
+			widget.NewSeparator(),
+			widget.NewLabel("⚠️ 旧的 Key B 已失效，请在 TOTP 应用中删除旧凭证并添加新的 Key B。"),
+		), myWindow)
+		dSuccess.Resize(fyne.NewSize(520, 480))
+		dSuccess.Show()
+	}, myWindow)
+	d.Resize(fyne.NewSize(450, 320))
+	d.Show()
+}
+
+// showDeleteAccountDialog 注销账户，删除该用户的全部数据
+func showDeleteAccountDialog() {
+	counts, err := authService.AccountDataCounts(currentUser)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取数据失败: %v", err), myWindow)
+		return
+	}
+
+	entryConfirm := widget.NewEntry()
+	entryConfirm.PlaceHolder = currentUser
+	entryOTP := widget.NewEntry()
+	entryOTP.PlaceHolder = "6位 OTP 验证码"
+	entryPass := widget.NewPasswordEntry()
+	entryPass.PlaceHolder = "未启用主密码请留空"
+
+	warning := widget.NewLabel(fmt.Sprintf(
+		"将永久删除账户 '%s' 及其 %s、恢复码等全部数据。\n"+
+			"删除的数据会从数据库文件中擦除，无法恢复！\n\n"+
+			"如需保留数据，请先使用「备份」导出。", currentUser, describeUserData(counts)))
+	warning.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewForm("注销账户", "永久删除", "取消", []*widget.FormItem{
+		widget.NewFormItem("", warning),
+		widget.NewFormItem("输入用户名确认", entryConfirm),
+		widget.NewFormItem("OTP 验证码", entryOTP),
+		widget.NewFormItem("主密码", entryPass),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if entryConfirm.Text != currentUser {
+			dialog.ShowError(fmt.Errorf("用户名不匹配，已取消"), myWindow)
+			return
+		}
+		deleted, err := authService.DeleteAccount(currentUser, entryOTP.Text, entryPass.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("注销失败: %v", err), myWindow)
+			return
+		}
+		username := currentUser
+		logout()
+		dialog.ShowInformation("账户已注销",
+			fmt.Sprintf("账户 '%s' 已删除，共擦除 %s。", username, describeUserData(deleted)), myWindow)
+	}, myWindow)
+	d.Resize(fyne.NewSize(480, 380))
+	d.Show()
+}
+
+// describeUserData 账户数据统计的文字说明
+func describeUserData(c db.UserDataCounts) string {
+	return fmt.Sprintf("%d 条密码记录、回收站中 %d 条、%d 个历史版本、%d 个附件", c.Items, c.Trashed, c.History, c.Attachments)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/strength"
+	"key-box/internal/vault"
+)
+
+// newStrengthMeter 密码强度条: 随密码输入实时评估，显示等级、离线破解时间和改进建议。
+// related 为网站、用户名等输入框，其内容作为额外词典 (密码中包含它们视为极弱)。
+func newStrengthMeter(entryPass *widget.Entry, related ...*widget.Entry) fyne.CanvasObject {
+	bar := widget.NewProgressBar()
+	bar.Max = strength.ScoreVeryStrong + 1
+	bar.TextFormatter = func() string { return "" }
+
+	labelScore := widget.NewLabel("")
+	labelFeedback := widget.NewLabel("")
+	labelFeedback.Importance = widget.WarningImportance
+
+	update := func() {
+		if entryPass.Text == "" {
+			bar.SetValue(0)
+			labelScore.SetText("强度: -")
+			labelFeedback.SetText("")
+			labelFeedback.Hide()
+			return
+		}
+
+		inputs := make([]string, 0, len(related))
+		for _, e := range related {
+			inputs = append(inputs, e.Text)
+		}
+		res := strength.Estimate(entryPass.Text, inputs...)
+
+		bar.SetValue(float64(res.Score + 1))
+		labelScore.SetText("强度: " + strength.ScoreLabel(res.Score) +
+			"  (离线破解约 " + strength.DisplayTime(res.CrackTimes.OfflineSlowHash) + ")")
+		labelScore.Importance = strengthImportance(res.Score)
+		labelScore.Refresh()
+
+		var lines []string
+		if res.Warning != "" {
+			lines = append(lines, "⚠️ "+res.Warning)
+		}
+		for _, s := range res.Suggestions {
+			lines = append(lines, "• "+s)
+		}
+		labelFeedback.SetText(strings.Join(lines, "\n"))
+		labelFeedback.Hidden = len(lines) == 0
+		labelFeedback.Refresh()
+	}
+
+	// 保留输入框原有的回调
+	chain := func(e *widget.Entry) {
+		prev := e.OnChanged
+		e.OnChanged = func(s string) {
+			if prev != nil {
+				prev(s)
+			}
+			update()
+		}
+	}
+	chain(entryPass)
+	for _, e := range related {
+		chain(e)
+	}
+	update()
+
+	return container.NewVBox(bar, labelScore, labelFeedback)
+}
+
+// newStrengthBadge 详情栏中密码的强度等级
+func newStrengthBadge(item vault.VaultItem) fyne.CanvasObject {
+	res := strength.Estimate(item.Password, item.Site, item.Username)
+	label := widget.NewLabel(strength.ScoreLabel(res.Score))
+	label.Importance = strengthImportance(res.Score)
+	return label
+}
+
+// strengthImportance 等级对应的颜色: 0-1 红色，2 黄色，3-4 绿色
+func strengthImportance(score int) widget.Importance {
+	switch {
+	case score <= strength.ScoreWeak:
+		return widget.DangerImportance
+	case score == strength.ScoreFair:
+		return widget.WarningImportance
+	default:
+		return widget.SuccessImportance
+	}
+}
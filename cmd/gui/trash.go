@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/audit"
+)
+
+// purgeExpiredTrash 登录后永久删除在回收站中超过保留天数的条目
+func purgeExpiredTrash() {
+	n, err := vaultManager.PurgeExpiredTrash(currentUser, settings.TrashRetentionDays)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("清理回收站失败: %v", err), myWindow)
+		return
+	}
+	if n > 0 {
+		logEvent(audit.EventItemPurge, fmt.Sprintf("自动清理 %d 个超过 %d 天的条目", n, settings.TrashRetentionDays))
+	}
+}
+
+// showTrashDialog 回收站: 还原或永久删除已删除的条目
+func showTrashDialog(refreshCallback func()) {
+	list := container.NewVBox()
+	status := widget.NewLabel("")
+
+	var btnEmpty *widget.Button
+	var refresh func()
+	refresh = func() {
+		items, err := vaultManager.ListTrash(currentUser, currentKeyC)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("读取回收站失败: %v", err), myWindow)
+			return
+		}
+
+		list.RemoveAll()
+		if len(items) == 0 {
+			list.Add(container.NewCenter(widget.NewLabelWithStyle("回收站是空的", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})))
+			btnEmpty.Disable()
+		} else {
+			btnEmpty.Enable()
+		}
+		for _, item := range items {
+			item := item
+
+			btnRestore := widget.NewButtonWithIcon("还原", theme.ContentUndoIcon(), func() {
+				if err := vaultManager.RestoreItem(item.ID); err != nil {
+					dialog.ShowError(fmt.Errorf("还原失败: %v", err), myWindow)
+					return
+				}
+				logEvent(audit.EventItemRestore, item.Site)
+				refresh()
+				refreshCallback()
+			})
+			btnPurge := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("永久删除",
+					fmt.Sprintf("永久删除「%s」及其历史版本？此操作无法撤销。", item.Site),
+					func(ok bool) {
+						if !ok {
+							return
+						}
+						if err := vaultManager.PurgeItem(item.ID); err != nil {
+							dialog.ShowError(fmt.Errorf("删除失败: %v", err), myWindow)
+							return
+						}
+						logEvent(audit.EventItemPurge, item.Site)
+						refresh()
+					}, myWindow)
+			})
+			btnPurge.Importance = widget.DangerImportance
+
+			label := widget.NewLabel(fmt.Sprintf("%s | %s  —  删除于 %s", item.Site, item.Username, item.DeletedAt.Local().Format("2006-01-02 15:04")))
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(btnRestore, btnPurge), label))
+		}
+
+		if settings.TrashRetentionDays > 0 {
+			status.SetText(fmt.Sprintf("共 %d 个条目，删除 %d 天后自动永久删除 (可在「安全」中设置)。", len(items), settings.TrashRetentionDays))
+		} else {
+			status.SetText(fmt.Sprintf("共 %d 个条目，不自动删除 (可在「安全」中设置)。", len(items)))
+		}
+	}
+
+	btnEmpty = widget.NewButtonWithIcon("清空回收站", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm("清空回收站", "永久删除回收站中的所有条目及其历史版本？此操作无法撤销。", func(ok bool) {
+			if !ok {
+				return
+			}
+			n, err := vaultManager.EmptyTrash(currentUser)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("清空失败: %v", err), myWindow)
+				return
+			}
+			logEvent(audit.EventItemPurge, fmt.Sprintf("清空回收站 (%d 个条目)", n))
+			refresh()
+		}, myWindow)
+	})
+	btnEmpty.Importance = widget.DangerImportance
+	refresh()
+
+	content := container.NewBorder(
+		nil,
+		container.NewBorder(nil, nil, nil, btnEmpty, status),
+		nil, nil,
+		container.NewVScroll(list),
+	)
+
+	d := dialog.NewCustom("回收站", "关闭", content, myWindow)
+	d.Resize(fyne.NewSize(640, 440))
+	d.Show()
+}
+
+// newTrashSettingsBox 账户安全对话框中的回收站设置，修改后立即保存
+func newTrashSettingsBox() fyne.CanvasObject {
+	selectDays := newPresetSelect([]int{0, 7, 30, 90}, "天", settings.TrashRetentionDays, func(v int) {
+		settings.TrashRetentionDays = v
+		saveSettings()
+	})
+
+	return container.NewHBox(widget.NewLabel("删除的条目保留:"), selectDays)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+
+	"key-box/internal/generator"
+)
+
+// statusNotifierWatcher 系统托盘宿主在会话总线上的名称 (KDE、Xfce、带 AppIndicator 扩展的 GNOME 等)
+const statusNotifierWatcher = "org.kde.StatusNotifierWatcher"
+
+var (
+	// trayApp 支持系统托盘时为桌面应用，否则为 nil
+	trayApp desktop.App
+
+	trayIconLocked, trayIconUnlocked fyne.Resource
+)
+
+// initTray 创建系统托盘图标和菜单，并接管主窗口的关闭按钮。
+// 托盘图标显示锁定状态；菜单可立即锁定、打开密码库、打开快速访问、生成密码到剪贴板和退出。
+func initTray() {
+	desk, ok := myApp.(desktop.App)
+	if !ok {
+		return
+	}
+	trayApp = desk
+	trayIconLocked = newTrayIcon("tray-locked.png", true)
+	trayIconUnlocked = newTrayIcon("tray-unlocked.png", false)
+	updateTray()
+
+	myWindow.SetCloseIntercept(func() {
+		if settings.CloseToTray && statusNotifierAvailable() {
+			// 隐藏到托盘等同于最小化
+			if settings.LockOnBackground {
+				lockSession("minimize")
+			}
+			myWindow.Hide()
+			return
+		}
+		myWindow.Close()
+	})
+}
+
+// updateTray 按会话状态更新托盘图标和菜单 (登录、解锁、锁定、退出登录后调用)
+func updateTray() {
+	if trayApp == nil {
+		return
+	}
+	unlocked := currentUser != "" && currentKeyC != nil
+
+	status := "未登录"
+	icon := trayIconLocked
+	switch {
+	case unlocked:
+		status = fmt.Sprintf("🔓 已解锁: %s", currentUser)
+		icon = trayIconUnlocked
+	case currentUser != "":
+		status = fmt.Sprintf("🔒 已锁定: %s", currentUser)
+	}
+	statusItem := fyne.NewMenuItem(status, nil)
+	statusItem.Disabled = true
+
+	launcherLabel := "快速访问"
+	if settings.LauncherHotkey != "" {
+		launcherLabel += " (" + settings.LauncherHotkey + ")"
+	}
+	lockItem := fyne.NewMenuItem("立即锁定", func() {
+		lockSession("manual")
+	})
+	lockItem.Disabled = !unlocked
+	quitItem := fyne.NewMenuItem("退出", quitApp)
+	quitItem.IsQuit = true
+
+	trayApp.SetSystemTrayMenu(fyne.NewMenu("Key-Box",
+		statusItem,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("打开密码库", showMainWindow),
+		fyne.NewMenuItem(launcherLabel, showLauncher),
+		fyne.NewMenuItem("生成密码到剪贴板", copyGeneratedPassword),
+		lockItem,
+		fyne.NewMenuItemSeparator(),
+		quitItem,
+	))
+	if icon != nil {
+		trayApp.SetSystemTrayIcon(icon)
+	}
+}
+
+// showMainWindow 显示 (可能已隐藏到托盘的) 主窗口并获得焦点
+func showMainWindow() {
+	myWindow.Show()
+	myWindow.RequestFocus()
+}
+
+// quitApp 清除剪贴板中的敏感内容后退出
+func quitApp() {
+	clearClipboardSecret()
+	myApp.Quit()
+}
+
+// copyGeneratedPassword 按默认选项生成密码并复制到剪贴板，以系统通知提示 (主窗口可能已隐藏)。
+// 生成的密码不保存，按剪贴板设置自动清除。
+func copyGeneratedPassword() {
+	res, err := generator.Generate(generator.DefaultOptions())
+	if err == nil {
+		var msg string
+		msg, err = putSecret(res.Password, "新密码", settings.ClipboardClearSeconds)
+		if err == nil {
+			myApp.SendNotification(fyne.NewNotification("Key-Box", msg))
+			return
+		}
+	}
+	myApp.SendNotification(fyne.NewNotification("Key-Box", fmt.Sprintf("生成密码失败: %v", err)))
+}
+
+// newTraySettingsBox 账户安全对话框中的系统托盘设置，修改后立即保存
+func newTraySettingsBox() fyne.CanvasObject {
+	label := "关闭主窗口时最小化到系统托盘 (不退出)"
+	if trayApp == nil || !statusNotifierAvailable() {
+		label += " (未检测到系统托盘，关闭时仍退出)"
+	}
+	check := widget.NewCheck(label, func(on bool) {
+		settings.CloseToTray = on
+		saveSettings()
+	})
+	check.Checked = settings.CloseToTray
+	return check
+}
+
+// statusNotifierAvailable 会话总线上是否有系统托盘宿主。
+// 安全决策: 没有托盘时图标不会显示，关闭窗口若只是隐藏，程序 (及解锁的会话) 将无法找回，因此直接退出。
+func statusNotifierAvailable() bool {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	var has bool
+	err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, statusNotifierWatcher).Store(&has)
+	return err == nil && has
+}
+
+// newTrayIcon 绘制 64×64 的挂锁托盘图标 (PNG)。
+// Fyne 在 Linux 上把图标原样交给托盘，主题图标是 SVG 无法显示，因此直接绘制位图。
+// 锁定时锁梁闭合、灰色；解锁时锁梁打开、绿色。
+func newTrayIcon(name string, locked bool) fyne.Resource {
+	const size = 64
+	fill := color.NRGBA{R: 0x71, G: 0x80, B: 0x96, A: 0xff}
+	if !locked {
+		fill = color.NRGBA{R: 0x38, G: 0xa1, B: 0x69, A: 0xff}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	// 锁梁: 以 (32, cy) 为圆心的上半圆环，两侧竖直延伸到锁体；解锁时抬高且右侧断开
+	cy, rightLeg := 22, true
+	if !locked {
+		cy, rightLeg = 16, false
+	}
+	const outer, inner = 15, 9
+	for y := 0; y < 30; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := x-32, y-cy
+			d2 := dx*dx + dy*dy
+			ring := y <= cy && d2 <= outer*outer && d2 >= inner*inner
+			legs := y > cy && ((x >= 32-outer && x < 32-inner) || (rightLeg && x > 32+inner && x <= 32+outer))
+			if ring || legs {
+				img.SetNRGBA(x, y, fill)
+			}
+		}
+	}
+
+	// 锁体和锁孔
+	for y := 30; y < 60; y++ {
+		for x := 10; x < 54; x++ {
+			dx, dy := x-32, y-41
+			keyhole := dx*dx+dy*dy <= 16 || (x >= 30 && x <= 34 && y >= 41 && y <= 51)
+			if !keyhole {
+				img.SetNRGBA(x, y, fill)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		fyne.LogError("绘制托盘图标失败", err)
+		return nil
+	}
+	return fyne.NewStaticResource(name, buf.Bytes())
+}
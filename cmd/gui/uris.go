@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"key-box/internal/vault"
+)
+
+// uriEditor 添加/编辑对话框中的网址列表，每行一个网址及其匹配方式。
+type uriEditor struct {
+	box  *fyne.Container
+	rows []*uriRow
+}
+
+type uriRow struct {
+	entry *widget.Entry
+	mode  *widget.Select
+}
+
+func newURIEditor(uris []vault.URI) *uriEditor {
+	e := &uriEditor{box: container.NewVBox()}
+	for _, u := range uris {
+		e.addRow(u)
+	}
+	if len(uris) == 0 {
+		e.addRow(vault.URI{})
+	}
+	return e
+}
+
+func (e *uriEditor) addRow(u vault.URI) {
+	labels := make([]string, len(vault.MatchModes))
+	for i, m := range vault.MatchModes {
+		labels[i] = m.Label()
+	}
+
+	row := &uriRow{entry: widget.NewEntry(), mode: widget.NewSelect(labels, nil)}
+	row.entry.PlaceHolder = "网址，如 https://github.com/login"
+	row.entry.SetText(u.URI)
+	row.mode.SetSelected(u.Match.Label())
+	e.rows = append(e.rows, row)
+
+	var obj fyne.CanvasObject
+	btnRemove := widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), func() {
+		for i, r := range e.rows {
+			if r == row {
+				e.rows = append(e.rows[:i], e.rows[i+1:]...)
+				break
+			}
+		}
+		e.box.Remove(obj)
+	})
+	obj = container.NewBorder(nil, nil, nil, container.NewHBox(row.mode, btnRemove), row.entry)
+	e.box.Add(obj)
+}
+
+// object 网址列表及「添加网址」按钮。
+func (e *uriEditor) object() fyne.CanvasObject {
+	btnAdd := widget.NewButtonWithIcon("添加网址", theme.ContentAddIcon(), func() {
+		e.addRow(vault.URI{})
+	})
+	return container.NewVBox(e.box, container.NewHBox(btnAdd))
+}
+
+// values 返回填写的网址 (忽略空行)。
+func (e *uriEditor) values() []vault.URI {
+	var uris []vault.URI
+	for _, r := range e.rows {
+		u := vault.URI{URI: r.entry.Text}
+		for _, m := range vault.MatchModes {
+			if m.Label() == r.mode.Selected {
+				u.Match = m
+			}
+		}
+		uris = append(uris, u)
+	}
+	return vault.NormalizeURIs(uris)
+}
@@ -0,0 +1,320 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// EventType 审计事件类型。
+type EventType string
+
+const (
+	EventRegister    EventType = "register" // 注册账户，日志链的第一条 (创世条目)
+	EventLogin       EventType = "login"
+	EventLoginFailed EventType = "login_failed"
+	EventReset       EventType = "reset"
+	EventResetFailed EventType = "reset_failed"
+	EventAuthFailed  EventType = "auth_failed" // 修改安全设置时身份验证失败 (如密保答案错误)
+	EventLock        EventType = "lock"        // 会话自动或手动锁定
+	EventSecurity    EventType = "security"    // 主密码、恢复码、密保问题、用户名等设置变更
+	EventItemView    EventType = "item_view"
+	EventItemCopy    EventType = "item_copy"
+	EventItemAdd     EventType = "item_add"
+	EventItemEdit    EventType = "item_edit"
+	EventItemDelete  EventType = "item_delete"
+	EventItemRevert  EventType = "item_revert"
+	EventItemRestore EventType = "item_restore" // 从回收站还原
+	EventItemPurge   EventType = "item_purge"   // 从回收站永久删除 (手动或过期自动清理)
+	EventAttachAdd   EventType = "attachment_add"
+	EventAttachSave  EventType = "attachment_save" // 附件解密后另存为文件或输出到 stdout
+	EventAttachDel   EventType = "attachment_delete"
+	EventBackup      EventType = "backup"
+	EventRestore     EventType = "restore"
+	EventUnknown     EventType = "unknown"
+)
+
+var eventLabels = map[EventType]string{
+	EventRegister:    "注册账户",
+	EventLogin:       "登录成功",
+	EventLoginFailed: "登录失败",
+	EventReset:       "重置 Key B",
+	EventResetFailed: "重置失败",
+	EventAuthFailed:  "验证失败",
+	EventLock:        "会话锁定",
+	EventSecurity:    "安全设置",
+	EventItemView:    "查看密码",
+	EventItemCopy:    "复制密码",
+	EventItemAdd:     "添加条目",
+	EventItemEdit:    "编辑条目",
+	EventItemDelete:  "删除条目",
+	EventItemRevert:  "恢复历史版本",
+	EventItemRestore: "还原条目",
+	EventItemPurge:   "永久删除",
+	EventAttachAdd:   "添加附件",
+	EventAttachSave:  "导出附件",
+	EventAttachDel:   "删除附件",
+	EventBackup:      "备份",
+	EventRestore:     "恢复",
+}
+
+// Label 返回事件类型的中文名称，用于界面展示。
+func (t EventType) Label() string {
+	if label, ok := eventLabels[t]; ok {
+		return label
+	}
+	return string(t)
+}
+
+// HKDF info 常量: 审计日志的加密密钥和 MAC 密钥都由 Key C 派生。
+const (
+	infoAuditEncrypt = "audit-log-encrypt"
+	infoAuditMAC     = "audit-log-mac"
+)
+
+// ErrTampered 表示审计日志被篡改、删除或截断。
+var ErrTampered = errors.New("audit log integrity check failed")
+
+// Event 一条审计事件。
+type Event struct {
+	Type   EventType `json:"type"`
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail,omitempty"`
+	// Pending 表示事件发生在登录前 (例如 OTP 错误)，在下次登录时才并入日志链。
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Entry 解密后的审计日志条目。
+type Entry struct {
+	Seq int64
+	Event
+}
+
+// Logger 审计日志管理器。
+// 安全决策:
+//  1. 每条事件用 HKDF(Key C, "audit-log-encrypt") 加密，数据库中不留明文操作记录。
+//  2. 每条记录的 MAC = HMAC(HKDF(Key C, "audit-log-mac"), 上一条 MAC || seq || 密文)，
+//     修改、删除或调换任意一条都会使之后的链校验失败。
+//  3. users.audit_head 保存最后序号及其 MAC，与追加在同一事务中更新，用于发现尾部截断。
+//     注册时写入创世条目，每次登录也会追加一条，因此能校验的用户必然已有链头；
+//     链头为空即视为整个日志被删除。
+//  4. 登录前 (没有 Key C) 发生的事件暂存在 audit_pending，登录成功后并入日志链。
+//     暂存事件在并入前不受 MAC 保护。
+type Logger struct {
+	db *db.DB
+}
+
+func NewLogger(db *db.DB) *Logger {
+	return &Logger{db: db}
+}
+
+// Append 追加一条事件。
+func (l *Logger) Append(username string, keyC []byte, typ EventType, detail string) error {
+	return l.db.WithTx(func(tx *sql.Tx) error {
+		return AppendTx(tx, username, keyC, Event{Type: typ, Time: time.Now(), Detail: detail})
+	})
+}
+
+// AppendTx 在调用方的事务中追加一条事件，使事件与对应的数据变更原子提交。
+func AppendTx(tx *sql.Tx, username string, keyC []byte, ev Event) error {
+	encKey, macKey, err := deriveKeys(keyC)
+	if err != nil {
+		return err
+	}
+
+	last, err := db.GetLastAuditRow(tx, username)
+	if err != nil {
+		return err
+	}
+	seq := int64(1)
+	prevMAC := make([]byte, sha256.Size)
+	if last != nil {
+		seq = last.Seq + 1
+		prevMAC = last.MAC
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	encEvent, err := crypto.EncryptAESGCM(encKey, data)
+	if err != nil {
+		return err
+	}
+	mac := chainMAC(macKey, prevMAC, seq, encEvent)
+
+	if err := db.InsertAuditRow(tx, username, db.AuditRow{Seq: seq, EncEvent: encEvent, MAC: mac}); err != nil {
+		return err
+	}
+	return db.SetAuditHead(tx, username, encodeHead(macKey, seq, mac))
+}
+
+// RecordPending 暂存一条登录前的事件 (没有 Key C，无法加密和链接)。
+func (l *Logger) RecordPending(username string, typ EventType, detail string) error {
+	data, err := json.Marshal(Event{Type: typ, Time: time.Now(), Detail: detail, Pending: true})
+	if err != nil {
+		return err
+	}
+	return l.db.InsertAuditPending(username, data)
+}
+
+// Flush 将暂存事件按发生顺序并入日志链，并删除暂存记录。
+func (l *Logger) Flush(username string, keyC []byte) error {
+	return l.db.WithTx(func(tx *sql.Tx) error {
+		return FlushTx(tx, username, keyC)
+	})
+}
+
+// FlushTx 在调用方的事务中并入暂存事件。
+func FlushTx(tx *sql.Tx, username string, keyC []byte) error {
+	pending, err := db.GetAuditPending(tx, username)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		var ev Event
+		if err := json.Unmarshal(p.Event, &ev); err != nil {
+			ev = Event{Type: EventUnknown, Time: time.Now(), Detail: "corrupted pending event", Pending: true}
+		}
+		if err := AppendTx(tx, username, keyC, ev); err != nil {
+			return err
+		}
+		if err := db.DeleteAuditPending(tx, p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List 解密并返回用户的全部审计日志 (按时间正序)。
+// 只做解密，不校验链；完整性请使用 Verify。
+func (l *Logger) List(username string, keyC []byte) ([]Entry, error) {
+	encKey, _, err := deriveKeys(keyC)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.GetAuditRows(l.db, username)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, r := range rows {
+		ev, err := decryptEvent(encKey, r.EncEvent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt audit entry %d: %v", r.Seq, err)
+		}
+		entries = append(entries, Entry{Seq: r.Seq, Event: *ev})
+	}
+	return entries, nil
+}
+
+// Verify 校验用户审计日志的完整性，返回已校验的条目数。
+// 核心逻辑:
+// 1. 序号必须从 1 开始连续递增 (发现删除和插入)。
+// 2. 逐条重算链式 MAC 并解密 (发现修改和调换)。
+// 3. 链头记录的序号和 MAC 必须与最后一条一致 (发现尾部截断)。
+// 4. 链头不能为空: 调用方已通过 Login 取得 Key C，而登录总会追加事件 (发现整个日志连同链头被删除)。
+func (l *Logger) Verify(username string, keyC []byte) (int, error) {
+	encKey, macKey, err := deriveKeys(keyC)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.GetAuditRows(l.db, username)
+	if err != nil {
+		return 0, err
+	}
+	head, err := db.GetAuditHead(l.db, username)
+	if err != nil {
+		return 0, err
+	}
+
+	prevMAC := make([]byte, sha256.Size)
+	for i, r := range rows {
+		want := int64(i + 1)
+		if r.Seq != want {
+			return i, fmt.Errorf("%w: expected entry %d, found %d (entries missing or inserted)", ErrTampered, want, r.Seq)
+		}
+		if !hmac.Equal(r.MAC, chainMAC(macKey, prevMAC, r.Seq, r.EncEvent)) {
+			return i, fmt.Errorf("%w: entry %d has been modified", ErrTampered, r.Seq)
+		}
+		if _, err := decryptEvent(encKey, r.EncEvent); err != nil {
+			return i, fmt.Errorf("%w: entry %d cannot be decrypted", ErrTampered, r.Seq)
+		}
+		prevMAC = r.MAC
+	}
+
+	if len(head) == 0 {
+		return len(rows), fmt.Errorf("%w: chain head missing (log deleted?)", ErrTampered)
+	}
+	headSeq, ok := decodeHead(head)
+	if !ok {
+		return len(rows), fmt.Errorf("%w: chain head is invalid", ErrTampered)
+	}
+	if headSeq != int64(len(rows)) || !hmac.Equal(head, encodeHead(macKey, headSeq, prevMAC)) {
+		return len(rows), fmt.Errorf("%w: chain head expects %d entries, found %d (log truncated?)", ErrTampered, headSeq, len(rows))
+	}
+	return len(rows), nil
+}
+
+// deriveKeys 由 Key C 派生审计日志的加密密钥和 MAC 密钥。
+func deriveKeys(keyC []byte) (encKey, macKey []byte, err error) {
+	if encKey, err = crypto.DeriveSubKey(keyC, infoAuditEncrypt); err != nil {
+		return nil, nil, err
+	}
+	if macKey, err = crypto.DeriveSubKey(keyC, infoAuditMAC); err != nil {
+		return nil, nil, err
+	}
+	return encKey, macKey, nil
+}
+
+func decryptEvent(encKey, encEvent []byte) (*Event, error) {
+	data, err := crypto.DecryptAESGCM(encKey, encEvent)
+	if err != nil {
+		return nil, err
+	}
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// chainMAC 计算 HMAC(macKey, prevMAC || seq || encEvent)。
+func chainMAC(macKey, prevMAC []byte, seq int64, encEvent []byte) []byte {
+	m := hmac.New(sha256.New, macKey)
+	m.Write(prevMAC)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seq))
+	m.Write(buf[:])
+	m.Write(encEvent)
+	return m.Sum(nil)
+}
+
+// encodeHead 链头格式: seq (8 字节) || HMAC(macKey, "audit-head" || seq || 最后一条 MAC)。
+func encodeHead(macKey []byte, seq int64, lastMAC []byte) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seq))
+
+	m := hmac.New(sha256.New, macKey)
+	m.Write([]byte("audit-head"))
+	m.Write(buf[:])
+	m.Write(lastMAC)
+	return append(buf[:], m.Sum(nil)...)
+}
+
+// decodeHead 解析链头中的序号 (MAC 由调用方重算比对)。
+func decodeHead(head []byte) (int64, bool) {
+	if len(head) != 8+sha256.Size {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(head[:8])), true
+}
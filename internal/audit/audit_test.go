@@ -0,0 +1,103 @@
+package audit_test
+
+import (
+	"errors"
+	"testing"
+
+	"key-box/internal/audit"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// newTestLog 在临时 HOME 中创建数据库，为用户 alice 追加 n 条事件，返回数据库和 Key C。
+func newTestLog(t *testing.T, n int) (*db.DB, *audit.Logger, []byte) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	d, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	if _, err := d.Exec(`INSERT INTO users (username) VALUES ('alice')`); err != nil {
+		t.Fatal(err)
+	}
+
+	keyC, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := audit.NewLogger(d)
+	for i := 0; i < n; i++ {
+		if err := l.Append("alice", keyC, audit.EventItemView, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d, l, keyC
+}
+
+func TestVerifyIntactLog(t *testing.T) {
+	_, l, keyC := newTestLog(t, 4)
+	n, err := l.Verify("alice", keyC)
+	if err != nil || n != 4 {
+		t.Fatalf("Verify = %d, %v; want 4, nil", n, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper string
+	}{
+		{"modified event", `UPDATE audit_log SET enc_event = randomblob(length(enc_event)) WHERE seq = 2`},
+		{"modified mac", `UPDATE audit_log SET mac = randomblob(32) WHERE seq = 3`},
+		{"swapped sequence", `UPDATE audit_log SET seq = 5 - seq WHERE seq IN (2, 3)`},
+		{"deleted middle entry", `DELETE FROM audit_log WHERE seq = 2`},
+		{"truncated tail", `DELETE FROM audit_log WHERE seq = 4`},
+		{"deleted log", `DELETE FROM audit_log`},
+		{"removed head", `UPDATE users SET audit_head = NULL`},
+		{"truncated head", `UPDATE users SET audit_head = substr(audit_head, 1, 4)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, l, keyC := newTestLog(t, 4)
+			if _, err := d.Exec(tt.tamper); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := l.Verify("alice", keyC); !errors.Is(err, audit.ErrTampered) {
+				t.Errorf("Verify after %s = %v, want ErrTampered", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestVerifyWithWrongKey(t *testing.T) {
+	_, l, _ := newTestLog(t, 2)
+	other, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Verify("alice", other); !errors.Is(err, audit.ErrTampered) {
+		t.Errorf("Verify with another key = %v, want ErrTampered", err)
+	}
+}
+
+func TestFlushChainsPendingEvents(t *testing.T) {
+	_, l, keyC := newTestLog(t, 1)
+	if err := l.RecordPending("alice", audit.EventLoginFailed, "invalid OTP code"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Flush("alice", keyC); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := l.List("alice", keyC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Type != audit.EventLoginFailed || !entries[1].Pending {
+		t.Fatalf("entries after Flush = %+v, want the pending login failure last", entries)
+	}
+	if n, err := l.Verify("alice", keyC); err != nil || n != 2 {
+		t.Errorf("Verify after Flush = %d, %v; want 2, nil", n, err)
+	}
+}
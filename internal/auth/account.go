@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"key-box/internal/audit"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// DeleteAccount 注销账户，删除该用户的全部数据。
+// 核心逻辑:
+// 1. 通过 TOTP 登录 (及主密码) 确认是账户持有人本人操作。
+// 2. 在同一事务中删除用户在所有表中的记录。
+// 3. 提交后执行增量 VACUUM，将释放的页从数据库文件中截断。
+// 安全决策: 数据库以 secure_delete 打开，删除的记录先被清零，不会残留在空闲页中。
+// 返回被删除的各类数据数量 (条目、回收站、历史版本、附件)。
+func (s *Service) DeleteAccount(username, code, passphrase string) (db.UserDataCounts, error) {
+	if _, err := s.authenticate(username, code, passphrase); err != nil {
+		return db.UserDataCounts{}, err
+	}
+
+	var counts db.UserDataCounts
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		counts, err = db.DeleteUserRows(tx, username)
+		return err
+	})
+	if err != nil {
+		return db.UserDataCounts{}, err
+	}
+
+	if err := s.db.IncrementalVacuum(); err != nil {
+		return counts, fmt.Errorf("account deleted, but vacuum failed: %v", err)
+	}
+	return counts, nil
+}
+
+// AccountDataCounts 统计注销账户时将删除的数据 (与 DeleteAccount 的返回值一致)，用于确认提示。
+func (s *Service) AccountDataCounts(username string) (db.UserDataCounts, error) {
+	return db.CountUserData(s.db, username)
+}
+
+// RenameUser 修改用户名。
+// 核心逻辑:
+// 1. 通过 TOTP 登录 (及主密码) 拿到 Key C，并经 enc_mb 恢复 Key M。
+// 2. Key B 以用户名为 HKDF Salt，因此用 M 和新用户名派生新的 Key B。
+// 3. 用 Root Key 重新加密新 B (enc_b)，用新的解锁密钥重新加密 C 和 M (enc_c、enc_mb)。
+// 4. 在同一事务中更新所有表中的用户名和上述密文。
+// 结果: 用户获得新的 Key B (需重新绑定 TOTP)，旧 Key B 失效；M、C 不变，数据、密保和恢复码不受影响。
+func (s *Service) RenameUser(oldName, newName, code, passphrase string) (*RegisterResult, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, errors.New("username must not be empty")
+	}
+	if newName == oldName {
+		return nil, errors.New("new username is the same as the current one")
+	}
+	if _, err := s.db.GetUser(newName); err == nil {
+		return nil, errors.New("user already exists")
+	}
+
+	// 1. 登录并恢复 M
+	sess, err := s.authenticate(oldName, code, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	keyM, err := masterKey(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 以新用户名派生 Key B
+	newKeyB, err := crypto.DeriveKeyB(keyM, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 重新包装 B、C、M
+	rootKey, err := s.rootKey()
+	if err != nil {
+		return nil, err
+	}
+	encB, err := crypto.EncryptAESGCM(rootKey, newKeyB)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := unlockKey(sess.user, newKeyB, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	encC, err := crypto.EncryptAESGCM(unlock, sess.keyC)
+	if err != nil {
+		return nil, err
+	}
+	encMB, err := crypto.EncryptAESGCM(unlock, keyM)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 同一事务中更新所有表
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		if err := db.RenameUserRows(tx, oldName, newName); err != nil {
+			return err
+		}
+		stmt := `UPDATE users SET enc_b=?, enc_c=?, enc_mb=? WHERE username=?`
+		if _, err := tx.Exec(stmt, encB, encC, encMB, newName); err != nil {
+			return err
+		}
+		return audit.AppendTx(tx, newName, sess.keyC, securityEvent(fmt.Sprintf("username changed from %q", oldName)))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegisterResult{
+		SecretKeyBBase32: crypto.EncodeKeyB(newKeyB),
+		OTPAuthURI:       crypto.OTPAuthURI(newKeyB, newName),
+	}, nil
+}
@@ -1,24 +1,56 @@
 package auth
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
+	"key-box/internal/audit"
 	"key-box/internal/crypto"
 	"key-box/internal/db"
 )
 
+var (
+	// errInvalidLogin 用户名不存在或 OTP 错误。
+	errInvalidLogin = errors.New("invalid username or OTP code")
+	// errWrongAnswers 用户名不存在或密保答案错误。
+	errWrongAnswers = errors.New("failed to recover Key M (wrong security answers)")
+)
+
 type Service struct {
-	db *db.DB
+	db       *db.DB
+	rootKeys crypto.RootKeyProvider
+	audit    *audit.Logger
+}
+
+// NewService 创建认证服务。
+// rootKeys 决定 Root Key 的来源 (配置文件 Salt、内核密钥环、Secret Service 或口令)。
+func NewService(db *db.DB, rootKeys crypto.RootKeyProvider) *Service {
+	return &Service{db: db, rootKeys: rootKeys, audit: audit.NewLogger(db)}
+}
+
+// rootKey 从注入的提供者获取 Root Key，并在错误信息中注明提供者名称。
+func (s *Service) rootKey() ([]byte, error) {
+	rootKey, err := s.rootKeys.RootKey()
+	if err != nil {
+		return nil, fmt.Errorf("root key error (%s): %v", s.rootKeys.Name(), err)
+	}
+	return rootKey, nil
 }
 
-func NewService(db *db.DB) *Service {
-	return &Service{db: db}
+// securityEvent 构造账户安全设置变更的审计事件。
+func securityEvent(detail string) audit.Event {
+	return audit.Event{Type: audit.EventSecurity, Time: time.Now(), Detail: detail}
 }
 
 // RegisterResult contains the secret key B for the user to save.
 type RegisterResult struct {
 	SecretKeyBBase32 string
+	// OTPAuthURI Key B 的 otpauth:// URI，可供 TOTP 应用扫码导入。
+	OTPAuthURI string
+	// RecoveryCodes 一次性恢复码，仅在注册时返回，需要用户离线保存。
+	RecoveryCodes []string
 }
 
 // Register 用户注册流程。
@@ -30,12 +62,17 @@ type RegisterResult struct {
 // 5. 用 M 派生出 密钥 B (Auth Key)。
 // 6. 用 RootKey 加密 B -> EncB (存储到 DB)。
 // 7. 用 B 加密 C -> EncC (存储到 DB)。
-// 8. 返回 Key B 的 Base32 编码供用户绑定 TOTP。
+// 8. 生成 Recovery Key W 和 10 个一次性恢复码，并保存 M 的各个包装副本。
+// 9. 用 Root Key 派生密钥加密密保问题。
+// 10. 返回 Key B 的 Base32 编码供用户绑定 TOTP，以及恢复码明文。
 func (s *Service) Register(username, q1, q2, q3, a1, a2, a3 string) (*RegisterResult, error) {
 	// Check if user exists
 	if _, err := s.db.GetUser(username); err == nil {
 		return nil, errors.New("user already exists")
 	}
+	if err := validateQuestions([]string{q1, q2, q3}); err != nil {
+		return nil, err
+	}
 
 	// 1. 生成随机盐值 (Salt)
 	// 这个 Salt 是公开的 (存储在 DB)，用于混淆 SSS 的分片 Hash 计算。
@@ -79,13 +116,13 @@ func (s *Service) Register(username, q1, q2, q3, a1, a2, a3 string) (*RegisterRe
 
 	// 6. 获取 Root Key
 	// Root Key 用于保护 Key B 存储在数据库中。
-	rootKey, err := crypto.GetRootKey()
+	rootKey, err := s.rootKey()
 	if err != nil {
-		return nil, fmt.Errorf("root key error: %v (did you set SEC_APP_SALT?)", err)
+		return nil, err
 	}
 
 	// 7. 用 Root Key 加密 Key B
-	// 即使 DB 泄露，攻击者没有 Root Key (环境变量+硬编码) 也无法获取 B。
+	// 即使 DB 泄露，攻击者没有 Root Key (由注入的提供者给出) 也无法获取 B。
 	encB, err := crypto.EncryptAESGCM(rootKey, keyB)
 	if err != nil {
 		return nil, err
@@ -99,33 +136,62 @@ func (s *Service) Register(username, q1, q2, q3, a1, a2, a3 string) (*RegisterRe
 		return nil, err
 	}
 
-	// 9. 保存所有密文和元数据到数据库
-	u := &db.User{
-		Username:  username,
-		Salt:      salt,
-		Question1: q1,
-		Question2: q2,
-		Question3: q3,
-		EncM:      encM,
-		EncB:      encB,
-		EncC:      encC,
+	// 9. 生成 Recovery Key W，并保存 M 的各个包装副本 (恢复通道、TOTP 登录后恢复 M 等)
+	keyW, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	wraps, err := wrapMasterKey(keyM, keyA, keyC, keyW, keyB)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.db.CreateUser(u); err != nil {
+	// 10. 生成一次性恢复码，每个恢复码各自加密一份 W
+	codes, rows, err := issueRecoveryCodes(keyW)
+	if err != nil {
 		return nil, err
 	}
 
-	return &RegisterResult{
-		SecretKeyBBase32: crypto.EncodeKeyB(keyB),
-	}, nil
-}
+	// 11. 用 Root Key 派生密钥加密密保问题 (数据库中不保存明文问题)
+	encQuestions, err := sealQuestions(rootKey, []string{q1, q2, q3})
+	if err != nil {
+		return nil, err
+	}
 
-func (s *Service) GetSecurityQuestions(username string) ([]string, error) {
-	u, err := s.db.GetUser(username)
+	// 12. 在同一事务中保存用户、恢复码和审计日志的创世条目
+	u := &db.User{
+		Username:     username,
+		Salt:         salt,
+		EncM:         encM,
+		EncB:         encB,
+		EncC:         encC,
+		EncR:         wraps.EncR,
+		EncMB:        wraps.EncMB,
+		EncA:         wraps.EncA,
+		EncW:         wraps.EncW,
+		EncMW:        wraps.EncMW,
+		EncQuestions: encQuestions,
+	}
+
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		if err := db.InsertUser(tx, u); err != nil {
+			return err
+		}
+		if err := db.ReplaceRecoveryCodes(tx, username, rows); err != nil {
+			return err
+		}
+		// 创世条目: 日志链从注册开始，之后整个日志被删除即可由链头缺失发现
+		return audit.AppendTx(tx, username, keyC, audit.Event{Type: audit.EventRegister, Time: time.Now()})
+	})
 	if err != nil {
 		return nil, err
 	}
-	return []string{u.Question1, u.Question2, u.Question3}, nil
+
+	return &RegisterResult{
+		SecretKeyBBase32: crypto.EncodeKeyB(keyB),
+		OTPAuthURI:       crypto.OTPAuthURI(keyB, username),
+		RecoveryCodes:    codes,
+	}, nil
 }
 
 // Login 用户登录流程。
@@ -133,22 +199,47 @@ func (s *Service) GetSecurityQuestions(username string) ([]string, error) {
 // 1. 从 DB 读取用户的加密元数据。
 // 2. 计算 RootKey 并解密得到 Key B。
 // 3. 使用 Key B 验证用户输入的 TOTP。
-// 4. 验证通过后，用 Key B 解密得到 Key C (数据密钥)。
+// 4. 验证通过后，用 Key B (启用主密码时为 Key B + 主密码) 解密得到 Key C (数据密钥)。
 // 5. 返回 Key C 供后续操作使用。
-func (s *Service) Login(username, code string) ([]byte, error) {
+// passphrase 为主密码，未启用主密码的用户传空字符串即可 (传入也会被忽略)。
+func (s *Service) Login(username, code, passphrase string) ([]byte, error) {
+	sess, err := s.authenticate(username, code, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// 登录前暂存的事件 (例如 OTP 错误) 并入审计日志，再记录本次登录
+	// 审计写入失败不影响登录，完整性问题由 audit --verify 发现
+	_ = s.audit.Flush(username, sess.keyC)
+	_ = s.audit.Append(username, sess.keyC, audit.EventLogin, "")
+	return sess.keyC, nil
+}
+
+// unlocked 保存一次登录校验得到的密钥材料。
+type unlocked struct {
+	user   *db.User
+	keyB   []byte
+	keyC   []byte
+	unlock []byte // 保护 Key C 的解锁密钥 L
+}
+
+// authenticate 完成登录校验，返回用户记录及 Key B、Key C 和解锁密钥。
+// 需要 Key B 的后续流程 (例如修改主密码) 复用该方法，保证校验逻辑一致。
+func (s *Service) authenticate(username, code, passphrase string) (*unlocked, error) {
+	// 用户不存在与 OTP 错误返回相同的错误，避免枚举用户名
 	u, err := s.db.GetUser(username)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, errInvalidLogin
 	}
 
 	// 1. 获取 Root Key
-	rootKey, err := crypto.GetRootKey()
+	rootKey, err := s.rootKey()
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. 解密 Key B
-	// 如果环境变量配置错误，RootKey 会变，解密 B 将失败 (GCM Auth Tag 校验失败)。
+	// 如果 Root Key 来源配置错误，RootKey 会变，解密 B 将失败 (GCM Auth Tag 校验失败)。
 	keyB, err := crypto.DecryptAESGCM(rootKey, u.EncB)
 	if err != nil {
 		return nil, errors.New("failed to decrypt system key (root key mismatch?)")
@@ -157,33 +248,45 @@ func (s *Service) Login(username, code string) ([]byte, error) {
 	// 3. 验证 TOTP
 	// 证明用户持有 Key B (即 "最高权限凭证")。
 	if !crypto.VerifyOTP(keyB, code) {
-		return nil, errors.New("invalid OTP code")
+		_ = s.audit.RecordPending(username, audit.EventLoginFailed, "invalid OTP code")
+		return nil, errInvalidLogin
+	}
+
+	// 旧版本用户的密保问题为明文，登录成功后顺便加密
+	if err := s.migrateQuestions(u, rootKey); err != nil {
+		return nil, err
 	}
 
-	// 4. 解密 Key C
-	// 只有通过了上述步骤，才能拿到解密用户数据的钥匙。
-	keyC, err := crypto.DecryptAESGCM(keyB, u.EncC)
+	// 4. 计算解锁密钥并解密 Key C
+	// 只有通过了上述步骤 (以及主密码)，才能拿到解密用户数据的钥匙。
+	unlock, err := unlockKey(u, keyB, passphrase)
 	if err != nil {
+		return nil, err
+	}
+	keyC, err := crypto.DecryptAESGCM(unlock, u.EncC)
+	if err != nil {
+		if len(u.PassSalt) > 0 {
+			_ = s.audit.RecordPending(username, audit.EventLoginFailed, "wrong master passphrase")
+			return nil, errors.New("failed to unlock vault (wrong master passphrase?)")
+		}
 		return nil, errors.New("failed to unlock vault (key C decryption failed)")
 	}
 
-	return keyC, nil
+	return &unlocked{user: u, keyB: keyB, keyC: keyC, unlock: unlock}, nil
 }
 
 // ResetPassword 密码重置/密钥轮转流程。
 // 核心逻辑:
 // 1. 验证密保答案，恢复 Key A。
 // 2. 用 A 解密得到旧的 M。
-// 3. 用旧 M 派生出旧 B，进而解密得到 C (数据密钥)。
-// 4. 生成全新的随机密钥 M_new (Key Rotation)。
-// 5. 用 M_new 派生新 B_new。
-// 6. 重新加密链条: A->M_new, RootKey->B_new, B_new->C。
-// 7. 更新数据库。
+// 3. 通过 rotateMasterKey 轮转 M 并重建整条密钥链。
 // 结果: 用户获得新的 Key B，旧的 Key B 失效。数据本身 (由 C 加密) 无需重加密，只需重新保护 C。
+// 重置是忘记主密码时的恢复路径: 主密码会被关闭，用户登录后可重新启用。
 func (s *Service) ResetPassword(username, a1, a2, a3 string) (*RegisterResult, error) {
+	// 用户不存在与答案错误返回相同的错误，避免枚举用户名
 	u, err := s.db.GetUser(username)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, errWrongAnswers
 	}
 
 	// 1. 恢复 Key A
@@ -197,23 +300,34 @@ func (s *Service) ResetPassword(username, a1, a2, a3 string) (*RegisterResult, e
 	// 如果答案错误，Key A 错误，解密 M 必然失败。
 	keyM, err := crypto.DecryptAESGCM(keyA, u.EncM)
 	if err != nil {
-		return nil, errors.New("failed to recover Key M (wrong security answers)")
+		_ = s.audit.RecordPending(username, audit.EventResetFailed, "wrong security answers")
+		return nil, errWrongAnswers
 	}
 
-	// 3. 恢复旧 Key B
-	// 需要旧 B 来解密 Key C。
-	oldKeyB, err := crypto.DeriveKeyB(keyM, username)
+	return s.rotateMasterKey(u, keyA, keyM, "security answers", nil)
+}
+
+// rotateMasterKey 密钥轮转，由密保问题重置和恢复码共用。
+// 核心逻辑:
+// 1. 用旧 M 恢复 C (数据密钥) 和 W (恢复码保护的 Recovery Key)。
+// 2. 生成全新的随机密钥 M_new (Key Rotation)。
+// 3. 用 M_new 派生新 B_new。
+// 4. 重新加密链条: A->M_new, RootKey->B_new, B_new->C，以及 M_new 的各个包装副本。
+// 5. 在同一事务中更新数据库并写入审计日志，extra 可追加同一事务内的写操作 (例如标记恢复码已使用)。
+// W 保持不变，因此尚未使用的恢复码在轮转后仍然有效。
+// via 记录重置方式，写入审计事件。
+func (s *Service) rotateMasterKey(u *db.User, keyA, keyM []byte, via string, extra func(ex db.Execer) error) (*RegisterResult, error) {
+	// 1. 恢复 Key C 和 Key W
+	keyC, err := recoverKeyC(u, keyM)
 	if err != nil {
 		return nil, err
 	}
-
-	// 4. 解密 Key C (数据密钥)
-	keyC, err := crypto.DecryptAESGCM(oldKeyB, u.EncC)
+	keyW, err := recoverKeyW(u, keyM)
 	if err != nil {
-		return nil, errors.New("failed to recover Key C")
+		return nil, err
 	}
 
-	// 5. 生成新 M (实现密钥轮转)
+	// 2. 生成新 M (实现密钥轮转)
 	// 按照需求，我们需要 "再次生成随机数后获得新的密钥B"。
 	// 通过轮转 M，我们可以彻底切断与旧密钥链的联系。
 	newKeyM, err := crypto.GenerateRandomBytes(32)
@@ -221,20 +335,20 @@ func (s *Service) ResetPassword(username, a1, a2, a3 string) (*RegisterResult, e
 		return nil, err
 	}
 
-	// 6. 重新加密新 M (用同一个 A)
+	// 3. 重新加密新 M (用同一个 A)
 	newEncM, err := crypto.EncryptAESGCM(keyA, newKeyM)
 	if err != nil {
 		return nil, err
 	}
 
-	// 7. 派生新 Key B
-	newKeyB, err := crypto.DeriveKeyB(newKeyM, username)
+	// 4. 派生新 Key B
+	newKeyB, err := crypto.DeriveKeyB(newKeyM, u.Username)
 	if err != nil {
 		return nil, err
 	}
 
-	// 8. 用 Root Key 加密新 B
-	rootKey, err := crypto.GetRootKey()
+	// 5. 用 Root Key 加密新 B
+	rootKey, err := s.rootKey()
 	if err != nil {
 		return nil, err
 	}
@@ -243,22 +357,43 @@ func (s *Service) ResetPassword(username, a1, a2, a3 string) (*RegisterResult, e
 		return nil, err
 	}
 
-	// 9. 用新 B 重新加密 Key C
+	// 6. 用新 B 重新加密 Key C
 	// 这样 Key C 就被新 B 保护了。
 	newEncC, err := crypto.EncryptAESGCM(newKeyB, keyC)
 	if err != nil {
 		return nil, err
 	}
 
-	// 10. 更新数据库记录
-	stmt := `UPDATE users SET enc_m=?, enc_b=?, enc_c=? WHERE username=?`
-	_, err = s.db.Exec(stmt, newEncM, newEncB, newEncC, username)
+	// 7. 用新 M 重新生成各个包装副本 (主密码关闭，解锁密钥即新 B)
+	wraps, err := wrapMasterKey(newKeyM, keyA, keyC, keyW, newKeyB)
+	if err != nil {
+		return nil, err
+	}
+
+	// 8. 更新数据库记录 (同时关闭主密码)
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		stmt := `UPDATE users SET enc_m=?, enc_b=?, enc_c=?, enc_r=?, enc_mb=?, enc_a=?, enc_w=?, enc_mw=?, pass_salt=NULL WHERE username=?`
+		if _, err := tx.Exec(stmt, newEncM, newEncB, newEncC, wraps.EncR, wraps.EncMB, wraps.EncA, wraps.EncW, wraps.EncMW, u.Username); err != nil {
+			return err
+		}
+		if extra != nil {
+			if err := extra(tx); err != nil {
+				return err
+			}
+		}
+		// 先并入重置前暂存的失败事件，保证日志按时间顺序
+		if err := audit.FlushTx(tx, u.Username, keyC); err != nil {
+			return err
+		}
+		return audit.AppendTx(tx, u.Username, keyC, audit.Event{Type: audit.EventReset, Time: time.Now(), Detail: via})
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &RegisterResult{
 		SecretKeyBBase32: crypto.EncodeKeyB(newKeyB),
+		OTPAuthURI:       crypto.OTPAuthURI(newKeyB, u.Username),
 	}, nil
 }
 
@@ -267,14 +402,26 @@ func (s *Service) GetUserInfo(username string) (*db.User, error) {
 	return s.db.GetUser(username)
 }
 
-// RestoreUser 恢复用户信息（用于恢复备份）
-func (s *Service) RestoreUser(u *db.User) error {
-	return s.db.CreateUser(u)
+// GetRecoveryCodes 获取用户的恢复码记录（用于备份，恢复码本身不存储）
+func (s *Service) GetRecoveryCodes(username string) ([]db.RecoveryCode, error) {
+	return s.db.GetRecoveryCodes(username)
+}
+
+// RestoreUser 恢复用户信息及恢复码记录（用于恢复备份）
+// 恢复码记录与 enc_w、enc_mw 一起恢复，用户此前打印的恢复码恢复后仍然可用。
+func (s *Service) RestoreUser(u *db.User, codes []db.RecoveryCode) error {
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		if err := db.InsertUser(tx, u); err != nil {
+			return err
+		}
+		return db.ReplaceRecoveryCodes(tx, u.Username, codes)
+	})
 }
 
-// DeleteUser 删除用户（用于覆盖恢复）
+// DeleteUser 删除用户及其所有数据（用于覆盖恢复）
 func (s *Service) DeleteUser(username string) error {
-	stmt := `DELETE FROM users WHERE username = ?`
-	_, err := s.db.Exec(stmt, username)
-	return err
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		_, err := db.DeleteUserRows(tx, username)
+		return err
+	})
 }
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// HKDF info 常量，区分由同一源密钥派生出的不同用途子密钥。
+const (
+	infoUnlockKey       = "unlock-key"
+	infoDataKeyRecovery = "data-key-recovery"
+	infoAnswerKeyWrap   = "answer-key-wrap"
+	infoRecoveryKeyWrap = "recovery-key-wrap"
+	infoQuestionsKey    = "security-questions"
+	infoDecoyQuestions  = "decoy-questions"
+	infoLookupLimit     = "lookup-limit"
+)
+
+// ErrMasterKeyUnavailable 表示旧版本注册的用户缺少 Key M 的登录副本，
+// 需要先通过密保问题重置一次以升级密钥链。
+var ErrMasterKeyUnavailable = errors.New("master key copy missing: reset once with security answers to upgrade the key chain")
+
+// masterKeyWraps 保存 Key M 相关的各个包装副本。
+//   - EncR:  M 派生密钥 -> Key C，密保/恢复码重置时恢复数据密钥
+//   - EncMB: 解锁密钥 L -> Key M，TOTP 登录后恢复 M (修改密保、改名等)
+//   - EncA:  M 派生密钥 -> Key A，恢复码轮转时重新用 A 保护新 M
+//   - EncW:  M 派生密钥 -> Key W，轮转时保留恢复码
+//   - EncMW: Key W -> Key M，恢复码经 W 解开 M
+type masterKeyWraps struct {
+	EncR, EncMB, EncA, EncW, EncMW []byte
+}
+
+// wrapMasterKey 为 Key M 生成全部包装副本。
+// 安全决策: enc_r、enc_a、enc_w 只能由 M 解开，enc_mw 只能由 W 解开，不会降低密保和恢复码路径的安全性。
+// 但 enc_mb 让持有解锁密钥 (Key B，设置了主密码时再加主密码) 的人无需密保答案即可得到 M，
+// 进而经 enc_a 得到 A：M 和 A 的强度因此降到与 TOTP 登录相同。这是修改密保、改名、轮转恢复码
+// 无需重新回答密保的代价，取舍见 docs/DESIGN.md 2.2 节。
+func wrapMasterKey(keyM, keyA, keyC, keyW, unlock []byte) (*masterKeyWraps, error) {
+	var w masterKeyWraps
+	var err error
+
+	if w.EncR, err = encryptWithSubKey(keyM, infoDataKeyRecovery, keyC); err != nil {
+		return nil, err
+	}
+	if w.EncMB, err = crypto.EncryptAESGCM(unlock, keyM); err != nil {
+		return nil, err
+	}
+	if w.EncA, err = encryptWithSubKey(keyM, infoAnswerKeyWrap, keyA); err != nil {
+		return nil, err
+	}
+	if w.EncW, err = encryptWithSubKey(keyM, infoRecoveryKeyWrap, keyW); err != nil {
+		return nil, err
+	}
+	if w.EncMW, err = crypto.EncryptAESGCM(keyW, keyM); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// encryptWithSubKey 用 secret 按 info 派生的子密钥加密 plaintext。
+func encryptWithSubKey(secret []byte, info string, plaintext []byte) ([]byte, error) {
+	key, err := crypto.DeriveSubKey(secret, info)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.EncryptAESGCM(key, plaintext)
+}
+
+// decryptWithSubKey 用 secret 按 info 派生的子密钥解密 ciphertext。
+func decryptWithSubKey(secret []byte, info string, ciphertext []byte) ([]byte, error) {
+	key, err := crypto.DeriveSubKey(secret, info)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptAESGCM(key, ciphertext)
+}
+
+// recoverKeyC 在已知 Key M 的情况下恢复 Key C。
+// 优先使用恢复通道 (enc_r)；旧版本注册的用户没有 enc_r，退回到由 M 派生旧 B 解密 enc_c。
+func recoverKeyC(u *db.User, keyM []byte) ([]byte, error) {
+	if len(u.EncR) > 0 {
+		keyC, err := decryptWithSubKey(keyM, infoDataKeyRecovery, u.EncR)
+		if err != nil {
+			return nil, errors.New("failed to recover Key C")
+		}
+		return keyC, nil
+	}
+
+	oldKeyB, err := crypto.DeriveKeyB(keyM, u.Username)
+	if err != nil {
+		return nil, err
+	}
+	keyC, err := crypto.DecryptAESGCM(oldKeyB, u.EncC)
+	if err != nil {
+		return nil, errors.New("failed to recover Key C")
+	}
+	return keyC, nil
+}
+
+// recoverKeyW 在已知 Key M 的情况下恢复 Recovery Key W。
+// 旧版本用户尚无 W，此时生成新的 W (此前不存在任何恢复码)。
+func recoverKeyW(u *db.User, keyM []byte) ([]byte, error) {
+	if len(u.EncW) == 0 {
+		return crypto.GenerateRandomBytes(32)
+	}
+	keyW, err := decryptWithSubKey(keyM, infoRecoveryKeyWrap, u.EncW)
+	if err != nil {
+		return nil, errors.New("failed to recover recovery key")
+	}
+	return keyW, nil
+}
+
+// masterKey 在 TOTP 登录后通过 enc_mb 恢复 Key M。
+func masterKey(sess *unlocked) ([]byte, error) {
+	if len(sess.user.EncMB) == 0 {
+		return nil, ErrMasterKeyUnavailable
+	}
+	keyM, err := crypto.DecryptAESGCM(sess.unlock, sess.user.EncMB)
+	if err != nil {
+		return nil, errors.New("failed to recover Key M")
+	}
+	return keyM, nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"key-box/internal/audit"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// MinPassphraseLength 主密码的最小长度 (按字符计)。
+const MinPassphraseLength = 8
+
+var (
+	// ErrPassphraseRequired 表示该用户已启用主密码，登录时必须提供。
+	ErrPassphraseRequired = errors.New("master passphrase required")
+	// ErrPassphraseTooShort 表示新主密码长度不足。
+	ErrPassphraseTooShort = fmt.Errorf("master passphrase must be at least %d characters", MinPassphraseLength)
+)
+
+// unlockKey 计算保护 Key C 的解锁密钥 L。
+// 安全决策:
+// 1. 未启用主密码时 L = Key B，与旧版本行为一致。
+// 2. 启用主密码后 L = HKDF(Key B || Argon2id(主密码, PassSalt))。
+// 3. 仅持有 TOTP 种子 (Key B) 或仅知道主密码，都无法解开 Key C。
+func unlockKey(u *db.User, keyB []byte, passphrase string) ([]byte, error) {
+	if len(u.PassSalt) == 0 {
+		return keyB, nil
+	}
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	return deriveUnlockKey(keyB, passphrase, u.PassSalt)
+}
+
+// deriveUnlockKey 组合 Key B 与 Argon2id 拉伸后的主密码。
+func deriveUnlockKey(keyB []byte, passphrase string, passSalt []byte) ([]byte, error) {
+	passKey := crypto.DerivePassphraseKey(passphrase, passSalt)
+	material := make([]byte, 0, len(keyB)+len(passKey))
+	material = append(material, keyB...)
+	material = append(material, passKey...)
+	return crypto.DeriveSubKey(material, infoUnlockKey)
+}
+
+// PassphraseEnabled 返回用户是否已启用主密码 (仅供已登录界面展示状态)。
+func (s *Service) PassphraseEnabled(username string) (bool, error) {
+	u, err := s.db.GetUser(username)
+	if err != nil {
+		return false, err
+	}
+	return len(u.PassSalt) > 0, nil
+}
+
+// EnablePassphrase 启用主密码。
+// 核心逻辑:
+// 1. 通过 TOTP 登录拿到 Key B 和 Key C。
+// 2. 生成新的 PassSalt，计算 L = HKDF(B || Argon2id(主密码))。
+// 3. 用 L 重新加密 Key C。
+// 前提: 用户必须拥有恢复通道 (enc_r)，否则忘记主密码后将无法通过密保问题找回数据。
+func (s *Service) EnablePassphrase(username, code, passphrase string) error {
+	sess, err := s.authenticate(username, code, "")
+	if err != nil {
+		return err
+	}
+	if len(sess.user.PassSalt) > 0 {
+		return errors.New("master passphrase is already enabled")
+	}
+	if len(sess.user.EncR) == 0 {
+		return errors.New("recovery channel missing: reset once with security answers before enabling a master passphrase")
+	}
+	return s.setPassphrase(sess, passphrase, "master passphrase enabled")
+}
+
+// ChangePassphrase 修改主密码，需要 TOTP 和当前主密码。
+func (s *Service) ChangePassphrase(username, code, oldPassphrase, newPassphrase string) error {
+	sess, err := s.authenticate(username, code, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if len(sess.user.PassSalt) == 0 {
+		return errors.New("master passphrase is not enabled")
+	}
+	return s.setPassphrase(sess, newPassphrase, "master passphrase changed")
+}
+
+// DisablePassphrase 关闭主密码，Key C 恢复为仅由 Key B 保护。
+func (s *Service) DisablePassphrase(username, code, passphrase string) error {
+	sess, err := s.authenticate(username, code, passphrase)
+	if err != nil {
+		return err
+	}
+	if len(sess.user.PassSalt) == 0 {
+		return errors.New("master passphrase is not enabled")
+	}
+	return s.rewrapUnlock(sess, sess.keyB, nil, "master passphrase disabled")
+}
+
+// setPassphrase 生成新的 PassSalt 并用新的解锁密钥重新加密 Key C。
+// detail 写入审计事件。
+func (s *Service) setPassphrase(sess *unlocked, passphrase, detail string) error {
+	if len([]rune(passphrase)) < MinPassphraseLength {
+		return ErrPassphraseTooShort
+	}
+
+	passSalt, err := crypto.GenerateRandomBytes(16)
+	if err != nil {
+		return err
+	}
+	unlock, err := deriveUnlockKey(sess.keyB, passphrase, passSalt)
+	if err != nil {
+		return err
+	}
+	return s.rewrapUnlock(sess, unlock, passSalt, detail)
+}
+
+// rewrapUnlock 用新的解锁密钥重新加密 Key C 和 Key M 的登录副本 (enc_mb)。
+// passSalt 为 nil 表示关闭主密码。更新与审计事件在同一事务中提交。
+func (s *Service) rewrapUnlock(sess *unlocked, unlock, passSalt []byte, detail string) error {
+	encC, err := crypto.EncryptAESGCM(unlock, sess.keyC)
+	if err != nil {
+		return err
+	}
+
+	// 旧版本用户没有 enc_mb，保持为空
+	var encMB []byte
+	if len(sess.user.EncMB) > 0 {
+		keyM, err := masterKey(sess)
+		if err != nil {
+			return err
+		}
+		if encMB, err = crypto.EncryptAESGCM(unlock, keyM); err != nil {
+			return err
+		}
+	}
+
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		stmt := `UPDATE users SET enc_c=?, enc_mb=?, pass_salt=? WHERE username=?`
+		if _, err := tx.Exec(stmt, encC, encMB, passSalt, sess.user.Username); err != nil {
+			return err
+		}
+		return audit.AppendTx(tx, sess.user.Username, sess.keyC, securityEvent(detail))
+	})
+}
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"key-box/internal/audit"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// 密保问题查询限流参数: 窗口期内单个用户名和全局的最大查询次数。
+const (
+	QuestionLookupWindow       = 15 * time.Minute
+	QuestionLookupLimitPerUser = 5
+	QuestionLookupLimitGlobal  = 30
+)
+
+// ErrTooManyLookups 表示密保问题查询过于频繁。
+var ErrTooManyLookups = errors.New("too many security question lookups, please try again later")
+
+// ErrQuestionNotInPool 表示密保问题不在 SecurityQuestionPool 中或有重复。
+var ErrQuestionNotInPool = errors.New("security questions must be 3 different questions from the built-in list")
+
+// SecurityQuestionPool 可选的密保问题。注册和修改密保时只能从中选择 3 个不同的问题，
+// 用户名不存在时的伪装问题也从中挑选。
+// 安全决策: 若允许自由填写，任何不在列表中的问题都能证明该用户名存在，伪装问题将失去意义。
+var SecurityQuestionPool = []string{
+	"您母亲的姓名是？",
+	"您父亲的姓名是？",
+	"您的出生地是？",
+	"您小学的名称是？",
+	"您高中班主任的姓名是？",
+	"您第一只宠物的名字是？",
+	"您最喜欢的电影是？",
+	"您最喜欢的书是？",
+	"您配偶的生日是？",
+	"您童年最好朋友的名字是？",
+	"您第一份工作的公司名称是？",
+	"您最喜欢的食物是？",
+	"您的小名是？",
+	"您第一辆车的品牌是？",
+	"您最喜欢的老师的名字是？",
+	"您大学的专业是？",
+}
+
+// GetSecurityQuestions 查询用户的密保问题 (用于重置前展示)。
+// 安全决策:
+// 1. 问题以 Root Key 派生密钥加密存储，只有持有 Root Key 的本机才能读取。
+// 2. 用户名不存在时返回由 HMAC(Root Key, 用户名) 确定的伪装问题，同一用户名每次结果相同，
+// 调用方无法据此判断用户名是否存在。
+// 3. 查询按用户名和全局限流 (记录中只保存用户名的 HMAC)，存在与不存在的用户名同样计数。
+// 注意: 旧版本注册时可自由填写问题，这类用户修改密保前仍可能被识别出来。
+func (s *Service) GetSecurityQuestions(username string) ([]string, error) {
+	rootKey, err := s.rootKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLookupLimit(rootKey, username); err != nil {
+		return nil, err
+	}
+
+	u, err := s.db.GetUser(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decoyQuestions(rootKey, username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(u.EncQuestions) == 0 {
+		questions := []string{u.Question1, u.Question2, u.Question3}
+		if err := s.migrateQuestions(u, rootKey); err != nil {
+			return nil, err
+		}
+		return questions, nil
+	}
+	return openQuestions(rootKey, u.EncQuestions)
+}
+
+// sealQuestions 用 Root Key 派生密钥加密密保问题。
+func sealQuestions(rootKey []byte, questions []string) ([]byte, error) {
+	data, err := json.Marshal(questions)
+	if err != nil {
+		return nil, err
+	}
+	return encryptWithSubKey(rootKey, infoQuestionsKey, data)
+}
+
+// openQuestions 解密密保问题。
+func openQuestions(rootKey, encQuestions []byte) ([]string, error) {
+	data, err := decryptWithSubKey(rootKey, infoQuestionsKey, encQuestions)
+	if err != nil {
+		return nil, errors.New("failed to decrypt security questions (root key mismatch?)")
+	}
+	var questions []string
+	if err := json.Unmarshal(data, &questions); err != nil {
+		return nil, err
+	}
+	if len(questions) != 3 {
+		return nil, errors.New("corrupted security questions")
+	}
+	return questions, nil
+}
+
+// migrateQuestions 将旧版本明文存储的密保问题加密，并清空明文列。
+func (s *Service) migrateQuestions(u *db.User, rootKey []byte) error {
+	if len(u.EncQuestions) > 0 {
+		return nil
+	}
+	encQuestions, err := sealQuestions(rootKey, []string{u.Question1, u.Question2, u.Question3})
+	if err != nil {
+		return err
+	}
+	stmt := `UPDATE users SET enc_questions=?, question_1='', question_2='', question_3='' WHERE username=?`
+	if _, err := s.db.Exec(stmt, encQuestions, u.Username); err != nil {
+		return err
+	}
+	u.EncQuestions = encQuestions
+	u.Question1, u.Question2, u.Question3 = "", "", ""
+	return nil
+}
+
+// validateQuestions 检查密保问题是 SecurityQuestionPool 中 3 个不同的问题。
+func validateQuestions(questions []string) error {
+	if len(questions) != 3 {
+		return ErrQuestionNotInPool
+	}
+	seen := make(map[string]bool)
+	for _, q := range questions {
+		if seen[q] || !slices.Contains(SecurityQuestionPool, q) {
+			return ErrQuestionNotInPool
+		}
+		seen[q] = true
+	}
+	return nil
+}
+
+// decoyQuestions 为不存在的用户名生成稳定的伪装问题。
+// 以 HMAC 的前三个字节对问题下标做部分 Fisher-Yates 洗牌，结果总是 3 个不同的问题
+// (调用方直接按 [0]..[2] 取用)。
+func decoyQuestions(rootKey []byte, username string) ([]string, error) {
+	if len(SecurityQuestionPool) < 3 {
+		return nil, errors.New("security question pool has fewer than 3 questions")
+	}
+	digest, err := lookupDigest(rootKey, infoDecoyQuestions, username)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := make([]int, len(SecurityQuestionPool))
+	for i := range idx {
+		idx[i] = i
+	}
+	questions := make([]string, 3)
+	for i := range questions {
+		j := i + int(digest[i])%(len(idx)-i)
+		idx[i], idx[j] = idx[j], idx[i]
+		questions[i] = SecurityQuestionPool[idx[i]]
+	}
+	return questions, nil
+}
+
+// checkLookupLimit 检查并记录一次密保问题查询。
+func (s *Service) checkLookupLimit(rootKey []byte, username string) error {
+	now := time.Now()
+	since := now.Add(-QuestionLookupWindow).Unix()
+	if err := s.db.PruneLookupAttempts(since); err != nil {
+		return err
+	}
+
+	userKey, err := lookupDigest(rootKey, infoLookupLimit, username)
+	if err != nil {
+		return err
+	}
+	// 全局计数使用固定 key，长度与 HMAC 不同，不会与任何用户名冲突
+	globalKey := []byte("*")
+
+	n, err := s.db.CountLookupAttempts(userKey, since)
+	if err != nil {
+		return err
+	}
+	total, err := s.db.CountLookupAttempts(globalKey, since)
+	if err != nil {
+		return err
+	}
+	if n >= QuestionLookupLimitPerUser || total >= QuestionLookupLimitGlobal {
+		return ErrTooManyLookups
+	}
+
+	if err := s.db.RecordLookupAttempt(userKey, now.Unix()); err != nil {
+		return err
+	}
+	return s.db.RecordLookupAttempt(globalKey, now.Unix())
+}
+
+// lookupDigest 计算 HMAC(Root Key 派生密钥, 用户名)。
+func lookupDigest(rootKey []byte, info, username string) ([]byte, error) {
+	key, err := crypto.DeriveSubKey(rootKey, info)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(username))
+	return mac.Sum(nil), nil
+}
+
+// SecurityProof 修改密保问题时的身份证明。
+// 提供当前三个密保答案 (Answers)，或提供 TOTP 验证码 (Code，启用主密码时还需 Passphrase)。
+type SecurityProof struct {
+	Answers    []string
+	Code       string
+	Passphrase string
+}
+
+// ChangeSecurityQuestions 修改密保问题和答案。
+// 核心逻辑:
+// 1. 通过当前答案 (A -> M) 或 TOTP 登录 (L -> enc_mb -> M) 恢复 Key M。
+// 2. 生成新的 Salt，由新答案派生新的 Key A。
+// 3. 用新 A 重新加密 M (enc_m)，并更新 A 的包装副本 (enc_a)。
+// 4. 在同一事务中写入新 Salt、加密后的问题和密文。
+// 安全决策: M 不变，因此 Key B、Key C、主密码和恢复码都不受影响，旧答案立即失效。
+func (s *Service) ChangeSecurityQuestions(username string, proof SecurityProof, questions, answers []string) error {
+	if len(questions) != 3 || len(answers) != 3 {
+		return errors.New("must provide exactly 3 questions and 3 answers")
+	}
+	if err := validateQuestions(questions); err != nil {
+		return err
+	}
+	for i := range answers {
+		if crypto.NormalizeAnswer(answers[i]) == "" {
+			return errors.New("security answers must not be empty")
+		}
+	}
+
+	// 1. 恢复 Key M (以及写审计日志所需的 Key C)
+	u, keyM, keyC, err := s.masterKeyFromProof(username, proof)
+	if err != nil {
+		return err
+	}
+
+	// 2. 新 Salt + 新答案 -> 新 Key A
+	salt, err := crypto.GenerateRandomBytes(16)
+	if err != nil {
+		return err
+	}
+	keyA, err := crypto.DeriveKeyA(answers, salt)
+	if err != nil {
+		return err
+	}
+
+	// 3. 用新 A 保护 M
+	encM, err := crypto.EncryptAESGCM(keyA, keyM)
+	if err != nil {
+		return err
+	}
+	encA, err := encryptWithSubKey(keyM, infoAnswerKeyWrap, keyA)
+	if err != nil {
+		return err
+	}
+	rootKey, err := s.rootKey()
+	if err != nil {
+		return err
+	}
+	encQuestions, err := sealQuestions(rootKey, questions)
+	if err != nil {
+		return err
+	}
+
+	// 4. 同一事务中更新
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		stmt := `UPDATE users SET salt=?, question_1='', question_2='', question_3='', enc_questions=?, enc_m=?, enc_a=? WHERE username=?`
+		if _, err := tx.Exec(stmt, salt, encQuestions, encM, encA, u.Username); err != nil {
+			return err
+		}
+		// 之前失败的验证 (暂存事件) 先并入日志链
+		if err := audit.FlushTx(tx, u.Username, keyC); err != nil {
+			return err
+		}
+		return audit.AppendTx(tx, u.Username, keyC, securityEvent("security questions changed"))
+	})
+}
+
+// masterKeyFromProof 根据身份证明恢复 Key M 和 Key C。
+// 安全决策: 用答案验证时与密保问题查询共用限流 (lookup_attempts)，答错记为验证失败事件，
+// 避免已解锁的会话被用来无限次猜测密保答案；OTP 验证的失败由 authenticate 记录。
+func (s *Service) masterKeyFromProof(username string, proof SecurityProof) (u *db.User, keyM, keyC []byte, err error) {
+	if len(proof.Answers) > 0 {
+		rootKey, err := s.rootKey()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := s.checkLookupLimit(rootKey, username); err != nil {
+			return nil, nil, nil, err
+		}
+		u, err = s.db.GetUser(username)
+		if err != nil {
+			return nil, nil, nil, errWrongAnswers
+		}
+		keyA, err := crypto.DeriveKeyA(proof.Answers, u.Salt)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if keyM, err = crypto.DecryptAESGCM(keyA, u.EncM); err != nil {
+			_ = s.audit.RecordPending(username, audit.EventAuthFailed, "wrong security answers (change security questions)")
+			return nil, nil, nil, errWrongAnswers
+		}
+		if keyC, err = recoverKeyC(u, keyM); err != nil {
+			return nil, nil, nil, err
+		}
+		return u, keyM, keyC, nil
+	}
+
+	sess, err := s.authenticate(username, proof.Code, proof.Passphrase)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if keyM, err = masterKey(sess); err != nil {
+		return nil, nil, nil, err
+	}
+	return sess.user, keyM, sess.keyC, nil
+}
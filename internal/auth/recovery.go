@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+
+	"key-box/internal/audit"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// RecoveryCodeCount 每次发放的一次性恢复码数量。
+const RecoveryCodeCount = 10
+
+// ErrInvalidRecoveryCode 表示恢复码错误或已被使用。
+var ErrInvalidRecoveryCode = errors.New("invalid or already used recovery code")
+
+// issueRecoveryCodes 生成一组恢复码，每个恢复码各自加密一份 Recovery Key W。
+// 安全决策:
+// 1. 恢复码只以明文形式返回给用户一次，数据库中只保存 Argon2id(恢复码, Salt) 加密的 W。
+// 2. 每个恢复码使用独立 Salt，互相之间无法推导。
+// 3. W 再解开 Key M 的副本 (enc_mw)，因此密钥轮转后未使用的恢复码依然有效。
+func issueRecoveryCodes(keyW []byte) ([]string, []db.RecoveryCode, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	rows := make([]db.RecoveryCode, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := crypto.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		salt, err := crypto.GenerateRandomBytes(16)
+		if err != nil {
+			return nil, nil, err
+		}
+		codeKey := crypto.DerivePassphraseKey(crypto.NormalizeRecoveryCode(code), salt)
+		encW, err := crypto.EncryptAESGCM(codeKey, keyW)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, db.RecoveryCode{Salt: salt, EncW: encW})
+	}
+	return codes, rows, nil
+}
+
+// RemainingRecoveryCodes 返回用户剩余可用的恢复码数量。
+func (s *Service) RemainingRecoveryCodes(username string) (int, error) {
+	return s.db.CountUnusedRecoveryCodes(username)
+}
+
+// RegenerateRecoveryCodes 重新生成一组恢复码，旧的恢复码全部作废。
+// 核心逻辑:
+// 1. 通过 TOTP 登录 (及主密码) 拿到解锁密钥，解开 enc_mb 得到 Key M。
+// 2. 生成新的 Recovery Key W，使旧恢复码加密的 W 失效。
+// 3. 在同一事务中更新 enc_w、enc_mw 并替换恢复码。
+func (s *Service) RegenerateRecoveryCodes(username, code, passphrase string) ([]string, error) {
+	sess, err := s.authenticate(username, code, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	keyM, err := masterKey(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	keyW, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	encW, err := encryptWithSubKey(keyM, infoRecoveryKeyWrap, keyW)
+	if err != nil {
+		return nil, err
+	}
+	encMW, err := crypto.EncryptAESGCM(keyW, keyM)
+	if err != nil {
+		return nil, err
+	}
+	codes, rows, err := issueRecoveryCodes(keyW)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		stmt := `UPDATE users SET enc_w=?, enc_mw=? WHERE username=?`
+		if _, err := tx.Exec(stmt, encW, encMW, username); err != nil {
+			return err
+		}
+		if err := db.ReplaceRecoveryCodes(tx, username, rows); err != nil {
+			return err
+		}
+		return audit.AppendTx(tx, username, sess.keyC, securityEvent("recovery codes regenerated"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RedeemRecoveryCode 使用一次性恢复码代替密保答案完成重置。
+// 核心逻辑:
+// 1. 逐个尝试未使用的恢复码记录，解开 W。
+// 2. 用 W 解开 Key M，再用 M 解开 Key A。
+// 3. 执行与 ResetPassword 相同的密钥轮转，并在同一事务中将该恢复码标记为已使用。
+func (s *Service) RedeemRecoveryCode(username, recoveryCode string) (*RegisterResult, error) {
+	u, err := s.db.GetUser(username)
+	if err != nil {
+		return nil, ErrInvalidRecoveryCode
+	}
+	rows, err := s.db.GetRecoveryCodes(username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 查找匹配的恢复码
+	normalized := crypto.NormalizeRecoveryCode(recoveryCode)
+	var keyW []byte
+	var usedID int
+	for _, row := range rows {
+		if row.UsedAt.Valid {
+			continue
+		}
+		codeKey := crypto.DerivePassphraseKey(normalized, row.Salt)
+		if w, err := crypto.DecryptAESGCM(codeKey, row.EncW); err == nil {
+			keyW, usedID = w, row.ID
+			break
+		}
+	}
+	if keyW == nil {
+		_ = s.audit.RecordPending(username, audit.EventResetFailed, "invalid recovery code")
+		return nil, ErrInvalidRecoveryCode
+	}
+
+	// 2. W -> M -> A
+	keyM, err := crypto.DecryptAESGCM(keyW, u.EncMW)
+	if err != nil {
+		return nil, errors.New("failed to recover Key M (recovery codes are outdated, please regenerate)")
+	}
+	keyA, err := decryptWithSubKey(keyM, infoAnswerKeyWrap, u.EncA)
+	if err != nil {
+		return nil, errors.New("failed to recover Key A")
+	}
+
+	// 3. 轮转密钥并消耗恢复码
+	return s.rotateMasterKey(u, keyA, keyM, "recovery code", func(ex db.Execer) error {
+		return db.MarkRecoveryCodeUsed(ex, usedID)
+	})
+}
@@ -0,0 +1,150 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"key-box/internal/auth"
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// newTestService 在临时 HOME 中创建数据库和使用 Salt 的 Root Key。
+func newTestService(t *testing.T) *auth.Service {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SEC_APP_SALT", "recovery-test-salt")
+	d, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return auth.NewService(d, crypto.SaltRootKeyProvider{})
+}
+
+func register(t *testing.T, s *auth.Service, username string) *auth.RegisterResult {
+	t.Helper()
+	q := auth.SecurityQuestionPool
+	res, err := s.Register(username, q[0], q[1], q[2], "alice", "beijing", "no.1 primary")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return res
+}
+
+// login 用 RegisterResult 中的 Key B 生成当前 OTP 并登录，返回 Key C。
+func login(t *testing.T, s *auth.Service, username string, res *auth.RegisterResult) []byte {
+	t.Helper()
+	keyB, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(res.SecretKeyBBase32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyC, err := s.Login(username, crypto.GenerateTOTP(keyB, time.Now()), "")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return keyC
+}
+
+func remaining(t *testing.T, s *auth.Service, username string) int {
+	t.Helper()
+	n, err := s.RemainingRecoveryCodes(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRegisterIssuesRecoveryCodes(t *testing.T) {
+	s := newTestService(t)
+	res := register(t, s, "alice")
+
+	if len(res.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(res.RecoveryCodes), auth.RecoveryCodeCount)
+	}
+	seen := make(map[string]bool)
+	for _, c := range res.RecoveryCodes {
+		if seen[c] {
+			t.Errorf("duplicate recovery code %q", c)
+		}
+		seen[c] = true
+	}
+	if n := remaining(t, s, "alice"); n != auth.RecoveryCodeCount {
+		t.Errorf("RemainingRecoveryCodes = %d, want %d", n, auth.RecoveryCodeCount)
+	}
+}
+
+func TestRedeemRecoveryCode(t *testing.T) {
+	s := newTestService(t)
+	res := register(t, s, "alice")
+	keyC := login(t, s, "alice", res)
+
+	redeemed, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[0])
+	if err != nil {
+		t.Fatalf("RedeemRecoveryCode: %v", err)
+	}
+	if redeemed.SecretKeyBBase32 == res.SecretKeyBBase32 {
+		t.Error("redeeming a recovery code did not rotate Key B")
+	}
+	// 数据密钥不变，已有条目仍可解密
+	if got := login(t, s, "alice", redeemed); string(got) != string(keyC) {
+		t.Error("Key C changed after redeeming a recovery code")
+	}
+	if n := remaining(t, s, "alice"); n != auth.RecoveryCodeCount-1 {
+		t.Errorf("RemainingRecoveryCodes = %d, want %d", n, auth.RecoveryCodeCount-1)
+	}
+
+	// 已使用的恢复码不能再次使用，错误的恢复码同样被拒绝
+	if _, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[0]); !errors.Is(err, auth.ErrInvalidRecoveryCode) {
+		t.Errorf("reusing a recovery code: err = %v, want ErrInvalidRecoveryCode", err)
+	}
+	if _, err := s.RedeemRecoveryCode("alice", "ABCDE-FGHJK"); !errors.Is(err, auth.ErrInvalidRecoveryCode) {
+		t.Errorf("wrong recovery code: err = %v, want ErrInvalidRecoveryCode", err)
+	}
+
+	// 密钥轮转后其余恢复码仍然有效
+	if _, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[1]); err != nil {
+		t.Errorf("second recovery code after rotation: %v", err)
+	}
+}
+
+func TestRestoreKeepsRecoveryCodes(t *testing.T) {
+	s := newTestService(t)
+	res := register(t, s, "alice")
+	if _, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 备份: 用户记录和恢复码记录
+	u, err := s.GetUserInfo("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := s.GetRecoveryCodes("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 覆盖恢复: 先删除用户，再写回备份
+	if err := s.DeleteUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if n := remaining(t, s, "alice"); n != 0 {
+		t.Fatalf("RemainingRecoveryCodes after DeleteUser = %d, want 0", n)
+	}
+	if err := s.RestoreUser(u, codes); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	if n := remaining(t, s, "alice"); n != auth.RecoveryCodeCount-1 {
+		t.Errorf("RemainingRecoveryCodes after restore = %d, want %d", n, auth.RecoveryCodeCount-1)
+	}
+	if _, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[0]); !errors.Is(err, auth.ErrInvalidRecoveryCode) {
+		t.Errorf("code used before the backup: err = %v, want ErrInvalidRecoveryCode", err)
+	}
+	if _, err := s.RedeemRecoveryCode("alice", res.RecoveryCodes[2]); err != nil {
+		t.Errorf("unused code after restore: %v", err)
+	}
+}
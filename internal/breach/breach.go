@@ -0,0 +1,243 @@
+// Package breach 离线检查密码是否出现在 Have I Been Pwned (HIBP) 泄露密码数据集中。
+//
+// 支持三种本地数据:
+//  1. HIBP 原始文本 (pwned-passwords-sha1-ordered-by-hash)，每行 "SHA1:次数"，顺序读取整个文件。
+//  2. 按前缀下载的范围文件目录 (PwnedPasswordsDownloader)，文件名为 5 位哈希前缀，每行 "后 35 位:次数"；
+//     缺失的前缀文件视为该范围内没有泄露记录。
+//  3. BuildIndex 生成的二进制索引，按哈希排序的定长记录，二分查找。
+//
+// 安全决策: 待检查密码的 SHA-1 只保存在内存中，不写入任何文件或日志。
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"key-box/internal/vault"
+)
+
+// Hash 密码的 SHA-1 (HIBP 使用的哈希)
+type Hash [sha1.Size]byte
+
+// HashPassword 计算密码的 SHA-1。
+func HashPassword(password string) Hash {
+	return sha1.Sum([]byte(password))
+}
+
+// Source 本地泄露数据。
+type Source interface {
+	// Lookup 返回各哈希在数据集中出现的次数，未出现的哈希不在结果中。
+	Lookup(hashes []Hash) (map[Hash]int, error)
+	Close() error
+}
+
+// ErrUnknownFormat 无法识别的数据文件
+var ErrUnknownFormat = errors.New("unrecognized breach data format")
+
+// Open 打开本地泄露数据，根据路径自动识别格式: 目录视为范围文件目录，
+// 以索引文件头开头的视为二进制索引，其余视为原始文本。
+func Open(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		if matches, _ := filepath.Glob(filepath.Join(path, "[0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f]*")); len(matches) == 0 {
+			return nil, fmt.Errorf("%w: no range files in %s", ErrUnknownFormat, path)
+		}
+		return &rangeDir{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(f, magic); err == nil && string(magic) == indexMagic {
+		return openIndex(f, info.Size())
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	first, _ := bufio.NewReader(f).ReadSlice('\n')
+	if _, _, err := parseLine(bytes.TrimRight(first, "\n"), 2*sha1.Size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+	}
+	return &textFile{f: f}, nil
+}
+
+// Exposed 出现在泄露数据中的条目。
+type Exposed struct {
+	vault.ItemRef
+	// Count 该密码在泄露数据中出现的次数
+	Count int `json:"count"`
+}
+
+// CheckItems 检查已解密的条目，按出现次数从多到少返回泄露的条目。
+func CheckItems(src Source, items []vault.VaultItem) ([]Exposed, error) {
+	hashes := make([]Hash, 0, len(items))
+	byHash := make(map[Hash][]vault.ItemRef)
+	for _, item := range items {
+		if item.Password == "" {
+			continue
+		}
+		h := HashPassword(item.Password)
+		if _, ok := byHash[h]; !ok {
+			hashes = append(hashes, h)
+		}
+		byHash[h] = append(byHash[h], vault.ItemRef{ID: item.ID, Site: item.Site, Username: item.Username, UpdatedAt: item.UpdatedAt})
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	counts, err := src.Lookup(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	var exposed []Exposed
+	for _, h := range hashes {
+		count, ok := counts[h]
+		if !ok {
+			continue
+		}
+		for _, ref := range byHash[h] {
+			exposed = append(exposed, Exposed{ItemRef: ref, Count: count})
+		}
+	}
+	sort.SliceStable(exposed, func(i, j int) bool { return exposed[i].Count > exposed[j].Count })
+	return exposed, nil
+}
+
+// parseLine 解析 "十六进制哈希:次数" 行，hexLen 为哈希部分的长度 (完整 40 位或去掉前缀的 35 位)。
+func parseLine(line []byte, hexLen int) (hexHash []byte, count int, err error) {
+	line = bytes.TrimRight(line, "\r")
+	sep := bytes.IndexByte(line, ':')
+	if sep != hexLen {
+		return nil, 0, fmt.Errorf("malformed line %q", truncate(line))
+	}
+	count, err = strconv.Atoi(string(line[sep+1:]))
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed count in line %q", truncate(line))
+	}
+	return bytes.ToUpper(line[:sep]), count, nil
+}
+
+func truncate(b []byte) string {
+	if len(b) > 60 {
+		return string(b[:60]) + "..."
+	}
+	return string(b)
+}
+
+func upperHex(h Hash) string {
+	return strings.ToUpper(hex.EncodeToString(h[:]))
+}
+
+// textFile 原始文本: 顺序扫描一遍，与所有待查哈希比对。
+type textFile struct {
+	f *os.File
+}
+
+func (t *textFile) Lookup(hashes []Hash) (map[Hash]int, error) {
+	// 数据集中的哈希通常为大写，也兼容小写
+	wanted := make(map[string]Hash, 2*len(hashes))
+	for _, h := range hashes {
+		wanted[upperHex(h)] = h
+		wanted[hex.EncodeToString(h[:])] = h
+	}
+
+	if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	found := make(map[Hash]int)
+	scanner := bufio.NewScanner(bufio.NewReaderSize(t.f, 1<<20))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) < 2*sha1.Size {
+			continue
+		}
+		if h, ok := wanted[string(line[:2*sha1.Size])]; ok {
+			_, count, err := parseLine(line, 2*sha1.Size)
+			if err != nil {
+				return nil, err
+			}
+			found[h] = count
+			if len(found) == len(hashes) {
+				break
+			}
+		}
+	}
+	return found, scanner.Err()
+}
+
+func (t *textFile) Close() error {
+	return t.f.Close()
+}
+
+// rangeDir 范围文件目录: 每个哈希只读取对应前缀的文件 (约 30 KB)。
+type rangeDir struct {
+	dir string
+}
+
+func (r *rangeDir) Lookup(hashes []Hash) (map[Hash]int, error) {
+	found := make(map[Hash]int)
+	for _, h := range hashes {
+		full := upperHex(h)
+		prefix, suffix := full[:5], full[5:]
+
+		f, err := os.Open(filepath.Join(r.dir, prefix+".txt"))
+		if errors.Is(err, os.ErrNotExist) {
+			// 下载器旧版本不带扩展名
+			f, err = os.Open(filepath.Join(r.dir, prefix))
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// 只下载了部分前缀时，缺失的范围视为未泄露
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("range file %s: %w", prefix, err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) < len(suffix) || !bytes.EqualFold(line[:len(suffix)], []byte(suffix)) {
+				continue
+			}
+			_, count, err := parseLine(line, len(suffix))
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			// 填充 (padding) 记录的次数为 0
+			if count > 0 {
+				found[h] = count
+			}
+			break
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func (r *rangeDir) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// 剪贴板管理器识别的敏感内容提示。
+// KDE Klipper、GNOME 剪贴板扩展、CopyQ 等在目标列表中发现这些类型时不记录历史。
+const (
+	KDEPasswordHint   = "x-kde-passwordManagerHint"
+	SensitiveHint     = "CLIPBOARD_SENSITIVE"
+	SensitiveHintData = "secret"
+)
+
+// ErrUnavailable 表示当前环境没有可用的剪贴板 (没有 X11 显示，也没有 wl-copy)。
+var ErrUnavailable = errors.New("no clipboard available (need an X11 display or wl-clipboard)")
+
+// Clipboard 系统剪贴板。
+type Clipboard interface {
+	// Name 后端名称，用于提示信息。
+	Name() string
+	// Sensitive 写入时是否附带敏感内容提示。为 false 时剪贴板管理器可能记录复制的内容，
+	// 调用方应提示用户。
+	Sensitive() bool
+	// Copy 写入文本，并在后端支持时附带敏感内容提示。
+	Copy(text string) error
+	// Holds 剪贴板当前内容是否仍是 text。
+	Holds(text string) (bool, error)
+	// Clear 清空剪贴板。
+	Clear() error
+}
+
+// Open 根据当前桌面环境选择剪贴板后端。
+// 核心逻辑:
+//  1. 有 DISPLAY 时直接以 X11 选区所有者身份提供内容 (Wayland 会话中经 XWayland 同步，提示同样生效)。
+//  2. 只有 WAYLAND_DISPLAY 时使用 wl-copy / wl-paste (wl-copy 只能提供一种类型，不带提示，Sensitive 为 false)。
+func Open() (Clipboard, error) {
+	if os.Getenv("DISPLAY") != "" {
+		return NewX11(), nil
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-copy"); err == nil {
+			return &wlClipboard{}, nil
+		}
+	}
+	return nil, ErrUnavailable
+}
+
+// ClearIfHeld 剪贴板内容仍是 text 时清空，返回是否清空。
+// 用户在此期间复制了其他内容时保留其内容不动。
+func ClearIfHeld(c Clipboard, text string) (bool, error) {
+	held, err := c.Holds(text)
+	if err != nil || !held {
+		return false, err
+	}
+	if err := c.Clear(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
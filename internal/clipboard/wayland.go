@@ -0,0 +1,93 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// wlErrorLimit 错误信息中保留的 wl-copy 输出字节数上限
+const wlErrorLimit = 4096
+
+// wlEmptyMessages wl-paste 在剪贴板为空或没有文本内容时的错误输出 (此时以状态 1 退出)
+var wlEmptyMessages = []string{"Nothing is copied", "No selection", "No suitable type of content copied"}
+
+// wlClipboard 通过 wl-clipboard 命令访问纯 Wayland 会话 (没有 XWayland) 的剪贴板。
+// wl-copy 在后台进程中提供内容，调用方退出后剪贴板内容仍然保留。
+// 注意: wl-copy 只能提供一种类型，无法附带敏感内容提示 (Sensitive 返回 false)。
+type wlClipboard struct{}
+
+func (w *wlClipboard) Name() string {
+	return "wl-clipboard"
+}
+
+func (w *wlClipboard) Sensitive() bool {
+	return false
+}
+
+func (w *wlClipboard) Copy(text string) error {
+	return runWlCopy(strings.NewReader(text), "--type", "text/plain;charset=utf-8")
+}
+
+func (w *wlClipboard) Holds(text string) (bool, error) {
+	out, err := exec.Command("wl-paste", "--no-newline").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && isWlEmpty(exitErr.Stderr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("wl-paste failed: %w", wlCommandError(err))
+	}
+	return string(out) == text, nil
+}
+
+func (w *wlClipboard) Clear() error {
+	return runWlCopy(nil, "--clear")
+}
+
+// runWlCopy 运行 wl-copy 并等待前台进程退出。
+// wl-copy 会派生出继续提供内容的后台进程，它继承标准输出和错误输出：用管道收集输出时
+// (CombinedOutput) 要等后台进程退出管道才关闭，调用会一直阻塞。因此标准输出不连接，
+// 错误输出写入已删除的临时文件，失败时只读取开头的 wlErrorLimit 字节。
+func runWlCopy(stdin io.Reader, args ...string) error {
+	errFile, err := os.CreateTemp("", "key-box-wl-copy-*")
+	if err != nil {
+		return err
+	}
+	os.Remove(errFile.Name())
+	defer errFile.Close()
+
+	cmd := exec.Command("wl-copy", args...)
+	cmd.Stdin = stdin
+	cmd.Stderr = errFile
+	if err := cmd.Run(); err != nil {
+		var msg []byte
+		if _, seekErr := errFile.Seek(0, io.SeekStart); seekErr == nil {
+			msg, _ = io.ReadAll(io.LimitReader(errFile, wlErrorLimit))
+		}
+		return fmt.Errorf("wl-copy %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// isWlEmpty wl-paste 的错误输出是否表示剪贴板为空 (而不是无法连接等错误)。
+func isWlEmpty(stderr []byte) bool {
+	for _, m := range wlEmptyMessages {
+		if strings.Contains(string(stderr), m) {
+			return true
+		}
+	}
+	return false
+}
+
+// wlCommandError 在错误中附带命令的错误输出 (exec 只保留其开头和结尾)。
+func wlCommandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,440 @@
+package clipboard
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"key-box/internal/x11"
+)
+
+// x11ReplyTimeout 等待 X 服务器回复 (时间戳、选区所有者) 的最长时间
+const x11ReplyTimeout = 2 * time.Second
+
+// ErrNotOwner 表示申请 CLIPBOARD 所有权后查询到的所有者不是本进程
+// (例如其他程序在同一时刻取得了所有权)。
+var ErrNotOwner = errors.New("failed to take ownership of the X11 clipboard")
+
+// X11 以 CLIPBOARD 选区所有者的身份直接通过 X11 协议提供剪贴板内容。
+// 安全决策:
+//  1. 内容只保存在本进程内存中，其他程序粘贴时才按需传输 (X11 选区机制)。
+//  2. 目标列表中附带 x-kde-passwordManagerHint 和 CLIPBOARD_SENSITIVE (值为 "secret")，
+//     剪贴板管理器据此不记录历史；Wayland 会话中 XWayland 会把这些目标同步为 MIME 类型。
+//  3. 申请和放弃所有权都使用服务器时间戳 (ICCCM 禁止 CurrentTime)，申请后用 GetSelectionOwner 确认，
+//     未取得所有权时 Copy 返回错误，而不是误报复制成功。
+//  4. 其他程序复制内容后本进程收到 SelectionClear 并丢弃秘密，Holds 随即返回 false，不会误清用户的新内容。
+//  5. 进程退出时 X 服务器自动撤销所有权，秘密不会残留在剪贴板中。
+type X11 struct {
+	// op 串行化 Copy 和 Clear: 二者等待服务器回复期间不能持有 mu，否则 serve 无法处理事件
+	op sync.Mutex
+
+	mu    sync.Mutex
+	dial  func() (*x11.Conn, error)
+	conn  *x11Conn
+	text  string
+	owned bool
+}
+
+// NewX11 创建使用 DISPLAY 环境变量所指显示的 X11 剪贴板，首次复制时才建立连接。
+func NewX11() *X11 {
+	display := os.Getenv("DISPLAY")
+	return newX11(func() (*x11.Conn, error) {
+		return x11.Dial(display)
+	})
+}
+
+// newX11 创建通过 dial 连接 X 服务器的剪贴板 (测试中连接假服务器)。
+func newX11(dial func() (*x11.Conn, error)) *X11 {
+	return &X11{dial: dial}
+}
+
+func (x *X11) Name() string {
+	return "x11"
+}
+
+func (x *X11) Sensitive() bool {
+	return true
+}
+
+// Copy 申请 CLIPBOARD 所有权并提供 text。
+// 核心逻辑:
+// 1. 向自己的窗口追加零长度属性，从 PropertyNotify 事件取得服务器当前时间。
+// 2. 以该时间调用 SetSelectionOwner。
+// 3. GetSelectionOwner 确认所有者是本进程的窗口，否则返回 ErrNotOwner。
+func (x *X11) Copy(text string) error {
+	x.op.Lock()
+	defer x.op.Unlock()
+
+	c, err := x.connect()
+	if err != nil {
+		return err
+	}
+	if len(text) > c.maxPropertyBytes() {
+		return fmt.Errorf("text too large for the clipboard (%d bytes)", len(text))
+	}
+
+	t, err := c.serverTime()
+	if err != nil {
+		return err
+	}
+	// 先保存内容，取得所有权后立即到达的粘贴请求也能得到回应
+	x.setState(text, true)
+	if err := c.setSelectionOwner(c.window, c.atoms.clipboard, t); err != nil {
+		x.setState("", false)
+		return err
+	}
+	owner, err := c.selectionOwner(c.atoms.clipboard)
+	if err == nil && owner != c.window {
+		err = ErrNotOwner
+	}
+	if err != nil {
+		x.setState("", false)
+		return err
+	}
+	// 此前其他程序取得所有权的 SelectionClear 在回复之前已由 serve 处理，这里重新标记为所有者
+	x.setState(text, true)
+	return nil
+}
+
+func (x *X11) Holds(text string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.owned && x.text == text, nil
+}
+
+// Clear 仍是 CLIPBOARD 所有者时放弃所有权。
+// 安全决策: 放弃前先向服务器确认所有者仍是本进程，避免 SelectionClear 尚未送达时清掉其他程序刚复制的内容。
+func (x *X11) Clear() error {
+	x.op.Lock()
+	defer x.op.Unlock()
+
+	x.mu.Lock()
+	c, owned := x.conn, x.owned
+	x.text, x.owned = "", false
+	x.mu.Unlock()
+	if !owned || c == nil {
+		return nil
+	}
+
+	owner, err := c.selectionOwner(c.atoms.clipboard)
+	if err != nil || owner != c.window {
+		return err
+	}
+	t, err := c.serverTime()
+	if err != nil {
+		return err
+	}
+	if err := c.setSelectionOwner(0, c.atoms.clipboard, t); err != nil {
+		return err
+	}
+	// 等待服务器处理完毕 (本进程的 SelectionClear 随之送达并被忽略)
+	_, err = c.selectionOwner(c.atoms.clipboard)
+	return err
+}
+
+// connect 返回当前连接，尚未连接时建立连接并启动 serve。
+func (x *X11) connect() (*x11Conn, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.conn != nil {
+		return x.conn, nil
+	}
+	xc, err := x.dial()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newX11Conn(xc)
+	if err != nil {
+		xc.Close()
+		return nil, err
+	}
+	x.conn = c
+	go x.serve(c)
+	return c, nil
+}
+
+func (x *X11) setState(text string, owned bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.text, x.owned = text, owned
+}
+
+// serve 处理其他程序的粘贴请求和所有权变更，连接断开时退出。
+func (x *X11) serve(c *x11Conn) {
+	for {
+		ev, err := c.readEvent()
+		if err != nil {
+			x.mu.Lock()
+			x.conn, x.owned, x.text = nil, false, ""
+			x.mu.Unlock()
+			close(c.closed)
+			return
+		}
+
+		x.mu.Lock()
+		switch ev.code {
+		case x11.EventSelectionRequest:
+			c.answer(ev, x.owned, x.text)
+		case x11.EventSelectionClear:
+			if ev.selection == c.atoms.clipboard {
+				x.owned, x.text = false, ""
+			}
+		}
+		x.mu.Unlock()
+	}
+}
+
+// ---- X11 选区所有者 (连接和基础请求见 internal/x11) ----
+
+const (
+	x11WindowClassInputOnly = 2
+	x11CWEventMask          = 1 << 11
+	x11PropertyChangeMask   = 1 << 22
+	x11PropModeAppend       = 2
+	x11TimestampProperty    = "_KEY_BOX_TIMESTAMP"
+	// ReadPacket 读到的错误和回复的首字节 (事件为事件代码)
+	x11ErrorPacket = 0
+	x11ReplyPacket = 1
+)
+
+type x11Atoms struct {
+	clipboard, targets, utf8String, text, mimeUTF8, mimePlain, kdeHint, sensitiveHint, timestamp uint32
+}
+
+// x11Conn 剪贴板使用的连接。serve 所在的 goroutine 是唯一的读取方 (readEvent)，
+// 它把时间戳和回复转交给 Copy / Clear 中等待的请求。
+type x11Conn struct {
+	*x11.Conn
+	window uint32
+	atoms  x11Atoms
+
+	times   chan uint32
+	replies chan x11Reply
+	closed  chan struct{}
+}
+
+type x11Reply struct {
+	buf []byte
+	err error
+}
+
+type x11Event struct {
+	code                                         byte
+	time, requestor, selection, target, property uint32
+}
+
+// newX11Conn 在新连接上创建一个不可见窗口作为选区所有者，并查询所需的 atom。
+// 此时 serve 尚未启动，可以直接读取回复。
+func newX11Conn(xc *x11.Conn) (*x11Conn, error) {
+	c := &x11Conn{
+		Conn:    xc,
+		times:   make(chan uint32, 1),
+		replies: make(chan x11Reply, 1),
+		closed:  make(chan struct{}),
+	}
+
+	names := []struct {
+		name string
+		atom *uint32
+	}{
+		{"CLIPBOARD", &c.atoms.clipboard},
+		{"TARGETS", &c.atoms.targets},
+		{"UTF8_STRING", &c.atoms.utf8String},
+		{"TEXT", &c.atoms.text},
+		{"text/plain;charset=utf-8", &c.atoms.mimeUTF8},
+		{"text/plain", &c.atoms.mimePlain},
+		{KDEPasswordHint, &c.atoms.kdeHint},
+		{SensitiveHint, &c.atoms.sensitiveHint},
+		{x11TimestampProperty, &c.atoms.timestamp},
+	}
+	for _, n := range names {
+		var err error
+		if *n.atom, err = c.InternAtom(n.name); err != nil {
+			return nil, err
+		}
+	}
+
+	c.window = c.NewID()
+	if err := c.createWindow(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// createWindow 创建选区所有者窗口，接收自身属性变化事件 (用于获取服务器时间)。
+func (c *x11Conn) createWindow() error {
+	req := c.Request(x11.OpCreateWindow, 0, 32)
+	binary.LittleEndian.PutUint32(req[4:], c.window)
+	binary.LittleEndian.PutUint32(req[8:], c.Root)
+	binary.LittleEndian.PutUint16(req[16:], 1) // width
+	binary.LittleEndian.PutUint16(req[18:], 1) // height
+	binary.LittleEndian.PutUint16(req[22:], x11WindowClassInputOnly)
+	binary.LittleEndian.PutUint32(req[28:], x11CWEventMask)
+	binary.LittleEndian.PutUint32(req[32:], x11PropertyChangeMask)
+	_, err := c.Write(req)
+	return err
+}
+
+// serverTime 向自己的窗口追加零长度属性，从随后的 PropertyNotify 事件取得服务器当前时间。
+func (c *x11Conn) serverTime() (uint32, error) {
+	select {
+	case <-c.times: // 丢弃之前超时未取走的时间戳
+	default:
+	}
+	req := c.Request(x11.OpChangeProperty, x11PropModeAppend, 20)
+	binary.LittleEndian.PutUint32(req[4:], c.window)
+	binary.LittleEndian.PutUint32(req[8:], c.atoms.timestamp)
+	binary.LittleEndian.PutUint32(req[12:], x11.AtomString)
+	req[16] = 8 // format
+	if _, err := c.Write(req); err != nil {
+		return 0, err
+	}
+
+	select {
+	case t := <-c.times:
+		return t, nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	case <-time.After(x11ReplyTimeout):
+		return 0, errors.New("timed out waiting for the X server timestamp")
+	}
+}
+
+// setSelectionOwner owner 为 0 表示放弃所有权，t 为服务器时间戳。
+func (c *x11Conn) setSelectionOwner(owner, selection, t uint32) error {
+	req := c.Request(x11.OpSetSelectionOwner, 0, 12)
+	binary.LittleEndian.PutUint32(req[4:], owner)
+	binary.LittleEndian.PutUint32(req[8:], selection)
+	binary.LittleEndian.PutUint32(req[12:], t)
+	_, err := c.Write(req)
+	return err
+}
+
+// selectionOwner 查询选区当前的所有者窗口 (0 表示没有所有者)。
+func (c *x11Conn) selectionOwner(selection uint32) (uint32, error) {
+	select {
+	case <-c.replies: // 丢弃之前超时未取走的回复
+	default:
+	}
+	req := c.Request(x11.OpGetSelectionOwner, 0, 4)
+	binary.LittleEndian.PutUint32(req[4:], selection)
+	if _, err := c.Write(req); err != nil {
+		return 0, err
+	}
+
+	select {
+	case r := <-c.replies:
+		if r.err != nil {
+			return 0, r.err
+		}
+		return binary.LittleEndian.Uint32(r.buf[8:]), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	case <-time.After(x11ReplyTimeout):
+		return 0, errors.New("timed out waiting for the X server reply")
+	}
+}
+
+// answer 响应 SelectionRequest: 把内容写入请求方窗口的属性，并发送 SelectionNotify。
+// 不支持的目标或已失去所有权时 property 为 None，表示拒绝。
+func (c *x11Conn) answer(ev x11Event, owned bool, text string) {
+	property := ev.property
+	if property == 0 {
+		// 旧版客户端: 使用目标 atom 作为属性名
+		property = ev.target
+	}
+
+	a := c.atoms
+	var typ uint32
+	var format byte = 8
+	var data []byte
+	switch {
+	case !owned || ev.selection != a.clipboard:
+	case ev.target == a.targets:
+		typ, format = x11.AtomAtom, 32
+		for _, t := range []uint32{a.targets, a.utf8String, a.text, a.mimeUTF8, a.mimePlain, x11.AtomString, a.kdeHint, a.sensitiveHint} {
+			data = binary.LittleEndian.AppendUint32(data, t)
+		}
+	case ev.target == a.utf8String || ev.target == a.text:
+		typ, data = a.utf8String, []byte(text)
+	case ev.target == a.mimeUTF8 || ev.target == a.mimePlain || ev.target == x11.AtomString:
+		typ, data = ev.target, []byte(text)
+	case ev.target == a.kdeHint || ev.target == a.sensitiveHint:
+		typ, data = ev.target, []byte(SensitiveHintData)
+	}
+
+	if typ == 0 || c.ChangeProperty(ev.requestor, property, typ, format, data) != nil {
+		property = 0
+	}
+
+	req := c.Request(x11.OpSendEvent, 0, 40)
+	binary.LittleEndian.PutUint32(req[4:], ev.requestor)
+	notify := req[12:]
+	notify[0] = x11.EventSelectionNotify
+	binary.LittleEndian.PutUint32(notify[4:], ev.time)
+	binary.LittleEndian.PutUint32(notify[8:], ev.requestor)
+	binary.LittleEndian.PutUint32(notify[12:], ev.selection)
+	binary.LittleEndian.PutUint32(notify[16:], ev.target)
+	binary.LittleEndian.PutUint32(notify[20:], property)
+	c.Write(req)
+}
+
+// readEvent 读取下一个选区事件。期间收到的时间戳 (PropertyNotify) 和 GetSelectionOwner 的回复、错误
+// 转交给等待中的请求，其他事件和错误被丢弃 (例如 answer 写入已销毁的请求方窗口产生的 BadWindow)。
+func (c *x11Conn) readEvent() (x11Event, error) {
+	for {
+		buf, err := c.ReadPacket()
+		if err != nil {
+			return x11Event{}, err
+		}
+
+		switch code := buf[0] & 0x7f; code {
+		case x11ReplyPacket:
+			// 剪贴板连接上只有 GetSelectionOwner 有回复
+			c.deliver(x11Reply{buf: buf})
+		case x11ErrorPacket:
+			if xerr := x11.ErrorFromPacket(buf); xerr.Major == x11.OpGetSelectionOwner {
+				c.deliver(x11Reply{err: xerr})
+			}
+		case x11.EventPropertyNotify:
+			if binary.LittleEndian.Uint32(buf[4:]) == c.window && binary.LittleEndian.Uint32(buf[8:]) == c.atoms.timestamp {
+				select {
+				case c.times <- binary.LittleEndian.Uint32(buf[12:]):
+				default:
+				}
+			}
+		case x11.EventSelectionRequest:
+			return x11Event{
+				code:      code,
+				time:      binary.LittleEndian.Uint32(buf[4:]),
+				requestor: binary.LittleEndian.Uint32(buf[12:]),
+				selection: binary.LittleEndian.Uint32(buf[16:]),
+				target:    binary.LittleEndian.Uint32(buf[20:]),
+				property:  binary.LittleEndian.Uint32(buf[24:]),
+			}, nil
+		case x11.EventSelectionClear:
+			return x11Event{
+				code:      code,
+				time:      binary.LittleEndian.Uint32(buf[4:]),
+				selection: binary.LittleEndian.Uint32(buf[12:]),
+			}, nil
+		}
+	}
+}
+
+// deliver 把回复交给等待中的请求，没有请求在等待时丢弃。
+func (c *x11Conn) deliver(r x11Reply) {
+	select {
+	case c.replies <- r:
+	default:
+	}
+}
+
+// maxPropertyBytes 单次 ChangeProperty 能写入的最大字节数 (不支持 INCR 分段传输)。
+func (c *x11Conn) maxPropertyBytes() int {
+	return c.MaxRequest - 24
+}
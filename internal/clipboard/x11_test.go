@@ -0,0 +1,233 @@
+package clipboard
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"key-box/internal/x11"
+	"key-box/internal/x11/x11test"
+)
+
+func newTestX11(t *testing.T, s *x11test.Server) *X11 {
+	t.Helper()
+	x := newX11(s.Dial)
+	t.Cleanup(func() {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		if x.conn != nil {
+			x.conn.Close()
+		}
+	})
+	return x
+}
+
+func TestX11CopyTakesOwnershipWithServerTime(t *testing.T) {
+	s := x11test.NewServer()
+	x := newTestX11(t, s)
+
+	if err := x.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if owner := s.Owner("CLIPBOARD"); owner == 0 || owner != x.conn.window {
+		t.Errorf("CLIPBOARD owner = %#x, want %#x", owner, x.conn.window)
+	}
+	if n := s.CurrentTimeRequests(); n != 0 {
+		t.Errorf("%d SetSelectionOwner requests used CurrentTime", n)
+	}
+	if held, err := x.Holds("s3cret"); err != nil || !held {
+		t.Errorf("Holds = %v, %v; want true", held, err)
+	}
+}
+
+func TestX11CopyFailsWithoutOwnership(t *testing.T) {
+	s := x11test.NewServer()
+	x := newTestX11(t, s)
+	s.IgnoreSelectionOwner(true)
+
+	if err := x.Copy("s3cret"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("Copy = %v, want ErrNotOwner", err)
+	}
+	if held, _ := x.Holds("s3cret"); held {
+		t.Error("Holds = true after a failed Copy")
+	}
+}
+
+func TestX11Clear(t *testing.T) {
+	s := x11test.NewServer()
+	x := newTestX11(t, s)
+
+	if err := x.Copy("s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if owner := s.Owner("CLIPBOARD"); owner != 0 {
+		t.Errorf("CLIPBOARD owner after Clear = %#x, want none", owner)
+	}
+	if n := s.CurrentTimeRequests(); n != 0 {
+		t.Errorf("%d SetSelectionOwner requests used CurrentTime", n)
+	}
+	if held, _ := x.Holds("s3cret"); held {
+		t.Error("Holds = true after Clear")
+	}
+
+	// 清除后可以再次复制 (本进程自己的 SelectionClear 不影响新的所有权)
+	if err := x.Copy("again"); err != nil {
+		t.Fatal(err)
+	}
+	if held, _ := x.Holds("again"); !held {
+		t.Error("Holds = false after copying again")
+	}
+}
+
+func TestX11LosesOwnershipToAnotherClient(t *testing.T) {
+	s := x11test.NewServer()
+	x := newTestX11(t, s)
+	if err := x.Copy("s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := dialRequestor(t, s)
+	clipboard := s.Atom("CLIPBOARD")
+	req := other.Request(x11.OpSetSelectionOwner, 0, 12)
+	binary.LittleEndian.PutUint32(req[4:], other.window)
+	binary.LittleEndian.PutUint32(req[8:], clipboard)
+	binary.LittleEndian.PutUint32(req[12:], s.Time())
+	if _, err := other.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		held, _ := x.Holds("s3cret")
+		if !held {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Holds still true after another client took the clipboard")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 不再是所有者时 Clear 不能清掉其他程序的内容
+	if err := x.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if owner := s.Owner("CLIPBOARD"); owner != other.window {
+		t.Errorf("CLIPBOARD owner after Clear = %#x, want the other client %#x", owner, other.window)
+	}
+}
+
+func TestX11AnswersPasteRequests(t *testing.T) {
+	s := x11test.NewServer()
+	x := newTestX11(t, s)
+	if err := x.Copy("s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	r := dialRequestor(t, s)
+
+	targets := r.convert(t, "TARGETS")
+	var atoms []uint32
+	for i := 0; i+4 <= len(targets); i += 4 {
+		atoms = append(atoms, binary.LittleEndian.Uint32(targets[i:]))
+	}
+	for _, name := range []string{"UTF8_STRING", "text/plain;charset=utf-8", KDEPasswordHint, SensitiveHint} {
+		if !slices.Contains(atoms, s.Atom(name)) {
+			t.Errorf("TARGETS does not include %s", name)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"UTF8_STRING", "s3cret"},
+		{"text/plain;charset=utf-8", "s3cret"},
+		{"STRING", "s3cret"},
+		{KDEPasswordHint, SensitiveHintData},
+		{SensitiveHint, SensitiveHintData},
+	}
+	for _, tt := range tests {
+		if got := r.convert(t, tt.target); string(got) != tt.want {
+			t.Errorf("convert %s = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+
+	if err := x.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.convert(t, "UTF8_STRING"); got != nil {
+		t.Errorf("convert after Clear = %q, want refusal", got)
+	}
+}
+
+// requestor 模拟粘贴的程序。
+type requestor struct {
+	*x11.Conn
+	window uint32
+	server *x11test.Server
+}
+
+func dialRequestor(t *testing.T, s *x11test.Server) *requestor {
+	t.Helper()
+	c, err := s.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	r := &requestor{Conn: c, window: c.NewID(), server: s}
+	req := c.Request(x11.OpCreateWindow, 0, 28)
+	binary.LittleEndian.PutUint32(req[4:], r.window)
+	binary.LittleEndian.PutUint32(req[8:], c.Root)
+	binary.LittleEndian.PutUint16(req[16:], 1)
+	binary.LittleEndian.PutUint16(req[18:], 1)
+	binary.LittleEndian.PutUint16(req[22:], x11WindowClassInputOnly)
+	if _, err := c.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// convert 请求 CLIPBOARD 转换为 target，返回写入的内容；所有者拒绝时返回 nil。
+func (r *requestor) convert(t *testing.T, target string) []byte {
+	t.Helper()
+	property := r.server.Atom("KEY_BOX_PASTE")
+	req := r.Request(x11.OpConvertSelection, 0, 20)
+	binary.LittleEndian.PutUint32(req[4:], r.window)
+	binary.LittleEndian.PutUint32(req[8:], r.server.Atom("CLIPBOARD"))
+	binary.LittleEndian.PutUint32(req[12:], r.server.Atom(target))
+	binary.LittleEndian.PutUint32(req[16:], property)
+	binary.LittleEndian.PutUint32(req[20:], r.server.Time())
+	if _, err := r.Write(req); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		buf, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf[0]&0x7f != x11.EventSelectionNotify {
+			continue
+		}
+		if binary.LittleEndian.Uint32(buf[20:]) == 0 {
+			return nil
+		}
+		_, data, err := r.GetProperty(r.window, property, 0, 1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+}
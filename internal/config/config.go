@@ -1,13 +1,17 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 )
 
 const (
-	configFileName = ".key-box.config"
-	saltKey        = "SEC_APP_SALT"
+	configFileName   = ".key-box.config"
+	settingsFileName = ".key-box.settings.json"
+	saltKey          = "SEC_APP_SALT"
+
+	rootKeyProviderKey = "KEY_BOX_ROOT_KEY_PROVIDER"
 )
 
 // GetSalt 获取配置文件中的 Salt
@@ -45,6 +49,103 @@ func SaveSalt(salt string) error {
 	return saveSalt(configPath, salt)
 }
 
+// GetRootKeyProvider 获取 Root Key 提供者名称
+// 从环境变量 KEY_BOX_ROOT_KEY_PROVIDER 读取，未设置时返回空字符串 (使用默认的配置文件 Salt)
+func GetRootKeyProvider() string {
+	return os.Getenv(rootKeyProviderKey)
+}
+
+// Settings 图形界面的偏好设置，保存在 ~/.key-box.settings.json。
+type Settings struct {
+	// AutoLockMinutes 无操作多少分钟后自动锁定，0 表示不自动锁定。
+	AutoLockMinutes int `json:"auto_lock_minutes"`
+	// LockOnBackground 主窗口最小化 (或隐藏到托盘) 时锁定，只切换到其他窗口不锁定。
+	LockOnBackground bool `json:"lock_on_background"`
+	// LockOnSystemLock 系统休眠或锁屏时锁定。
+	LockOnSystemLock bool `json:"lock_on_system_lock"`
+	// ClipboardClearSeconds 复制密码后多少秒清除剪贴板，0 表示不自动清除。
+	ClipboardClearSeconds int `json:"clipboard_clear_seconds"`
+	// HistoryLimit 每个条目保留的历史版本数，0 表示不保留。
+	HistoryLimit int `json:"history_limit"`
+	// TrashRetentionDays 回收站中的条目保留多少天后自动永久删除，0 表示不自动删除。
+	TrashRetentionDays int `json:"trash_retention_days"`
+	// BreachDataPath 本地 HIBP 泄露密码数据 (原始文本、范围文件目录或索引)，为空表示未配置。
+	BreachDataPath string `json:"breach_data_path,omitempty"`
+	// LauncherHotkey 打开快速访问窗口的全局快捷键 (如 "Ctrl+Alt+K")，为空表示不使用。
+	LauncherHotkey string `json:"launcher_hotkey"`
+	// CloseToTray 关闭主窗口时隐藏到系统托盘而不是退出 (没有系统托盘时仍退出)。
+	CloseToTray bool `json:"close_to_tray"`
+}
+
+// DefaultSettings 返回默认设置 (5 分钟无操作锁定，最小化、休眠和锁屏时锁定，30 秒清除剪贴板，保留 10 个历史版本，回收站保留 30 天，
+// Ctrl+Alt+K 打开快速访问窗口)。
+func DefaultSettings() Settings {
+	return Settings{
+		AutoLockMinutes:       5,
+		LockOnBackground:      true,
+		LockOnSystemLock:      true,
+		ClipboardClearSeconds: 30,
+		HistoryLimit:          10,
+		TrashRetentionDays:    30,
+		LauncherHotkey:        "Ctrl+Alt+K",
+	}
+}
+
+// LoadSettings 读取偏好设置，文件不存在时返回默认设置。
+// 文件中缺少的字段保持默认值。
+func LoadSettings() (Settings, error) {
+	s := DefaultSettings()
+	path, err := getSettingsPath()
+	if err != nil {
+		return s, err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return DefaultSettings(), err
+	}
+	if s.AutoLockMinutes < 0 {
+		s.AutoLockMinutes = 0
+	}
+	if s.ClipboardClearSeconds < 0 {
+		s.ClipboardClearSeconds = 0
+	}
+	if s.HistoryLimit < 0 {
+		s.HistoryLimit = 0
+	}
+	if s.TrashRetentionDays < 0 {
+		s.TrashRetentionDays = 0
+	}
+	return s, nil
+}
+
+// SaveSettings 保存偏好设置。
+func SaveSettings(s Settings) error {
+	path, err := getSettingsPath()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// getSettingsPath 获取偏好设置文件路径
+func getSettingsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, settingsFileName), nil
+}
+
 // getConfigPath 获取配置文件路径
 func getConfigPath() (string, error) {
 	home, err := os.UserHomeDir()
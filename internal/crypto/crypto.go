@@ -12,18 +12,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/corvus-ch/shamir"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/hkdf"
-
-	"key-box/internal/config"
 )
 
 // FixedKeyQ is the hardcoded key q.
+// 仅被 SaltRootKeyProvider 使用，用于兼容旧版本注册的数据。
 var FixedKeyQ = []byte("this-is-fixed-key-q-for-key-box-project-1234567890") // 32+ bytes
 
+// Argon2id parameters used by DerivePassphraseKey.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+)
+
 // GenerateRandomBytes generates n random bytes.
 func GenerateRandomBytes(n int) ([]byte, error) {
 	b := make([]byte, n)
@@ -34,6 +42,36 @@ func GenerateRandomBytes(n int) ([]byte, error) {
 	return b, nil
 }
 
+// recoveryCodeAlphabet 去掉了易混淆的 I/O/0/1，共 32 个字符 (每位 5 bit)。
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateRecoveryCode 生成形如 "ABCDE-FGHJK" 的一次性恢复码 (50 bit 熵)。
+func GenerateRecoveryCode() (string, error) {
+	b, err := GenerateRandomBytes(10)
+	if err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for i, v := range b {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, recoveryCodeAlphabet[v&31])
+	}
+	return string(code), nil
+}
+
+// NormalizeRecoveryCode 去掉分隔符和空格并转为大写，方便用户随意输入。
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
 // NormalizeAnswer trims spaces and converts to lower case.
 func NormalizeAnswer(ans string) string {
 	return strings.ToLower(strings.TrimSpace(ans))
@@ -152,40 +190,25 @@ func DeriveKeyB(masterKeyM []byte, username string) ([]byte, error) {
 	return keyB, nil
 }
 
-// GetRootKey 计算 RootKey = Hash(Salt) XOR FixedKeyQ。
-// 安全决策:
-// 1. RootKey 用于加密保护存储在数据库中的 Key B。
-// 2. 它不直接存储在磁盘上，而是运行时计算。
-// 3. 依赖 "双因素" 因子:
-//   - 因子1 (p): Salt 值 (支持从配置文件 ~/.key-box.config 或环境变量 SEC_APP_SALT 读取)
-//   - 因子2 (q): 硬编码常量 (编译在二进制中)
-//   - 即使数据库泄露，没有 Salt 也无法解密 Key B。
-//
-// 4. 读取顺序: 优先从配置文件读取，如果不存在或为空则从环境变量读取
-func GetRootKey() ([]byte, error) {
-	saltVal, err := config.GetSalt()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get salt: %w", err)
-	}
-	if saltVal == "" {
-		return nil, errors.New("salt is not set (check ~/.key-box.config file or SEC_APP_SALT environment variable)")
+// DeriveSubKey 使用 HKDF-SHA256 从源密钥派生一个 32 字节的用途子密钥。
+// info 用于区分不同用途 (例如 "unlock-key"、"data-key-recovery")，
+// 不同 info 派生出的子密钥彼此独立，泄露其一不会影响其他用途。
+func DeriveSubKey(secret []byte, info string) ([]byte, error) {
+	hkdfStream := hkdf.New(sha256.New, secret, nil, []byte(info))
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdfStream, key); err != nil {
+		return nil, err
 	}
+	return key, nil
+}
 
-	// 计算 p = SHA256(Salt)
-	h := sha256.Sum256([]byte(saltVal))
-	p := h[:]
-
-	// 计算 q = SHA256(FixedKeyQ)
-	// 确保 p 和 q 长度一致 (32字节)，便于异或操作
-	hq := sha256.Sum256(FixedKeyQ)
-	q := hq[:]
-
-	// 计算 RootKey = p XOR q
-	rootKey := make([]byte, 32)
-	for i := 0; i < 32; i++ {
-		rootKey[i] = p[i] ^ q[i]
-	}
-	return rootKey, nil
+// DerivePassphraseKey 使用 Argon2id 将口令拉伸为 32 字节密钥。
+// 安全决策:
+// 1. Argon2id 同时抵抗 GPU 暴力破解 (内存困难) 和侧信道攻击，是 RFC 9106 推荐的口令哈希算法。
+// 2. 参数取 time=3, memory=64MiB, threads=4，在桌面设备上耗时约数百毫秒。
+// 3. Salt 由调用方提供，必须随机且与口令一一对应。
+func DerivePassphraseKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, 32)
 }
 
 // GenerateTOTP generates a 6-digit TOTP code based on the secret and time.
@@ -234,3 +257,20 @@ func VerifyOTP(secretKeyB []byte, inputCode string) bool {
 func EncodeKeyB(keyB []byte) string {
 	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(keyB)
 }
+
+// OTPIssuer 写入 otpauth URI 的发行方名称，TOTP 应用据此显示账户来源。
+const OTPIssuer = "key-box"
+
+// OTPAuthURI 生成 Key B 的 otpauth:// URI，可转为二维码供 TOTP 应用扫描。
+// 格式参见 Google Authenticator Key Uri Format，参数与 GenerateTOTP 一致 (SHA1, 6 位, 30 秒)。
+func OTPAuthURI(keyB []byte, username string) string {
+	params := url.Values{}
+	params.Set("secret", EncodeKeyB(keyB))
+	params.Set("issuer", OTPIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", "6")
+	params.Set("period", "30")
+
+	label := url.PathEscape(OTPIssuer + ":" + username)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
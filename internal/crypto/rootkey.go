@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"key-box/internal/config"
+)
+
+// Root Key 提供者名称，对应环境变量 KEY_BOX_ROOT_KEY_PROVIDER 的取值。
+const (
+	RootKeyProviderSalt          = "salt"
+	RootKeyProviderKeyring       = "keyring"
+	RootKeyProviderSecretService = "secret-service"
+	RootKeyProviderPassphrase    = "passphrase"
+)
+
+// ErrRootKeyNotFound 表示提供者中尚未存放 Root Key 秘密。
+var ErrRootKeyNotFound = errors.New("root key secret not found")
+
+// RootKeyProvider 提供用于加密 Key B 的 Root Key。
+// 安全决策:
+//  1. Root Key 的来源与认证逻辑解耦，auth.Service 通过注入获得提供者。
+//  2. 所有实现都返回 32 字节密钥 (AES-256)，且不在磁盘上以明文形式保存最终密钥。
+//  3. 切换提供者会改变 Root Key，已注册用户的 Key B 将无法解密，需通过密保问题重置 (ResetPassword) 重新保护。
+type RootKeyProvider interface {
+	// Name 返回提供者名称，用于错误提示。
+	Name() string
+	// RootKey 返回 32 字节 Root Key。
+	RootKey() ([]byte, error)
+}
+
+// NewRootKeyProvider 根据名称创建 Root Key 提供者。
+// name 为空时使用配置文件 Salt (兼容旧版本)。
+// passphrase 仅在 name 为 "passphrase" 时使用，由 CLI/GUI 负责向用户索取口令。
+func NewRootKeyProvider(name string, passphrase func() (string, error)) (RootKeyProvider, error) {
+	switch name {
+	case "", RootKeyProviderSalt:
+		return SaltRootKeyProvider{}, nil
+	case RootKeyProviderKeyring:
+		return NewKeyringRootKeyProvider(), nil
+	case RootKeyProviderSecretService:
+		return NewSecretServiceRootKeyProvider(""), nil
+	case RootKeyProviderPassphrase:
+		if passphrase == nil {
+			return nil, errors.New("passphrase root key provider requires a passphrase prompt")
+		}
+		return &PassphraseRootKeyProvider{Passphrase: passphrase}, nil
+	default:
+		return nil, fmt.Errorf("unknown root key provider %q", name)
+	}
+}
+
+// SaltRootKeyProvider 计算 RootKey = Hash(Salt) XOR Hash(FixedKeyQ)。
+// 安全决策:
+// 1. 这是旧版本的默认行为，保留以兼容已有数据。
+// 2. 依赖 "双因素" 因子:
+//   - 因子1 (p): Salt 值 (支持从配置文件 ~/.key-box.config 或环境变量 SEC_APP_SALT 读取)
+//   - 因子2 (q): 硬编码常量 (编译在二进制中，所有安装都相同)
+//
+// 3. 注意: Salt 文件与数据库同在 $HOME 下，同时拷走两者即可解密 Key B。
+// 对安全性要求更高的场景应使用 keyring、secret-service 或 passphrase 提供者。
+type SaltRootKeyProvider struct{}
+
+func (SaltRootKeyProvider) Name() string {
+	return RootKeyProviderSalt
+}
+
+func (SaltRootKeyProvider) RootKey() ([]byte, error) {
+	saltVal, err := config.GetSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get salt: %w", err)
+	}
+	if saltVal == "" {
+		return nil, errors.New("salt is not set (check ~/.key-box.config file or SEC_APP_SALT environment variable)")
+	}
+
+	// 计算 p = SHA256(Salt)
+	h := sha256.Sum256([]byte(saltVal))
+	p := h[:]
+
+	// 计算 q = SHA256(FixedKeyQ)
+	// 确保 p 和 q 长度一致 (32字节)，便于异或操作
+	hq := sha256.Sum256(FixedKeyQ)
+	q := hq[:]
+
+	// 计算 RootKey = p XOR q
+	rootKey := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		rootKey[i] = p[i] ^ q[i]
+	}
+	return rootKey, nil
+}
+
+// PassphraseRootKeyProvider 从用户口令派生 Root Key。
+// 安全决策:
+// 1. RootKey = Argon2id(Passphrase, SHA256(Salt))，口令不落盘。
+// 2. Salt 复用配置文件中的随机 Salt，保证不同安装的派生结果不同。
+// 3. 口令错误时 Root Key 不同，解密 Key B 会因 GCM 认证失败而报错。
+type PassphraseRootKeyProvider struct {
+	// Passphrase 返回用户输入的口令。
+	Passphrase func() (string, error)
+}
+
+func (p *PassphraseRootKeyProvider) Name() string {
+	return RootKeyProviderPassphrase
+}
+
+func (p *PassphraseRootKeyProvider) RootKey() ([]byte, error) {
+	passphrase, err := p.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("root key passphrase is empty")
+	}
+
+	saltVal, err := config.GetSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get salt: %w", err)
+	}
+	if saltVal == "" {
+		return nil, errors.New("salt is not set (check ~/.key-box.config file or SEC_APP_SALT environment variable)")
+	}
+	salt := sha256.Sum256([]byte(saltVal))
+
+	return DerivePassphraseKey(passphrase, salt[:]), nil
+}
+
+// rootKeyFromSecret 将外部存放的秘密规整为 32 字节 Root Key。
+// 秘密可以是任意长度的字节串 (例如 keyctl padd 写入的随机文本)。
+func rootKeyFromSecret(secret []byte) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, ErrRootKeyNotFound
+	}
+	h := sha256.Sum256(secret)
+	return h[:], nil
+}
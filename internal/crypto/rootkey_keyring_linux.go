@@ -0,0 +1,64 @@
+//go:build linux
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// KeyringRootKeyDescription 是 Root Key 秘密在内核密钥环中的描述名。
+const KeyringRootKeyDescription = "key-box:root-key"
+
+// KeyringRootKeyProvider 从 Linux 内核密钥环 (keyctl) 读取 Root Key 秘密。
+// 安全决策:
+//  1. 秘密以 "user" 类型密钥存放在会话或用户密钥环中，只存在于内核内存，不落盘。
+//  2. RootKey = SHA256(秘密)，秘密可以是任意长度的随机字节。
+//  3. 内核密钥环在重启后清空，因此不会自动生成秘密 (否则重启后会得到不同的 Root Key)。
+//     用户需在登录会话中自行加载，例如:
+//     keyctl padd user key-box:root-key @u < /path/to/secret
+type KeyringRootKeyProvider struct {
+	// Description 为密钥描述名，默认 KeyringRootKeyDescription。
+	Description string
+}
+
+// NewKeyringRootKeyProvider 创建使用默认描述名的内核密钥环提供者。
+func NewKeyringRootKeyProvider() *KeyringRootKeyProvider {
+	return &KeyringRootKeyProvider{Description: KeyringRootKeyDescription}
+}
+
+func (k *KeyringRootKeyProvider) Name() string {
+	return RootKeyProviderKeyring
+}
+
+func (k *KeyringRootKeyProvider) RootKey() ([]byte, error) {
+	// 先查会话密钥环 (会级联搜索其链接的用户密钥环)，再显式查用户密钥环
+	var id int
+	var err error
+	for _, ring := range []int{unix.KEY_SPEC_SESSION_KEYRING, unix.KEY_SPEC_USER_KEYRING} {
+		id, err = unix.KeyctlSearch(ring, "user", k.Description, 0)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if errors.Is(err, unix.ENOKEY) {
+			return nil, fmt.Errorf("%w: load it with `keyctl padd user %s @u < secret-file`", ErrRootKeyNotFound, k.Description)
+		}
+		return nil, fmt.Errorf("keyctl search failed: %w", err)
+	}
+
+	// 第一次调用获取长度，第二次读取内容
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("keyctl read failed: %w", err)
+	}
+	secret := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, secret, 0)
+	if err != nil {
+		return nil, fmt.Errorf("keyctl read failed: %w", err)
+	}
+	return rootKeyFromSecret(secret[:n])
+}
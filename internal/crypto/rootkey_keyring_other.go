@@ -0,0 +1,26 @@
+//go:build !linux
+
+package crypto
+
+import "errors"
+
+// KeyringRootKeyDescription 是 Root Key 秘密在内核密钥环中的描述名。
+const KeyringRootKeyDescription = "key-box:root-key"
+
+// KeyringRootKeyProvider 仅在 Linux 上可用，其他平台返回错误。
+type KeyringRootKeyProvider struct {
+	Description string
+}
+
+// NewKeyringRootKeyProvider 创建使用默认描述名的内核密钥环提供者。
+func NewKeyringRootKeyProvider() *KeyringRootKeyProvider {
+	return &KeyringRootKeyProvider{Description: KeyringRootKeyDescription}
+}
+
+func (k *KeyringRootKeyProvider) Name() string {
+	return RootKeyProviderKeyring
+}
+
+func (k *KeyringRootKeyProvider) RootKey() ([]byte, error) {
+	return nil, errors.New("kernel keyring root key provider is only supported on Linux")
+}
@@ -0,0 +1,217 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceIface      = "org.freedesktop.Secret.Service"
+	secretCollectionIface   = "org.freedesktop.Secret.Collection"
+	secretItemIface         = "org.freedesktop.Secret.Item"
+	secretPromptIface       = "org.freedesktop.Secret.Prompt"
+	secretSessionIface      = "org.freedesktop.Secret.Session"
+	secretPromptTimeout     = 2 * time.Minute
+	secretServiceRootKeyLen = 32
+)
+
+// secretServiceSecret 对应 Secret Service API 中的 Secret 结构 (oayays)。
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceRootKeyProvider 从 freedesktop Secret Service (D-Bus) 读取 Root Key 秘密。
+// 兼容 GNOME Keyring、KWallet (ksecretd) 以及任何实现该协议的本地测试守护进程。
+// 安全决策:
+//  1. 秘密存放在系统密钥环中，由登录会话解锁，不与数据库放在同一目录。
+//  2. 首次使用时自动生成 32 字节随机秘密并写入默认集合 (密钥环会持久化保存)。
+//  3. 使用 "plain" 会话传输，秘密只经过本机 D-Bus，不经过网络。
+//  4. RootKey = SHA256(秘密)。
+type SecretServiceRootKeyProvider struct {
+	// Address 为 D-Bus 地址，为空时连接当前会话总线 (DBUS_SESSION_BUS_ADDRESS)。
+	// 测试时可指向本地启动的 dbus-daemon，例如 "unix:path=/tmp/test-bus"。
+	Address string
+	// Attributes 用于查找秘密条目的属性。
+	Attributes map[string]string
+	// Label 为新建条目时显示在密钥环管理器中的名称。
+	Label string
+}
+
+// NewSecretServiceRootKeyProvider 创建 Secret Service 提供者。
+func NewSecretServiceRootKeyProvider(address string) *SecretServiceRootKeyProvider {
+	return &SecretServiceRootKeyProvider{
+		Address: address,
+		Attributes: map[string]string{
+			"application": "key-box",
+			"purpose":     "root-key",
+		},
+		Label: "Key-Box Root Key",
+	}
+}
+
+func (s *SecretServiceRootKeyProvider) Name() string {
+	return RootKeyProviderSecretService
+}
+
+func (s *SecretServiceRootKeyProvider) RootKey() ([]byte, error) {
+	conn, err := s.connect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to secret service bus: %w", err)
+	}
+	defer conn.Close()
+
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	// 1. 打开明文传输会话
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return nil, fmt.Errorf("failed to open secret service session: %w", err)
+	}
+	defer conn.Object(secretServiceName, session).Call(secretSessionIface+".Close", 0)
+
+	// 2. 查找已存在的条目
+	var unlocked, locked []dbus.ObjectPath
+	if err := service.Call(secretServiceIface+".SearchItems", 0, s.Attributes).Store(&unlocked, &locked); err != nil {
+		return nil, fmt.Errorf("failed to search secret service: %w", err)
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		if unlocked, err = s.unlock(conn, service, locked); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 读取秘密，或在首次使用时生成
+	if len(unlocked) > 0 {
+		var secret secretServiceSecret
+		item := conn.Object(secretServiceName, unlocked[0])
+		if err := item.Call(secretItemIface+".GetSecret", 0, session).Store(&secret); err != nil {
+			return nil, fmt.Errorf("failed to read root key secret: %w", err)
+		}
+		return rootKeyFromSecret(secret.Value)
+	}
+
+	value, err := GenerateRandomBytes(secretServiceRootKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.create(conn, service, session, value); err != nil {
+		return nil, err
+	}
+	return rootKeyFromSecret(value)
+}
+
+// connect 连接指定地址或当前会话总线。
+func (s *SecretServiceRootKeyProvider) connect() (*dbus.Conn, error) {
+	if s.Address != "" {
+		return dbus.Connect(s.Address)
+	}
+	return dbus.ConnectSessionBus()
+}
+
+// unlock 解锁被锁定的条目，必要时弹出密钥环的解锁提示。
+func (s *SecretServiceRootKeyProvider) unlock(conn *dbus.Conn, service dbus.BusObject, objects []dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := service.Call(secretServiceIface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return nil, fmt.Errorf("failed to unlock secret service item: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked, nil
+	}
+
+	result, err := s.prompt(conn, prompt)
+	if err != nil {
+		return nil, err
+	}
+	paths, ok := result.Value().([]dbus.ObjectPath)
+	if !ok || len(paths) == 0 {
+		return nil, errors.New("secret service item is still locked")
+	}
+	return paths, nil
+}
+
+// create 在默认集合中创建 Root Key 条目。
+func (s *SecretServiceRootKeyProvider) create(conn *dbus.Conn, service dbus.BusObject, session dbus.ObjectPath, value []byte) error {
+	var collectionPath dbus.ObjectPath
+	if err := service.Call(secretServiceIface+".ReadAlias", 0, "default").Store(&collectionPath); err != nil {
+		return fmt.Errorf("failed to find default keyring: %w", err)
+	}
+	if collectionPath == "/" {
+		return errors.New("secret service has no default keyring")
+	}
+
+	props := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant(s.Label),
+		secretItemIface + ".Attributes": dbus.MakeVariant(s.Attributes),
+	}
+	secret := secretServiceSecret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       value,
+		ContentType: "application/octet-stream",
+	}
+
+	var item, prompt dbus.ObjectPath
+	collection := conn.Object(secretServiceName, collectionPath)
+	if err := collection.Call(secretCollectionIface+".CreateItem", 0, props, secret, false).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store root key secret: %w", err)
+	}
+	if item == "/" {
+		// 集合被锁定，需要用户在提示框中解锁
+		if _, err := s.prompt(conn, prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prompt 执行 Secret Service 提示 (例如输入密钥环密码)，并等待 Completed 信号。
+func (s *SecretServiceRootKeyProvider) prompt(conn *dbus.Conn, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	if prompt == "" || prompt == "/" {
+		return dbus.Variant{}, errors.New("secret service returned no prompt")
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(secretServiceName, prompt).Call(secretPromptIface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to show secret service prompt: %w", err)
+	}
+
+	timeout := time.After(secretPromptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != prompt || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, errors.New("secret service prompt was dismissed")
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+		case <-timeout:
+			return dbus.Variant{}, errors.New("timed out waiting for secret service prompt")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+// StreamChunkSize 分块加密时每块明文的大小
+const StreamChunkSize = 64 * 1024
+
+// ErrStreamFinished 结束块之后不能再加密或解密更多的块
+var ErrStreamFinished = errors.New("stream already finished")
+
+// StreamCipher 分块 AES-GCM 加密 (STREAM 结构)，用于不能整体放入内存的大文件。
+// 安全决策:
+//  1. 每个文件使用独立的随机密钥，因此 Nonce 可以由块序号确定而不会重复。
+//  2. Nonce = 8 字节块序号 + 1 字节结束标记 + 3 字节 0: 块被调换顺序、删除或复制到其他位置都会认证失败。
+//  3. 最后一块带结束标记，截断 (删掉末尾若干块) 后新的最后一块无法以结束标记通过认证。
+type StreamCipher struct {
+	aead cipher.AEAD
+	seq  uint64
+	done bool
+}
+
+// NewStreamCipher 使用 32 字节文件密钥创建分块加解密器，加密和解密各用一个新实例。
+func NewStreamCipher(key []byte) (*StreamCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamCipher{aead: aead}, nil
+}
+
+func (s *StreamCipher) nonce(last bool) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, s.seq)
+	if last {
+		nonce[8] = 1
+	}
+	return nonce
+}
+
+// Seal 加密下一块，last 表示这是最后一块 (空文件也需要加密一个空的最后一块)。
+func (s *StreamCipher) Seal(chunk []byte, last bool) ([]byte, error) {
+	if s.done {
+		return nil, ErrStreamFinished
+	}
+	out := s.aead.Seal(nil, s.nonce(last), chunk, nil)
+	s.seq++
+	s.done = last
+	return out, nil
+}
+
+// Open 按顺序解密下一块，last 表示存储中已没有后续的块。
+func (s *StreamCipher) Open(chunk []byte, last bool) ([]byte, error) {
+	if s.done {
+		return nil, ErrStreamFinished
+	}
+	out, err := s.aead.Open(nil, s.nonce(last), chunk, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.seq++
+	s.done = last
+	return out, nil
+}
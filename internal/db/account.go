@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// userTables 列出所有按 username 关联用户的表，删除账户时逐一清理。
+// users 表必须放在最后，其他表通过外键引用它。
+var userTables = []string{"vault", "item_history", "attachment_chunks", "attachments", "recovery_codes", "audit_log", "audit_pending", "users"}
+
+// UserDataCounts 用户各类数据的数量，注销账户前确认和注销后报告时使用。
+type UserDataCounts struct {
+	Items       int // 密码条目 (不含回收站)
+	Trashed     int // 回收站中的条目
+	History     int // 条目历史版本
+	Attachments int // 附件
+}
+
+// CountUserData 统计 DeleteUserRows 将删除的数据。
+func CountUserData(q Queryer, username string) (UserDataCounts, error) {
+	var c UserDataCounts
+	stmt := `SELECT
+		(SELECT COUNT(*) FROM vault WHERE username = ?1 AND deleted_at IS NULL),
+		(SELECT COUNT(*) FROM vault WHERE username = ?1 AND deleted_at IS NOT NULL),
+		(SELECT COUNT(*) FROM item_history WHERE username = ?1),
+		(SELECT COUNT(*) FROM attachments WHERE username = ?1)`
+	err := q.QueryRow(stmt, username).Scan(&c.Items, &c.Trashed, &c.History, &c.Attachments)
+	return c, err
+}
+
+// DeleteUserRows 删除用户在所有表中的记录，返回删除前的数据统计，需在事务中调用。
+func DeleteUserRows(tx *sql.Tx, username string) (UserDataCounts, error) {
+	counts, err := CountUserData(tx, username)
+	if err != nil {
+		return UserDataCounts{}, err
+	}
+	for _, table := range userTables {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE username = ?`, username); err != nil {
+			return UserDataCounts{}, err
+		}
+	}
+	return counts, nil
+}
+
+// IncrementalVacuum 释放空闲页，使被删除的数据不再残留在数据库文件中。
+// 配合 secure_delete，删除的内容先被清零，再从文件中截断。
+func (db *DB) IncrementalVacuum() error {
+	_, err := db.Exec(`PRAGMA incremental_vacuum`)
+	return err
+}
+
+// enableIncrementalVacuum 将数据库切换为增量 auto_vacuum 模式。
+// 旧版本创建的数据库 auto_vacuum 为 NONE，修改模式后必须执行一次 VACUUM 才会生效。
+// PRAGMA 与 VACUUM 需在同一连接上执行，因此固定一个连接。
+func (db *DB) enableIncrementalVacuum() error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, `PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return err
+	}
+	const incremental = 2
+	if mode == incremental {
+		return nil
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA auto_vacuum = INCREMENTAL`); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// RenameUserRows 将用户在所有表中的 username 改为 newName，需在事务中调用。
+func RenameUserRows(tx *sql.Tx, oldName, newName string) error {
+	for _, table := range userTables {
+		if _, err := tx.Exec(`UPDATE `+table+` SET username = ? WHERE username = ?`, newName, oldName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Attachment 附件记录 (不含内容)。
+type Attachment struct {
+	ID        int
+	ItemID    int
+	EncKey    []byte
+	EncMeta   []byte
+	CreatedAt time.Time
+}
+
+// InsertAttachment 新建附件记录，返回附件 ID。内容和 enc_meta 随后写入，需在事务中调用。
+func InsertAttachment(ex Execer, itemID int, username string, encKey []byte) (int, error) {
+	res, err := ex.Exec(`INSERT INTO attachments (item_id, username, enc_key) VALUES (?, ?, ?)`, itemID, username, encKey)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// InsertAttachmentChunk 写入附件的一个加密块，需在事务中调用。
+func InsertAttachmentChunk(ex Execer, attachmentID, seq int, username string, data []byte) error {
+	_, err := ex.Exec(`INSERT INTO attachment_chunks (attachment_id, seq, username, data) VALUES (?, ?, ?, ?)`,
+		attachmentID, seq, username, data)
+	return err
+}
+
+// SetAttachmentMeta 写入附件的加密元数据，需在事务中调用。
+func SetAttachmentMeta(ex Execer, id int, encMeta []byte) error {
+	_, err := ex.Exec(`UPDATE attachments SET enc_meta = ? WHERE id = ?`, encMeta, id)
+	return err
+}
+
+// GetAttachments 获取条目的附件 (先添加的在前)。
+func (db *DB) GetAttachments(itemID int) ([]Attachment, error) {
+	rows, err := db.Query(`SELECT id, item_id, enc_key, enc_meta, created_at FROM attachments WHERE item_id = ? ORDER BY id`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Attachment
+	for rows.Next() {
+		var a Attachment
+		var createdAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.ItemID, &a.EncKey, &a.EncMeta, &createdAt); err != nil {
+			return nil, err
+		}
+		a.CreatedAt = createdAt.Time
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// GetAttachment 获取单个附件记录。
+func (db *DB) GetAttachment(id int) (*Attachment, error) {
+	var a Attachment
+	var createdAt sql.NullTime
+	err := db.QueryRow(`SELECT id, item_id, enc_key, enc_meta, created_at FROM attachments WHERE id = ?`, id).
+		Scan(&a.ID, &a.ItemID, &a.EncKey, &a.EncMeta, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("attachment %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt = createdAt.Time
+	return &a, nil
+}
+
+// ReadAttachmentChunks 按序号逐块读取附件内容，每次只在内存中保留两块。
+// fn 的 last 表示这是存储中的最后一块。序号不连续时返回错误。
+func (db *DB) ReadAttachmentChunks(id int, fn func(data []byte, last bool) error) error {
+	rows, err := db.Query(`SELECT seq, data FROM attachment_chunks WHERE attachment_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var prev []byte
+	next := 0
+	for rows.Next() {
+		var seq int
+		var data []byte
+		if err := rows.Scan(&seq, &data); err != nil {
+			return err
+		}
+		if seq != next {
+			return fmt.Errorf("attachment %d: missing chunk %d", id, next)
+		}
+		next++
+		if prev != nil {
+			if err := fn(prev, false); err != nil {
+				return err
+			}
+		}
+		prev = data
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if prev == nil {
+		return fmt.Errorf("attachment %d has no content", id)
+	}
+	return fn(prev, true)
+}
+
+// DeleteAttachment 删除附件及其内容。
+func (db *DB) DeleteAttachment(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM attachment_chunks WHERE attachment_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM attachments WHERE id = ?`, id)
+		return err
+	})
+}
+
+// DeleteItemAttachments 删除 vault 中满足条件 vaultCond 的条目的所有附件，需在事务中调用。
+func DeleteItemAttachments(ex Execer, vaultCond string, args ...interface{}) error {
+	items := `SELECT id FROM vault WHERE ` + vaultCond
+	stmt := `DELETE FROM attachment_chunks WHERE attachment_id IN (SELECT id FROM attachments WHERE item_id IN (` + items + `))`
+	if _, err := ex.Exec(stmt, args...); err != nil {
+		return err
+	}
+	_, err := ex.Exec(`DELETE FROM attachments WHERE item_id IN (`+items+`)`, args...)
+	return err
+}
@@ -0,0 +1,103 @@
+package db
+
+import "database/sql"
+
+// AuditRow 审计日志记录，事件内容已加密。
+type AuditRow struct {
+	Seq      int64
+	EncEvent []byte
+	MAC      []byte
+}
+
+// AuditPending 登录前暂存的审计事件。
+type AuditPending struct {
+	ID    int
+	Event []byte
+}
+
+// InsertAuditRow 追加一条审计日志，需在事务中调用。
+func InsertAuditRow(ex Execer, username string, r AuditRow) error {
+	stmt := `INSERT INTO audit_log (username, seq, enc_event, mac) VALUES (?, ?, ?, ?)`
+	_, err := ex.Exec(stmt, username, r.Seq, r.EncEvent, r.MAC)
+	return err
+}
+
+// GetAuditRows 按序号获取用户的全部审计日志。
+func GetAuditRows(q Queryer, username string) ([]AuditRow, error) {
+	stmt := `SELECT seq, enc_event, mac FROM audit_log WHERE username = ? ORDER BY seq, id`
+	rows, err := q.Query(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AuditRow
+	for rows.Next() {
+		var r AuditRow
+		if err := rows.Scan(&r.Seq, &r.EncEvent, &r.MAC); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// GetLastAuditRow 获取用户最后一条审计日志，没有记录时返回 nil。
+func GetLastAuditRow(q Queryer, username string) (*AuditRow, error) {
+	stmt := `SELECT seq, enc_event, mac FROM audit_log WHERE username = ? ORDER BY seq DESC, id DESC LIMIT 1`
+	var r AuditRow
+	err := q.QueryRow(stmt, username).Scan(&r.Seq, &r.EncEvent, &r.MAC)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetAuditHead 获取用户的审计日志链头。
+func GetAuditHead(q Queryer, username string) ([]byte, error) {
+	var head []byte
+	err := q.QueryRow(`SELECT audit_head FROM users WHERE username = ?`, username).Scan(&head)
+	return head, err
+}
+
+// SetAuditHead 更新用户的审计日志链头，需与 InsertAuditRow 在同一事务中调用。
+func SetAuditHead(ex Execer, username string, head []byte) error {
+	_, err := ex.Exec(`UPDATE users SET audit_head = ? WHERE username = ?`, head, username)
+	return err
+}
+
+// InsertAuditPending 暂存一条登录前的审计事件。
+func (db *DB) InsertAuditPending(username string, event []byte) error {
+	stmt := `INSERT INTO audit_pending (username, event) VALUES (?, ?)`
+	_, err := db.Exec(stmt, username, event)
+	return err
+}
+
+// GetAuditPending 获取用户暂存的审计事件。
+func GetAuditPending(q Queryer, username string) ([]AuditPending, error) {
+	stmt := `SELECT id, event FROM audit_pending WHERE username = ? ORDER BY id`
+	rows, err := q.Query(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AuditPending
+	for rows.Next() {
+		var p AuditPending
+		if err := rows.Scan(&p.ID, &p.Event); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// DeleteAuditPending 删除已并入审计日志的暂存事件，需在事务中调用。
+func DeleteAuditPending(ex Execer, id int) error {
+	_, err := ex.Exec(`DELETE FROM audit_pending WHERE id = ?`, id)
+	return err
+}
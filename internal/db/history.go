@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ItemHistory 条目的一个历史版本。
+type ItemHistory struct {
+	ID         int
+	ItemID     int
+	Site       string
+	EncData    []byte
+	SavedAt    time.Time
+	ArchivedAt time.Time
+}
+
+// ArchiveVaultItem 将条目当前的加密内容复制到 item_history，需在事务中调用。
+func ArchiveVaultItem(ex Execer, id int) error {
+	stmt := `INSERT INTO item_history (item_id, username, site, enc_data, saved_at)
+		SELECT id, username, site, enc_data, updated_at FROM vault WHERE id = ?`
+	_, err := ex.Exec(stmt, id)
+	return err
+}
+
+// PruneItemHistory 只保留条目最新的 keep 个历史版本。
+func PruneItemHistory(ex Execer, itemID, keep int) error {
+	stmt := `DELETE FROM item_history WHERE item_id = ? AND id NOT IN (
+		SELECT id FROM item_history WHERE item_id = ? ORDER BY id DESC LIMIT ?)`
+	_, err := ex.Exec(stmt, itemID, itemID, max(keep, 0))
+	return err
+}
+
+// GetItemHistory 获取条目的历史版本 (最新的在前)。
+func (db *DB) GetItemHistory(itemID int) ([]ItemHistory, error) {
+	stmt := `SELECT id, item_id, site, enc_data, saved_at, archived_at FROM item_history WHERE item_id = ? ORDER BY id DESC`
+	rows, err := db.Query(stmt, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []ItemHistory
+	for rows.Next() {
+		var h ItemHistory
+		var savedAt, archivedAt sql.NullTime
+		if err := rows.Scan(&h.ID, &h.ItemID, &h.Site, &h.EncData, &savedAt, &archivedAt); err != nil {
+			return nil, err
+		}
+		h.SavedAt, h.ArchivedAt = savedAt.Time, archivedAt.Time
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// GetItemHistoryEntry 获取单个历史版本。
+func (db *DB) GetItemHistoryEntry(id int) (*ItemHistory, error) {
+	stmt := `SELECT id, item_id, site, enc_data, saved_at, archived_at FROM item_history WHERE id = ?`
+	var h ItemHistory
+	var savedAt, archivedAt sql.NullTime
+	if err := db.QueryRow(stmt, id).Scan(&h.ID, &h.ItemID, &h.Site, &h.EncData, &savedAt, &archivedAt); err != nil {
+		return nil, err
+	}
+	h.SavedAt, h.ArchivedAt = savedAt.Time, archivedAt.Time
+	return &h, nil
+}
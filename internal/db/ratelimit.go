@@ -0,0 +1,23 @@
+package db
+
+// CountLookupAttempts 统计 since (Unix 秒) 之后指定 key 的查询次数。
+func (db *DB) CountLookupAttempts(key []byte, since int64) (int, error) {
+	stmt := `SELECT COUNT(*) FROM lookup_attempts WHERE lookup_key = ? AND attempted_at >= ?`
+	var n int
+	err := db.QueryRow(stmt, key, since).Scan(&n)
+	return n, err
+}
+
+// RecordLookupAttempt 记录一次查询。
+func (db *DB) RecordLookupAttempt(key []byte, at int64) error {
+	stmt := `INSERT INTO lookup_attempts (lookup_key, attempted_at) VALUES (?, ?)`
+	_, err := db.Exec(stmt, key, at)
+	return err
+}
+
+// PruneLookupAttempts 删除 before (Unix 秒) 之前的过期记录。
+func (db *DB) PruneLookupAttempts(before int64) error {
+	stmt := `DELETE FROM lookup_attempts WHERE attempted_at < ?`
+	_, err := db.Exec(stmt, before)
+	return err
+}
@@ -0,0 +1,59 @@
+package db
+
+import "database/sql"
+
+// RecoveryCode 一次性恢复码记录。
+// 恢复码本身不存储，只存储由其派生密钥加密的 Recovery Key W。
+type RecoveryCode struct {
+	ID     int
+	Salt   []byte
+	EncW   []byte
+	UsedAt sql.NullTime
+}
+
+// ReplaceRecoveryCodes 删除用户已有的恢复码并写入新的一组，需在事务中调用。
+func ReplaceRecoveryCodes(ex Execer, username string, codes []RecoveryCode) error {
+	if _, err := ex.Exec(`DELETE FROM recovery_codes WHERE username = ?`, username); err != nil {
+		return err
+	}
+	for _, c := range codes {
+		stmt := `INSERT INTO recovery_codes (username, salt, enc_w, used_at) VALUES (?, ?, ?, ?)`
+		if _, err := ex.Exec(stmt, username, c.Salt, c.EncW, c.UsedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRecoveryCodes 获取用户的所有恢复码记录 (包括已使用的)。
+func (db *DB) GetRecoveryCodes(username string) ([]RecoveryCode, error) {
+	stmt := `SELECT id, salt, enc_w, used_at FROM recovery_codes WHERE username = ? ORDER BY id`
+	rows, err := db.Query(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []RecoveryCode
+	for rows.Next() {
+		var c RecoveryCode
+		if err := rows.Scan(&c.ID, &c.Salt, &c.EncW, &c.UsedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// CountUnusedRecoveryCodes 统计用户剩余可用的恢复码数量。
+func (db *DB) CountUnusedRecoveryCodes(username string) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE username = ? AND used_at IS NULL`, username).Scan(&n)
+	return n, err
+}
+
+// MarkRecoveryCodeUsed 将恢复码标记为已使用，需在事务中调用。
+func MarkRecoveryCodeUsed(ex Execer, id int) error {
+	_, err := ex.Exec(`UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE id = ?`, id)
+	return err
+}
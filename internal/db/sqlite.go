@@ -2,8 +2,10 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -22,7 +24,8 @@ func InitDB() (*DB, error) {
 	}
 	dbPath := filepath.Join(home, ".key-box.db")
 
-	conn, err := sql.Open("sqlite3", dbPath)
+	// secure_delete: 删除的记录在数据库文件中被清零，而不是仅标记为空闲
+	conn, err := sql.Open("sqlite3", dbPath+"?_secure_delete=on")
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +38,12 @@ func InitDB() (*DB, error) {
 	if err := db.createTables(); err != nil {
 		return nil, err
 	}
+	if err := db.migrate(); err != nil {
+		return nil, err
+	}
+	if err := db.enableIncrementalVacuum(); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -42,14 +51,19 @@ func InitDB() (*DB, error) {
 // createTables 创建所需的数据库表结构。
 // users: 存储用户元数据和加密后的密钥链。
 // vault: 存储用户加密后的账号密码数据。
+// recovery_codes: 存储一次性恢复码各自加密的恢复密钥副本。
+// lookup_attempts: 记录密保问题查询，用于限流。
+// audit_log / audit_pending: 加密且哈希链接的审计日志，以及登录前暂存的事件。
+// item_history: 条目每次修改前的加密内容 (历史版本)。
+// attachments / attachment_chunks: 条目的附件，按块加密存储。
 func (db *DB) createTables() error {
 	usersTable := `
 	CREATE TABLE IF NOT EXISTS users (
 		username TEXT PRIMARY KEY,
 		salt BLOB,             -- 用于密保答案 Hash 的随机盐
-		question_1 TEXT,       -- 密保问题 (明文)
-		question_2 TEXT,       -- 密保问题 (明文)
-		question_3 TEXT,       -- 密保问题 (明文)
+		question_1 TEXT,       -- 密保问题 (旧版本明文，迁移到 enc_questions 后清空)
+		question_2 TEXT,       -- 密保问题 (旧版本明文，迁移到 enc_questions 后清空)
+		question_3 TEXT,       -- 密保问题 (旧版本明文，迁移到 enc_questions 后清空)
 		enc_m BLOB,            -- 被 Key A 加密后的 Master Key
 		enc_b BLOB,            -- 被 Root Key 加密后的 Auth Key
 		enc_c BLOB,            -- 被 Key B 加密后的 Data Key
@@ -66,16 +80,172 @@ func (db *DB) createTables() error {
 		FOREIGN KEY(username) REFERENCES users(username)
 	);`
 
-	if _, err := db.Exec(usersTable); err != nil {
+	recoveryCodesTable := `
+	CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT,
+		salt BLOB,             -- 恢复码 Argon2id 盐
+		enc_w BLOB,            -- 被恢复码派生密钥加密后的 Recovery Key W
+		used_at DATETIME,      -- 使用时间 (NULL 表示未使用)
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(username) REFERENCES users(username)
+	);`
+
+	lookupAttemptsTable := `
+	CREATE TABLE IF NOT EXISTS lookup_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		lookup_key BLOB,       -- HMAC(用户名)，不保存明文用户名
+		attempted_at INTEGER   -- Unix 时间戳 (秒)
+	);`
+
+	auditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT,
+		seq INTEGER,           -- 用户内的事件序号 (从 1 开始连续递增)
+		enc_event BLOB,        -- 被 Key C 派生密钥加密后的事件 JSON
+		mac BLOB,              -- HMAC(上一条 mac || seq || enc_event)，构成哈希链
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(username) REFERENCES users(username)
+	);`
+
+	auditPendingTable := `
+	CREATE TABLE IF NOT EXISTS audit_pending (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT,
+		event BLOB,            -- 未登录时发生的事件 JSON (无 Key C 可用)，下次登录时并入 audit_log
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(username) REFERENCES users(username)
+	);`
+
+	itemHistoryTable := `
+	CREATE TABLE IF NOT EXISTS item_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		item_id INTEGER,       -- vault.id
+		username TEXT,
+		site TEXT,             -- 该版本的网站/应用名称
+		enc_data BLOB,         -- 该版本被 Key C 加密后的账号密码 JSON (原样归档，不重新加密)
+		saved_at DATETIME,     -- 该版本的写入时间 (原 vault.updated_at)
+		archived_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- 被新版本替换的时间
+		FOREIGN KEY(username) REFERENCES users(username)
+	);
+	CREATE INDEX IF NOT EXISTS idx_item_history_item ON item_history(item_id);`
+
+	attachmentsTable := `
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		item_id INTEGER,       -- vault.id
+		username TEXT,
+		enc_key BLOB,          -- 附件的随机文件密钥，被 Key C 派生的包装密钥加密
+		enc_meta BLOB,         -- 被 Key C 加密的文件名、大小等 JSON
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(username) REFERENCES users(username)
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachments_item ON attachments(item_id);
+	CREATE TABLE IF NOT EXISTS attachment_chunks (
+		attachment_id INTEGER, -- attachments.id
+		seq INTEGER,           -- 块序号，从 0 开始
+		username TEXT,
+		data BLOB,             -- 被文件密钥加密的块 (分块 AES-GCM)
+		PRIMARY KEY (attachment_id, seq),
+		FOREIGN KEY(username) REFERENCES users(username)
+	);`
+
+	for _, stmt := range []string{usersTable, vaultTable, recoveryCodesTable, lookupAttemptsTable, auditLogTable, auditPendingTable, itemHistoryTable, attachmentsTable} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithTx 在事务中执行 fn，fn 返回错误时回滚，否则提交。
+func (db *DB) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
 		return err
 	}
-	if _, err := db.Exec(vaultTable); err != nil {
+	if err := fn(tx); err != nil {
+		tx.Rollback()
 		return err
 	}
+	return tx.Commit()
+}
+
+// Execer 由 *sql.DB 和 *sql.Tx 共同实现，便于同一写操作在事务内外复用。
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Queryer 由 *sql.DB 和 *sql.Tx 共同实现，便于同一读操作在事务内外复用。
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// migrate 为旧版本创建的数据库补充新增的列。
+// CREATE TABLE IF NOT EXISTS 不会修改已存在的表，因此新列统一在这里通过 ALTER TABLE 添加。
+func (db *DB) migrate() error {
+	columns := []struct {
+		table, name, def string
+	}{
+		{"users", "pass_salt", "BLOB"},      // 主密码的 Argon2id 盐 (未启用主密码时为 NULL)
+		{"users", "enc_r", "BLOB"},          // 被 Key M 派生的恢复密钥加密后的 Data Key
+		{"users", "enc_mb", "BLOB"},         // 被解锁密钥 (Key B / Key B + 主密码) 加密后的 Master Key
+		{"users", "enc_a", "BLOB"},          // 被 Key M 派生密钥加密后的 Key A (恢复码轮转时使用)
+		{"users", "enc_w", "BLOB"},          // 被 Key M 派生密钥加密后的 Recovery Key W
+		{"users", "enc_mw", "BLOB"},         // 被 Recovery Key W 加密后的 Master Key
+		{"users", "enc_questions", "BLOB"},  // 被 Root Key 派生密钥加密后的密保问题
+		{"users", "audit_head", "BLOB"},     // 审计日志链头 (最后序号 + MAC)，用于发现截断
+		{"vault", "deleted_at", "DATETIME"}, // 移到回收站的时间 (NULL 表示未删除)
+		{"vault", "created_at", "DATETIME"}, // 创建时间 (此列加入前创建的条目为 NULL)
+		{"vault", "used_at", "DATETIME"},    // 最近一次查看或复制密码的时间，用于搜索排序
+	}
 
+	for _, c := range columns {
+		exists, err := db.hasColumn(c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.name, c.def)
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// hasColumn 检查表中是否已存在指定列。
+func (db *DB) hasColumn(table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 type User struct {
 	Username  string
 	Salt      []byte
@@ -85,40 +255,70 @@ type User struct {
 	EncM      []byte
 	EncB      []byte
 	EncC      []byte
+	PassSalt  []byte // 主密码盐，为空表示未启用主密码
+	EncR      []byte // 恢复通道: 被 Key M 派生密钥加密的 Key C
+	EncMB     []byte // 被解锁密钥加密的 Key M (TOTP 登录后恢复 M)
+	EncA      []byte // 被 Key M 派生密钥加密的 Key A
+	EncW      []byte // 被 Key M 派生密钥加密的 Recovery Key W
+	EncMW     []byte // 被 Recovery Key W 加密的 Key M
+	// EncQuestions 被 Root Key 派生密钥加密的密保问题 (JSON 数组)。
+	// 旧版本用户为空，此时 Question1-3 为明文。
+	EncQuestions []byte
 }
 
 func (db *DB) CreateUser(u *User) error {
-	stmt := `INSERT INTO users (username, salt, question_1, question_2, question_3, enc_m, enc_b, enc_c) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := db.Exec(stmt, u.Username, u.Salt, u.Question1, u.Question2, u.Question3, u.EncM, u.EncB, u.EncC)
+	return InsertUser(db, u)
+}
+
+// InsertUser 写入用户记录，可在事务中调用。
+func InsertUser(ex Execer, u *User) error {
+	stmt := `INSERT INTO users (username, salt, question_1, question_2, question_3, enc_m, enc_b, enc_c, pass_salt, enc_r, enc_mb, enc_a, enc_w, enc_mw, enc_questions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := ex.Exec(stmt, u.Username, u.Salt, u.Question1, u.Question2, u.Question3, u.EncM, u.EncB, u.EncC,
+		nullBytes(u.PassSalt), nullBytes(u.EncR), nullBytes(u.EncMB), nullBytes(u.EncA), nullBytes(u.EncW), nullBytes(u.EncMW), nullBytes(u.EncQuestions))
 	return err
 }
 
 func (db *DB) GetUser(username string) (*User, error) {
-	stmt := `SELECT username, salt, question_1, question_2, question_3, enc_m, enc_b, enc_c FROM users WHERE username = ?`
+	stmt := `SELECT username, salt, question_1, question_2, question_3, enc_m, enc_b, enc_c, pass_salt, enc_r, enc_mb, enc_a, enc_w, enc_mw, enc_questions FROM users WHERE username = ?`
 	row := db.QueryRow(stmt, username)
 
 	u := &User{}
-	err := row.Scan(&u.Username, &u.Salt, &u.Question1, &u.Question2, &u.Question3, &u.EncM, &u.EncB, &u.EncC)
+	err := row.Scan(&u.Username, &u.Salt, &u.Question1, &u.Question2, &u.Question3, &u.EncM, &u.EncB, &u.EncC,
+		&u.PassSalt, &u.EncR, &u.EncMB, &u.EncA, &u.EncW, &u.EncMW, &u.EncQuestions)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
+// nullBytes 将空字节切片转换为 NULL，避免在可选列中写入空 BLOB。
+func nullBytes(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
 func (db *DB) SaveVaultItem(username, site string, encData []byte) error {
-	stmt := `INSERT INTO vault (username, site, enc_data) VALUES (?, ?, ?)`
+	stmt := `INSERT INTO vault (username, site, enc_data, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)`
 	_, err := db.Exec(stmt, username, site, encData)
 	return err
 }
 
 type VaultItem struct {
-	ID      int
-	Site    string
-	EncData []byte
+	ID        int
+	Site      string
+	EncData   []byte
+	UpdatedAt time.Time
+	// CreatedAt 创建时间，旧条目没有记录时为零值
+	CreatedAt time.Time
+	// UsedAt 最近一次使用的时间，从未使用时为零值
+	UsedAt time.Time
 }
 
+// GetVaultItems 获取用户未删除的条目 (不含回收站)。
 func (db *DB) GetVaultItems(username string) ([]VaultItem, error) {
-	stmt := `SELECT id, site, enc_data FROM vault WHERE username = ?`
+	stmt := `SELECT id, site, enc_data, updated_at, created_at, used_at FROM vault WHERE username = ? AND deleted_at IS NULL`
 	rows, err := db.Query(stmt, username)
 	if err != nil {
 		return nil, err
@@ -128,22 +328,56 @@ func (db *DB) GetVaultItems(username string) ([]VaultItem, error) {
 	var items []VaultItem
 	for rows.Next() {
 		var i VaultItem
-		if err := rows.Scan(&i.ID, &i.Site, &i.EncData); err != nil {
+		var updatedAt, createdAt, usedAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.Site, &i.EncData, &updatedAt, &createdAt, &usedAt); err != nil {
 			return nil, err
 		}
+		i.UpdatedAt = updatedAt.Time
+		i.CreatedAt = createdAt.Time
+		i.UsedAt = usedAt.Time
 		items = append(items, i)
 	}
 	return items, nil
 }
 
-func (db *DB) UpdateVaultItem(id int, site string, encData []byte) error {
-	stmt := `UPDATE vault SET site = ?, enc_data = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := db.Exec(stmt, site, encData, id)
+// TouchVaultItem 记录条目被使用 (查看或复制密码) 的时间，不改变 updated_at。
+func (db *DB) TouchVaultItem(id int) error {
+	_, err := db.Exec(`UPDATE vault SET used_at = CURRENT_TIMESTAMP WHERE id = ?`, id)
 	return err
 }
 
-func (db *DB) DeleteVaultItem(id int) error {
-	stmt := `DELETE FROM vault WHERE id = ?`
-	_, err := db.Exec(stmt, id)
+// UpdateVaultItem 更新条目，更新前将旧内容归档到 item_history，每个条目最多保留 keepHistory 个历史版本。
+func (db *DB) UpdateVaultItem(id int, site string, encData []byte, keepHistory int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if keepHistory > 0 {
+			if err := ArchiveVaultItem(tx, id); err != nil {
+				return err
+			}
+		}
+		stmt := `UPDATE vault SET site = ?, enc_data = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+		if _, err := tx.Exec(stmt, site, encData, id); err != nil {
+			return err
+		}
+		return PruneItemHistory(tx, id, keepHistory)
+	})
+}
+
+// SetVaultItemData 只替换条目的加密内容，不归档历史版本，也不改变 updated_at。
+func (db *DB) SetVaultItemData(id int, encData []byte) error {
+	_, err := db.Exec(`UPDATE vault SET enc_data = ? WHERE id = ?`, encData, id)
 	return err
 }
+
+// DeleteVaultItem 永久删除条目及其历史版本和附件。
+func (db *DB) DeleteVaultItem(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM item_history WHERE item_id = ?`, id); err != nil {
+			return err
+		}
+		if err := DeleteItemAttachments(tx, `id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM vault WHERE id = ?`, id)
+		return err
+	})
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TrashedItem 回收站中的条目。
+type TrashedItem struct {
+	VaultItem
+	DeletedAt time.Time
+}
+
+// TrashVaultItem 将条目移到回收站 (软删除)，历史版本保留。
+func (db *DB) TrashVaultItem(id int) error {
+	_, err := db.Exec(`UPDATE vault SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
+	return err
+}
+
+// RestoreTrashedItem 将条目从回收站还原。
+func (db *DB) RestoreTrashedItem(id int) error {
+	res, err := db.Exec(`UPDATE vault SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("item %d is not in trash", id)
+	}
+	return nil
+}
+
+// GetTrashedItems 获取用户回收站中的条目 (最近删除的在前)。
+func (db *DB) GetTrashedItems(username string) ([]TrashedItem, error) {
+	stmt := `SELECT id, site, enc_data, updated_at, deleted_at FROM vault WHERE username = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`
+	rows, err := db.Query(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []TrashedItem
+	for rows.Next() {
+		var i TrashedItem
+		var updatedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.Site, &i.EncData, &updatedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+		i.UpdatedAt, i.DeletedAt = updatedAt.Time, deletedAt.Time
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// PurgeTrash 永久删除回收站中 olderThan 之前删除的条目及其历史版本和附件，返回删除的条目数。
+// olderThan 为零值时清空整个回收站。
+func (db *DB) PurgeTrash(username string, olderThan time.Time) (int, error) {
+	cond := `username = ? AND deleted_at IS NOT NULL`
+	args := []interface{}{username}
+	if !olderThan.IsZero() {
+		cond += ` AND deleted_at < ?`
+		args = append(args, olderThan.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	var n int64
+	err := db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM item_history WHERE item_id IN (SELECT id FROM vault WHERE `+cond+`)`, args...); err != nil {
+			return err
+		}
+		if err := DeleteItemAttachments(tx, cond, args...); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM vault WHERE `+cond, args...)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
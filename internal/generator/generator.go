@@ -0,0 +1,229 @@
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// 字符类别
+const (
+	LowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	UpperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DigitChars  = "0123456789"
+	SymbolChars = "!@#$%^&*()-_=+[]{};:,.<>/?~"
+
+	// AmbiguousChars 容易混淆的字符 (手抄或口述时)
+	AmbiguousChars = "0Oo1lI"
+)
+
+// 长度限制
+const (
+	MinLength = 4
+	MaxLength = 128
+)
+
+// Options 字符密码生成选项。
+type Options struct {
+	Length int
+
+	Lower   bool
+	Upper   bool
+	Digits  bool
+	Symbols bool
+
+	// ExcludeAmbiguous 排除 0/O/o/1/l/I 等易混淆字符
+	ExcludeAmbiguous bool
+
+	// 各类别至少出现的次数 (仅对启用的类别生效)
+	MinLower   int
+	MinUpper   int
+	MinDigits  int
+	MinSymbols int
+}
+
+// DefaultOptions 默认: 20 位，四类字符各至少 1 个。
+func DefaultOptions() Options {
+	return Options{
+		Length:     20,
+		Lower:      true,
+		Upper:      true,
+		Digits:     true,
+		Symbols:    true,
+		MinLower:   1,
+		MinUpper:   1,
+		MinDigits:  1,
+		MinSymbols: 1,
+	}
+}
+
+// Result 生成结果。
+type Result struct {
+	Password string
+	// Entropy 熵 (比特)，即 log2(满足选项的所有可能结果数)
+	Entropy float64
+}
+
+// charClass 一个启用的字符类别及其最少出现次数。
+type charClass struct {
+	chars []rune
+	min   int
+}
+
+// Generate 按选项生成随机密码。
+// 核心逻辑:
+// 1. 按选项确定启用的字符类别 (已去除易混淆字符) 及各自的最少次数。
+// 2. 用动态规划统计满足约束的密码总数 N，熵 = log2(N)。
+// 3. 按各类别出现次数的组合权重抽样，再随机排列类别位置并逐位填充。
+// 安全决策: 全部随机数来自 crypto/rand，且结果在所有满足约束的密码中均匀分布，
+// 不会因为"先放必选字符再打乱"而偏向某些模式，报告的熵是准确值。
+func Generate(opts Options) (*Result, error) {
+	if opts.Length < MinLength || opts.Length > MaxLength {
+		return nil, fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+	}
+
+	classes, err := opts.classes()
+	if err != nil {
+		return nil, err
+	}
+
+	// dp[c][n]: 使用前 c 个类别、长度为 n 且满足各自最少次数的密码数
+	n := opts.Length
+	dp := make([][]*big.Int, len(classes)+1)
+	for c := range dp {
+		dp[c] = make([]*big.Int, n+1)
+		for i := range dp[c] {
+			dp[c][i] = new(big.Int)
+		}
+	}
+	dp[0][0].SetInt64(1)
+	for c, cls := range classes {
+		for total := 0; total <= n; total++ {
+			for k := cls.min; k <= total; k++ {
+				dp[c+1][total].Add(dp[c+1][total], classWeight(cls, total, k, dp[c][total-k]))
+			}
+		}
+	}
+	count := dp[len(classes)][n]
+	if count.Sign() == 0 {
+		return nil, errors.New("minimum character counts exceed the password length")
+	}
+
+	// 从最后一个类别开始，按权重抽取每个类别的字符数
+	counts := make([]int, len(classes))
+	remaining := n
+	for c := len(classes) - 1; c >= 0; c-- {
+		r, err := rand.Int(rand.Reader, dp[c+1][remaining])
+		if err != nil {
+			return nil, err
+		}
+		for k := classes[c].min; k <= remaining; k++ {
+			w := classWeight(classes[c], remaining, k, dp[c][remaining-k])
+			if r.Cmp(w) < 0 {
+				counts[c] = k
+				break
+			}
+			r.Sub(r, w)
+		}
+		remaining -= counts[c]
+	}
+
+	// 类别标签随机排列后逐位填充
+	labels := make([]int, 0, n)
+	for c, k := range counts {
+		for i := 0; i < k; i++ {
+			labels = append(labels, c)
+		}
+	}
+	if err := shuffle(labels); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	for _, c := range labels {
+		ch, err := pick(classes[c].chars)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteRune(ch)
+	}
+	return &Result{Password: sb.String(), Entropy: log2(count)}, nil
+}
+
+// classes 返回启用的字符类别。
+func (opts Options) classes() ([]charClass, error) {
+	var classes []charClass
+	add := func(enabled bool, chars string, min int) {
+		if !enabled {
+			return
+		}
+		if opts.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(AmbiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, charClass{chars: []rune(chars), min: max(min, 0)})
+	}
+	add(opts.Lower, LowerChars, opts.MinLower)
+	add(opts.Upper, UpperChars, opts.MinUpper)
+	add(opts.Digits, DigitChars, opts.MinDigits)
+	add(opts.Symbols, SymbolChars, opts.MinSymbols)
+
+	if len(classes) == 0 {
+		return nil, errors.New("at least one character class must be enabled")
+	}
+	return classes, nil
+}
+
+// classWeight = C(total, k) * |class|^k * rest，即在 total 个位置中放 k 个该类别字符的方案数。
+func classWeight(cls charClass, total, k int, rest *big.Int) *big.Int {
+	w := new(big.Int).Binomial(int64(total), int64(k))
+	w.Mul(w, new(big.Int).Exp(big.NewInt(int64(len(cls.chars))), big.NewInt(int64(k)), nil))
+	return w.Mul(w, rest)
+}
+
+// pick 均匀随机选取一个元素。
+func pick[T any](items []T) (T, error) {
+	i, err := randIntn(len(items))
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return items[i], nil
+}
+
+// shuffle Fisher-Yates 洗牌。
+func shuffle[T any](items []T) error {
+	for i := len(items) - 1; i > 0; i-- {
+		j, err := randIntn(i + 1)
+		if err != nil {
+			return err
+		}
+		items[i], items[j] = items[j], items[i]
+	}
+	return nil
+}
+
+func randIntn(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// log2 计算大整数的以 2 为底的对数。
+func log2(x *big.Int) float64 {
+	bits := x.BitLen()
+	if bits <= 53 {
+		return math.Log2(float64(x.Int64()))
+	}
+	top := new(big.Int).Rsh(x, uint(bits-53))
+	return float64(bits-53) + math.Log2(float64(top.Int64()))
+}
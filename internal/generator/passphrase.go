@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// effLargeWordlist EFF 大词表 (7776 个单词，5 个骰子确定一个)，格式为 "骰子点数\t单词"。
+// 来源: https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Wordlist 返回内置的 EFF 大词表。
+var Wordlist = sync.OnceValue(func() []string {
+	words := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+	for scanner.Scan() {
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// 口令单词数限制
+const (
+	MinWords = 3
+	MaxWords = 20
+)
+
+// PassphraseOptions 口令 (diceware) 生成选项。
+type PassphraseOptions struct {
+	Words     int
+	Separator string
+	// Capitalize 每个单词首字母大写 (不增加熵，仅满足部分网站的规则)
+	Capitalize bool
+	// Digit 在随机一个单词后追加一位随机数字
+	Digit bool
+}
+
+// DefaultPassphraseOptions 默认: 6 个单词，以 "-" 分隔 (约 77.5 比特)。
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{Words: 6, Separator: "-"}
+}
+
+// Passphrase 从 EFF 大词表中随机选取单词生成口令。
+// 熵 = 单词数 × log2(7776)，启用 Digit 时另加 log2(10 × 单词数)。
+func Passphrase(opts PassphraseOptions) (*Result, error) {
+	if opts.Words < MinWords || opts.Words > MaxWords {
+		return nil, fmt.Errorf("word count must be between %d and %d", MinWords, MaxWords)
+	}
+
+	wordlist := Wordlist()
+	words := make([]string, opts.Words)
+	for i := range words {
+		w, err := pick(wordlist)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Capitalize {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		words[i] = w
+	}
+	entropy := float64(opts.Words) * math.Log2(float64(len(wordlist)))
+
+	if opts.Digit {
+		i, err := randIntn(len(words))
+		if err != nil {
+			return nil, err
+		}
+		d, err := randIntn(10)
+		if err != nil {
+			return nil, err
+		}
+		words[i] += fmt.Sprint(d)
+		entropy += math.Log2(float64(10 * len(words)))
+	}
+
+	return &Result{Password: strings.Join(words, opts.Separator), Entropy: entropy}, nil
+}
@@ -0,0 +1,158 @@
+// Package hotkey 解析全局快捷键并通过 X11 键盘抓取注册，桌面任意位置按下时回调。
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Modifier 修饰键，取值与 X11 的修饰键掩码相同。
+type Modifier uint16
+
+const (
+	ModShift Modifier = 1 << 0
+	ModCtrl  Modifier = 1 << 2
+	ModAlt   Modifier = 1 << 3 // Mod1
+	ModSuper Modifier = 1 << 6 // Mod4
+)
+
+// modifierNames 修饰键名称及别名 (不区分大小写)，String 使用每组的第一个名称
+var modifierNames = []struct {
+	mod   Modifier
+	names []string
+}{
+	{ModCtrl, []string{"Ctrl", "Control"}},
+	{ModAlt, []string{"Alt", "Option"}},
+	{ModShift, []string{"Shift"}},
+	{ModSuper, []string{"Super", "Win", "Meta", "Cmd"}},
+}
+
+// keysyms 支持的按键名称及对应的 X11 keysym (字母和数字另行处理)
+var keysyms = map[string]uint32{
+	"Space":    0x0020,
+	"Enter":    0xff0d,
+	"Tab":      0xff09,
+	"Insert":   0xff63,
+	"Delete":   0xffff,
+	"Home":     0xff50,
+	"End":      0xff57,
+	"PageUp":   0xff55,
+	"PageDown": 0xff56,
+	"Left":     0xff51,
+	"Up":       0xff52,
+	"Right":    0xff53,
+	"Down":     0xff54,
+}
+
+// keyAliases 按键名称的别名
+var keyAliases = map[string]string{
+	"return": "Enter",
+	"del":    "Delete",
+	"ins":    "Insert",
+	"pgup":   "PageUp",
+	"pgdn":   "PageDown",
+}
+
+// Hotkey 全局快捷键: 一个或多个修饰键加一个按键。
+type Hotkey struct {
+	Modifiers Modifier
+	// Key 规范化后的按键名称，如 "K"、"7"、"F5"、"Space"
+	Key string
+}
+
+// Parse 解析 "Ctrl+Alt+K" 形式的快捷键 (不区分大小写)。
+// 安全决策: 至少需要 Ctrl、Alt 或 Super 之一，避免全局抢占普通输入 (单独的按键或 Shift+字母)。
+func Parse(s string) (Hotkey, error) {
+	parts := strings.Split(s, "+")
+	var h Hotkey
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Hotkey{}, fmt.Errorf("invalid hotkey %q", s)
+		}
+		if i < len(parts)-1 {
+			mod, ok := parseModifier(part)
+			if !ok {
+				return Hotkey{}, fmt.Errorf("unknown modifier %q in hotkey %q", part, s)
+			}
+			h.Modifiers |= mod
+			continue
+		}
+		key, ok := normalizeKey(part)
+		if !ok {
+			return Hotkey{}, fmt.Errorf("unsupported key %q in hotkey %q", part, s)
+		}
+		h.Key = key
+	}
+	if h.Modifiers&(ModCtrl|ModAlt|ModSuper) == 0 {
+		return Hotkey{}, errors.New("hotkey needs at least one of Ctrl, Alt or Super")
+	}
+	return h, nil
+}
+
+func parseModifier(name string) (Modifier, bool) {
+	for _, m := range modifierNames {
+		for _, n := range m.names {
+			if strings.EqualFold(name, n) {
+				return m.mod, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// normalizeKey 返回按键的规范名称: 字母大写，F1-F24，其余按 keysyms 中的写法。
+func normalizeKey(name string) (string, bool) {
+	if len(name) == 1 {
+		c := name[0]
+		switch {
+		case c >= 'a' && c <= 'z':
+			return string(c - 'a' + 'A'), true
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			return name, true
+		}
+		return "", false
+	}
+	if alias, ok := keyAliases[strings.ToLower(name)]; ok {
+		return alias, true
+	}
+	for k := range keysyms {
+		if strings.EqualFold(name, k) {
+			return k, true
+		}
+	}
+	var n int
+	if _, err := fmt.Sscanf(strings.ToUpper(name), "F%d", &n); err == nil && n >= 1 && n <= 24 && fmt.Sprintf("F%d", n) == strings.ToUpper(name) {
+		return fmt.Sprintf("F%d", n), true
+	}
+	return "", false
+}
+
+// String 规范写法，如 "Ctrl+Alt+K"。
+func (h Hotkey) String() string {
+	var parts []string
+	for _, m := range modifierNames {
+		if h.Modifiers&m.mod != 0 {
+			parts = append(parts, m.names[0])
+		}
+	}
+	return strings.Join(append(parts, h.Key), "+")
+}
+
+// keysym 按键对应的 X11 keysym (字母为小写形式)。
+func (h Hotkey) keysym() uint32 {
+	if len(h.Key) == 1 {
+		c := h.Key[0]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		return uint32(c)
+	}
+	if k, ok := keysyms[h.Key]; ok {
+		return k
+	}
+	var n uint32
+	fmt.Sscanf(h.Key, "F%d", &n)
+	return 0xffbe + n - 1
+}
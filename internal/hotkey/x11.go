@@ -0,0 +1,150 @@
+package hotkey
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"key-box/internal/x11"
+)
+
+// ErrUnsupported 当前环境无法注册全局快捷键 (没有 X11 显示，例如纯 Wayland 会话)。
+var ErrUnsupported = errors.New("global hotkeys need an X11 display")
+
+// ErrTaken 快捷键已被其他程序占用。
+var ErrTaken = errors.New("hotkey is already grabbed by another application")
+
+const (
+	modLock Modifier = 1 << 1 // Caps Lock
+	modMod2 Modifier = 1 << 4 // 通常为 Num Lock
+
+	// modMask 判断快捷键时关心的修饰键，其余 (Caps Lock、Num Lock 等) 忽略
+	modMask = ModShift | ModCtrl | ModAlt | ModSuper
+
+	grabModeAsync = 1
+)
+
+// Register 在 X11 根窗口上抓取快捷键，按下时调用 onPress。
+// 核心逻辑:
+//  1. 通过键盘映射找到产生该按键的全部键码，逐个抓取。
+//  2. 同时抓取附加 Caps Lock / Num Lock 的组合，开启这些锁定键时快捷键仍然有效。
+//  3. 抓取失败 (BadAccess) 说明其他程序已占用，返回 ErrTaken。
+//
+// onPress 在后台 goroutine 中调用，调用方需自行切换到界面线程。
+// 返回的 stop 关闭连接，X 服务器随即释放抓取。
+// Wayland 会话中经 XWayland 注册的快捷键只在 X11 程序获得焦点时有效。
+func Register(h Hotkey, onPress func()) (stop func(), err error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return nil, ErrUnsupported
+	}
+	c, err := x11.Dial(display)
+	if err != nil {
+		return nil, err
+	}
+
+	keycodes, err := keycodesFor(c, h.keysym())
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if len(keycodes) == 0 {
+		c.Close()
+		return nil, fmt.Errorf("no key on the current keyboard layout produces %s", h.Key)
+	}
+
+	for _, kc := range keycodes {
+		for _, extra := range []Modifier{0, modLock, modMod2, modLock | modMod2} {
+			if err := grabKey(c, kc, h.Modifiers|extra); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+	}
+	if err := c.Sync(); err != nil {
+		c.Close()
+		var xerr *x11.Error
+		if errors.As(err, &xerr) && xerr.Code == x11.ErrorAccess {
+			return nil, ErrTaken
+		}
+		return nil, err
+	}
+
+	go serve(c, keycodes, h.Modifiers, onPress)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { c.Close() })
+	}, nil
+}
+
+// keycodesFor 查询键盘映射，返回产生 keysym 的全部键码 (只看每个键码的前两列: 无修饰和 Shift)。
+func keycodesFor(c *x11.Conn, keysym uint32) ([]byte, error) {
+	count := int(c.MaxKeycode) - int(c.MinKeycode) + 1
+	req := c.Request(x11.OpGetKeyboardMapping, 0, 4)
+	req[4] = c.MinKeycode
+	req[5] = byte(count)
+	if _, err := c.Write(req); err != nil {
+		return nil, err
+	}
+	reply, err := c.Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyboard mapping: %w", err)
+	}
+
+	perKeycode := int(reply[1])
+	upper := keysym
+	if keysym >= 'a' && keysym <= 'z' {
+		upper = keysym - 'a' + 'A'
+	}
+	var keycodes []byte
+	for i := 0; i < count; i++ {
+		for col := 0; col < min(perKeycode, 2); col++ {
+			off := 32 + (i*perKeycode+col)*4
+			if off+4 > len(reply) {
+				break
+			}
+			if sym := binary.LittleEndian.Uint32(reply[off:]); sym == keysym || sym == upper {
+				keycodes = append(keycodes, c.MinKeycode+byte(i))
+				break
+			}
+		}
+	}
+	return keycodes, nil
+}
+
+func grabKey(c *x11.Conn, keycode byte, mods Modifier) error {
+	req := c.Request(x11.OpGrabKey, 1, 12) // owner_events
+	binary.LittleEndian.PutUint32(req[4:], c.Root)
+	binary.LittleEndian.PutUint16(req[8:], uint16(mods))
+	req[10] = keycode
+	req[11] = grabModeAsync // pointer_mode
+	req[12] = grabModeAsync // keyboard_mode
+	_, err := c.Write(req)
+	return err
+}
+
+// serve 读取 KeyPress 事件直到连接关闭。
+func serve(c *x11.Conn, keycodes []byte, mods Modifier, onPress func()) {
+	for {
+		buf, err := c.ReadPacket()
+		if err != nil {
+			return
+		}
+		if buf[0]&0x7f != x11.EventKeyPress {
+			continue
+		}
+		state := Modifier(binary.LittleEndian.Uint16(buf[28:]))
+		if state&modMask != mods {
+			continue
+		}
+		for _, kc := range keycodes {
+			if buf[1] == kc {
+				onPress()
+				break
+			}
+		}
+	}
+}
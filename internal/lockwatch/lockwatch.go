@@ -0,0 +1,153 @@
+package lockwatch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// 锁定原因，传给 Watch 的回调。
+const (
+	ReasonSleep       = "system sleep"
+	ReasonSessionLock = "session lock"
+	ReasonScreenSaver = "screen saver"
+)
+
+const (
+	login1Name         = "org.freedesktop.login1"
+	login1Path         = dbus.ObjectPath("/org/freedesktop/login1")
+	login1ManagerIface = "org.freedesktop.login1.Manager"
+	login1SessionIface = "org.freedesktop.login1.Session"
+)
+
+// screenSaverIfaces 会话总线上发出 ActiveChanged 信号的屏保接口 (KDE/Xfce 与 GNOME)。
+var screenSaverIfaces = []string{
+	"org.freedesktop.ScreenSaver",
+	"org.gnome.ScreenSaver",
+}
+
+// Watch 监听系统休眠和锁屏信号，收到时调用 onLock(reason)。
+// 核心逻辑:
+// 1. 系统总线: logind 的 PrepareForSleep(true) 和当前会话的 Lock 信号。
+// 2. 会话总线: ScreenSaver 的 ActiveChanged(true) 信号。
+// 3. 任一总线不可用时跳过该总线，两者都不可用时返回错误。
+// onLock 在 D-Bus 信号 goroutine 中调用，调用方需自行切换到界面线程。
+// 返回的 stop 关闭连接并结束监听。
+func Watch(onLock func(reason string)) (stop func(), err error) {
+	var conns []*dbus.Conn
+	var errs []error
+
+	if conn, err := watchSystemBus(onLock); err != nil {
+		errs = append(errs, fmt.Errorf("system bus: %w", err))
+	} else {
+		conns = append(conns, conn)
+	}
+	if conn, err := watchSessionBus(onLock); err != nil {
+		errs = append(errs, fmt.Errorf("session bus: %w", err))
+	} else {
+		conns = append(conns, conn)
+	}
+
+	if len(conns) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}, nil
+}
+
+// watchSystemBus 订阅 logind 的休眠和会话锁定信号。
+func watchSystemBus(onLock func(string)) (*dbus.Conn, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	// 只关注本进程所在会话的 Lock 信号 (loginctl lock-session / lock-sessions)；
+	// 查不到会话时 (例如不在 logind 会话中运行) 退而匹配所有会话。
+	sessionMatch := []dbus.MatchOption{
+		dbus.WithMatchInterface(login1SessionIface),
+		dbus.WithMatchMember("Lock"),
+	}
+	var session dbus.ObjectPath
+	if err := conn.Object(login1Name, login1Path).Call(login1ManagerIface+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&session); err == nil {
+		sessionMatch = append(sessionMatch, dbus.WithMatchObjectPath(session))
+	}
+
+	matches := [][]dbus.MatchOption{
+		{dbus.WithMatchInterface(login1ManagerIface), dbus.WithMatchMember("PrepareForSleep")},
+		sessionMatch,
+	}
+	if err := subscribe(conn, matches, onLock); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// watchSessionBus 订阅桌面屏保的激活信号。
+func watchSessionBus(onLock func(string)) (*dbus.Conn, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches [][]dbus.MatchOption
+	for _, iface := range screenSaverIfaces {
+		matches = append(matches, []dbus.MatchOption{dbus.WithMatchInterface(iface), dbus.WithMatchMember("ActiveChanged")})
+	}
+	if err := subscribe(conn, matches, onLock); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// subscribe 注册匹配规则，并在后台 goroutine 中分发信号，连接关闭时 goroutine 退出。
+func subscribe(conn *dbus.Conn, matches [][]dbus.MatchOption, onLock func(string)) error {
+	for _, m := range matches {
+		if err := conn.AddMatchSignal(m...); err != nil {
+			return err
+		}
+	}
+
+	ch := make(chan *dbus.Signal, 8)
+	conn.Signal(ch)
+	go func() {
+		for sig := range ch {
+			if reason, ok := lockReason(sig); ok {
+				onLock(reason)
+			}
+		}
+	}()
+	return nil
+}
+
+// lockReason 判断信号是否表示需要锁定。
+// PrepareForSleep 和 ActiveChanged 只在参数为 true (即将休眠/屏保启动) 时锁定。
+func lockReason(sig *dbus.Signal) (string, bool) {
+	switch sig.Name {
+	case login1ManagerIface + ".PrepareForSleep":
+		return ReasonSleep, firstBool(sig.Body)
+	case login1SessionIface + ".Lock":
+		return ReasonSessionLock, true
+	}
+	for _, iface := range screenSaverIfaces {
+		if sig.Name == iface+".ActiveChanged" {
+			return ReasonScreenSaver, firstBool(sig.Body)
+		}
+	}
+	return "", false
+}
+
+func firstBool(body []interface{}) bool {
+	if len(body) == 0 {
+		return false
+	}
+	b, ok := body[0].(bool)
+	return ok && b
+}
@@ -0,0 +1,77 @@
+package strength
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"sync"
+)
+
+// 内置词表来自 zxcvbn 的频率表 (MIT 许可)，按出现频率排序，每行一个小写单词。
+// english 和 surnames 只保留最常见的前 20000 / 10000 个。
+//
+//go:embed data/*.txt
+var dataFS embed.FS
+
+// 词典名称
+const (
+	DictPasswords   = "passwords"
+	DictEnglish     = "english"
+	DictMaleNames   = "male_names"
+	DictFemaleNames = "female_names"
+	DictSurnames    = "surnames"
+	DictUserInputs  = "user_inputs"
+)
+
+// rankedDict 单词 -> 排名 (1 为最常见)
+type rankedDict map[string]int
+
+// namedDict 带名称的词典，按固定顺序匹配以保证结果稳定。
+type namedDict struct {
+	name  string
+	words rankedDict
+}
+
+// builtinDicts 加载内置词典 (只加载一次)。
+var builtinDicts = sync.OnceValue(func() []namedDict {
+	names := []string{DictPasswords, DictEnglish, DictMaleNames, DictFemaleNames, DictSurnames}
+	dicts := make([]namedDict, 0, len(names))
+	for _, name := range names {
+		content, err := dataFS.ReadFile("data/" + name + ".txt")
+		if err != nil {
+			panic("strength: missing embedded wordlist " + name)
+		}
+		words := make(rankedDict)
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for scanner.Scan() {
+			if w := scanner.Text(); w != "" {
+				if _, ok := words[w]; !ok {
+					words[w] = len(words) + 1
+				}
+			}
+		}
+		dicts = append(dicts, namedDict{name: name, words: words})
+	}
+	return dicts
+})
+
+// userInputsDict 将用户相关信息 (网站名、用户名等) 作为额外词典，排名按传入顺序。
+func userInputsDict(inputs []string) rankedDict {
+	words := make(rankedDict)
+	for _, in := range inputs {
+		w := strings.ToLower(strings.TrimSpace(in))
+		if w == "" {
+			continue
+		}
+		if _, ok := words[w]; !ok {
+			words[w] = len(words) + 1
+		}
+		// 邮箱地址同时加入 @ 之前的部分
+		if local, _, ok := strings.Cut(w, "@"); ok && local != "" {
+			if _, ok := words[local]; !ok {
+				words[local] = len(words) + 1
+			}
+		}
+	}
+	return words
+}
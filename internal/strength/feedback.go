@@ -0,0 +1,111 @@
+package strength
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 通用建议
+const (
+	suggestionDefault    = "使用多个不常见的单词组成口令，或使用密码生成器"
+	suggestionAddWord    = "再加一两个不常见的单词"
+	suggestionNoPatterns = "不需要符号、数字或大写字母，足够长的随机单词组合同样安全"
+)
+
+// feedback 根据等级和最主要的匹配模式给出警告和改进建议。
+// 等级 ≥ 3 时不再提示。
+func feedback(score int, sequence []*Match) (string, []string) {
+	if len(sequence) == 0 {
+		return "", []string{suggestionDefault, suggestionNoPatterns}
+	}
+	if score > ScoreFair {
+		return "", nil
+	}
+
+	// 以最长的匹配作为主要问题
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len([]rune(m.Token)) > len([]rune(longest.Token)) {
+			longest = m
+		}
+	}
+
+	warning, suggestions := matchFeedback(longest, len(sequence) == 1)
+	return warning, append([]string{suggestionAddWord}, suggestions...)
+}
+
+func matchFeedback(m *Match, soleMatch bool) (string, []string) {
+	switch m.Pattern {
+	case PatternDictionary:
+		return dictionaryFeedback(m, soleMatch)
+
+	case PatternSpatial:
+		warning := "整行键盘按键很容易被猜到"
+		if m.Turns > 1 {
+			warning = "较短的键盘图案很容易被猜到"
+		}
+		return warning, []string{"使用更长、转折更多的键盘图案，或者干脆不用"}
+
+	case PatternRepeat:
+		warning := `"aaa" 这样的重复字符很容易被猜到`
+		if len([]rune(m.BaseToken)) > 1 {
+			warning = `"abcabcabc" 这样的重复只比 "abc" 难猜一点点`
+		}
+		return warning, []string{"避免重复的单词和字符"}
+
+	case PatternSequence:
+		return `"abc" 或 "6543" 这样的序列很容易被猜到`, []string{"避免字母或数字序列"}
+
+	case PatternYear:
+		return "近年的年份很容易被猜到", []string{"避免使用近年的年份", "避免使用与自己相关的年份"}
+
+	case PatternDate:
+		return "日期往往很容易被猜到", []string{"避免使用与自己相关的日期和年份"}
+	}
+	return "", nil
+}
+
+func dictionaryFeedback(m *Match, soleMatch bool) (string, []string) {
+	var warning string
+	switch m.Dictionary {
+	case DictPasswords:
+		switch {
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 10:
+			warning = "这是最常用的 10 个密码之一"
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 100:
+			warning = "这是最常用的 100 个密码之一"
+		case soleMatch:
+			warning = "这是一个非常常见的密码"
+		default:
+			warning = "与常见密码相似"
+		}
+	case DictEnglish:
+		if soleMatch {
+			warning = "单个单词很容易被猜到"
+		}
+	case DictSurnames, DictMaleNames, DictFemaleNames:
+		if soleMatch {
+			warning = "单独的姓名很容易被猜到"
+		} else {
+			warning = "常见的姓名很容易被猜到"
+		}
+	case DictUserInputs:
+		warning = "密码包含了网站名或用户名等相关信息"
+	}
+
+	var suggestions []string
+	runes := []rune(m.Token)
+	switch {
+	case unicode.IsUpper(runes[0]) && strings.ToLower(string(runes[1:])) == string(runes[1:]):
+		suggestions = append(suggestions, "首字母大写没什么帮助")
+	case strings.ToUpper(m.Token) == m.Token && strings.ToLower(m.Token) != m.Token:
+		suggestions = append(suggestions, "全部大写和全部小写几乎一样容易猜")
+	}
+	if m.Reversed && len(runes) >= 4 {
+		suggestions = append(suggestions, "倒着拼写单词并不能让它更难猜")
+	}
+	if m.L33t {
+		suggestions = append(suggestions, `"@" 代替 "a" 这样的替换没什么帮助`)
+	}
+	return warning, suggestions
+}
@@ -0,0 +1,100 @@
+package strength
+
+import (
+	"strings"
+	"sync"
+)
+
+// 键盘布局: 每个按键写作 "未按 Shift 的字符 + 按 Shift 的字符"，行首缩进表示错位。
+const (
+	qwertyLayout = `
+` + "`~" + ` 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+
+    qQ wW eE rR tT yY uU iI oO pP [{ ]} \|
+     aA sS dD fF gG hH jJ kK lL ;: '"
+      zZ xX cC vV bB nN mM ,< .> /?
+`
+	dvorakLayout = `
+` + "`~" + ` 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) [{ ]}
+    '" ,< .> pP yY fF gG cC rR lL /? =+ \|
+     aA oO eE uU iI dD hH tT nN sS -_
+      ;: qQ jJ kK xX bB mM wW vV zZ
+`
+	keypadLayout = `
+  / * -
+7 8 9 +
+4 5 6
+1 2 3
+  0 .
+`
+)
+
+// keyboardGraph 键盘邻接图: 字符 -> 各方向相邻按键 (空字符串表示该方向没有按键)。
+// 方向的顺序固定，用于统计图案中的转折次数。
+type keyboardGraph struct {
+	name          string
+	adjacency     map[rune][]string
+	startingKeys  float64
+	averageDegree float64
+}
+
+// keyboardGraphs 由布局字符串生成邻接图 (与 zxcvbn 的构建方式一致)。
+var keyboardGraphs = sync.OnceValue(func() []*keyboardGraph {
+	return []*keyboardGraph{
+		buildKeyboardGraph("qwerty", qwertyLayout, true),
+		buildKeyboardGraph("dvorak", dvorakLayout, true),
+		buildKeyboardGraph("keypad", keypadLayout, false),
+	}
+})
+
+type keyPos struct{ x, y int }
+
+// buildKeyboardGraph 计算每个按键的相邻按键。
+// 错位键盘 (slanted) 每行向右错开半个键位，有 6 个方向；数字小键盘对齐，有 8 个方向。
+func buildKeyboardGraph(name, layout string, slanted bool) *keyboardGraph {
+	positions := make(map[keyPos]string)
+	var tokenSize int
+	for y, line := range strings.Split(layout, "\n") {
+		slant := 0
+		if slanted {
+			slant = y - 1
+		}
+		offset := 0
+		for _, token := range strings.Fields(line) {
+			if tokenSize == 0 {
+				tokenSize = len(token)
+			}
+			idx := offset + strings.Index(line[offset:], token)
+			offset = idx + len(token)
+			positions[keyPos{x: (idx - slant) / (tokenSize + 1), y: y}] = token
+		}
+	}
+
+	neighbours := func(p keyPos) []keyPos {
+		x, y := p.x, p.y
+		if slanted {
+			return []keyPos{{x - 1, y}, {x, y - 1}, {x + 1, y - 1}, {x + 1, y}, {x, y + 1}, {x - 1, y + 1}}
+		}
+		return []keyPos{{x - 1, y}, {x - 1, y - 1}, {x, y - 1}, {x + 1, y - 1}, {x + 1, y}, {x + 1, y + 1}, {x, y + 1}, {x - 1, y + 1}}
+	}
+
+	g := &keyboardGraph{name: name, adjacency: make(map[rune][]string)}
+	var degrees int
+	for pos, token := range positions {
+		for _, ch := range token {
+			adj := make([]string, 0, 8)
+			for _, n := range neighbours(pos) {
+				adj = append(adj, positions[n])
+				if positions[n] != "" {
+					degrees++
+				}
+			}
+			g.adjacency[ch] = adj
+		}
+	}
+	g.startingKeys = float64(len(g.adjacency))
+	g.averageDegree = float64(degrees) / float64(len(g.adjacency))
+	return g
+}
+
+// shiftedChars 在错位键盘上需要按 Shift 输入的字符。
+const shiftedChars = "~!@#$%^&*()_+QWERTYUIOP{}|ASDFGHJKL:\"ZXCVBNM<>?"
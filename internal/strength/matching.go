@@ -0,0 +1,554 @@
+package strength
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Pattern 匹配到的密码模式。
+type Pattern string
+
+const (
+	PatternDictionary Pattern = "dictionary"
+	PatternSpatial    Pattern = "spatial"
+	PatternRepeat     Pattern = "repeat"
+	PatternSequence   Pattern = "sequence"
+	PatternDate       Pattern = "date"
+	PatternYear       Pattern = "year"
+	PatternBruteforce Pattern = "bruteforce"
+)
+
+// Match 密码中被某种模式覆盖的一段 (I、J 为字符下标，闭区间)。
+type Match struct {
+	Pattern Pattern
+	I, J    int
+	Token   string
+	Guesses float64
+
+	// dictionary
+	Dictionary  string
+	Rank        int
+	MatchedWord string
+	Reversed    bool
+	L33t        bool
+	Sub         map[rune]rune // 替换字符 -> 原字母
+
+	// spatial
+	Graph        string
+	Turns        int
+	ShiftedCount int
+
+	// repeat
+	BaseToken   string
+	BaseGuesses float64
+	RepeatCount int
+
+	// sequence
+	Ascending bool
+
+	// date / year
+	Year, Month, Day int
+	Separator        string
+}
+
+// omnimatch 运行全部匹配器，结果按位置排序。
+func omnimatch(pw []rune, dicts []namedDict) []*Match {
+	var matches []*Match
+	matches = append(matches, dictionaryMatch(pw, dicts)...)
+	matches = append(matches, reverseDictionaryMatch(pw, dicts)...)
+	matches = append(matches, l33tMatch(pw, dicts)...)
+	matches = append(matches, spatialMatch(pw)...)
+	matches = append(matches, repeatMatch(pw, dicts)...)
+	matches = append(matches, sequenceMatch(pw)...)
+	matches = append(matches, yearMatch(pw)...)
+	matches = append(matches, dateMatch(pw)...)
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].I != matches[b].I {
+			return matches[a].I < matches[b].I
+		}
+		return matches[a].J < matches[b].J
+	})
+	return matches
+}
+
+// lowerRunes 逐字符转小写 (保持长度不变，下标与原密码一一对应)。
+func lowerRunes(pw []rune) []rune {
+	out := make([]rune, len(pw))
+	for i, r := range pw {
+		out[i] = unicode.ToLower(r)
+	}
+	return out
+}
+
+// ---- 词典 ----
+
+// dictionaryMatch 查找所有出现在词典中的子串 (不区分大小写)。
+func dictionaryMatch(pw []rune, dicts []namedDict) []*Match {
+	lower := lowerRunes(pw)
+	var matches []*Match
+	for _, d := range dicts {
+		for i := range lower {
+			for j := i; j < len(lower); j++ {
+				word := string(lower[i : j+1])
+				if rank, ok := d.words[word]; ok {
+					matches = append(matches, &Match{
+						Pattern:     PatternDictionary,
+						I:           i,
+						J:           j,
+						Token:       string(pw[i : j+1]),
+						Dictionary:  d.name,
+						Rank:        rank,
+						MatchedWord: word,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// reverseDictionaryMatch 查找倒序拼写的单词。
+func reverseDictionaryMatch(pw []rune, dicts []namedDict) []*Match {
+	reversed := slices.Clone(pw)
+	slices.Reverse(reversed)
+
+	matches := dictionaryMatch(reversed, dicts)
+	for _, m := range matches {
+		token := []rune(m.Token)
+		slices.Reverse(token)
+		m.Token = string(token)
+		m.Reversed = true
+		m.I, m.J = len(pw)-1-m.J, len(pw)-1-m.I
+	}
+	return matches
+}
+
+// l33tTable 常见的字母替换 (字母 -> 替换字符)。
+var l33tTable = map[rune][]rune{
+	'a': {'4', '@'},
+	'b': {'8'},
+	'c': {'(', '{', '[', '<'},
+	'e': {'3'},
+	'g': {'6', '9'},
+	'i': {'1', '!', '|'},
+	'l': {'1', '|', '7'},
+	'o': {'0'},
+	's': {'$', '5'},
+	't': {'+', '7'},
+	'x': {'%'},
+	'z': {'2'},
+}
+
+// maxL33tSubs 最多尝试的替换组合数，避免含大量替换字符的密码组合爆炸。
+const maxL33tSubs = 64
+
+// l33tMatch 还原 "p@ssw0rd" 这类替换后再查词典。
+func l33tMatch(pw []rune, dicts []namedDict) []*Match {
+	var matches []*Match
+	for _, sub := range enumerateL33tSubs(pw) {
+		translated := make([]rune, len(pw))
+		for i, r := range pw {
+			if letter, ok := sub[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+
+		for _, m := range dictionaryMatch(translated, dicts) {
+			token := pw[m.I : m.J+1]
+			// 没有实际替换的子串已由普通词典匹配覆盖；单字符替换噪音太大
+			if string(lowerRunes(token)) == m.MatchedWord || len(token) <= 1 {
+				continue
+			}
+			used := make(map[rune]rune)
+			for _, r := range token {
+				if letter, ok := sub[r]; ok {
+					used[r] = letter
+				}
+			}
+			m.Token = string(token)
+			m.L33t = true
+			m.Sub = used
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// enumerateL33tSubs 枚举密码中出现的替换字符到字母的所有映射组合。
+func enumerateL33tSubs(pw []rune) []map[rune]rune {
+	candidates := make(map[rune][]rune) // 替换字符 -> 可能的字母
+	for letter, subs := range l33tTable {
+		for _, s := range subs {
+			if slices.Contains(pw, s) && !slices.Contains(candidates[s], letter) {
+				candidates[s] = append(candidates[s], letter)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	keys := make([]rune, 0, len(candidates))
+	for k := range candidates {
+		keys = append(keys, k)
+		slices.Sort(candidates[k])
+	}
+	slices.Sort(keys)
+
+	subs := []map[rune]rune{{}}
+	for _, k := range keys {
+		var next []map[rune]rune
+		for _, s := range subs {
+			for _, letter := range candidates[k] {
+				if len(next) >= maxL33tSubs {
+					break
+				}
+				m := make(map[rune]rune, len(s)+1)
+				for kk, vv := range s {
+					m[kk] = vv
+				}
+				m[k] = letter
+				next = append(next, m)
+			}
+		}
+		subs = next
+	}
+	return subs
+}
+
+// ---- 键盘图案 ----
+
+// spatialMatch 查找在键盘上相邻的连续按键 (如 "qwerty"、"zxcvbn"、"7896321")。
+func spatialMatch(pw []rune) []*Match {
+	var matches []*Match
+	for _, g := range keyboardGraphs() {
+		matches = append(matches, spatialMatchGraph(pw, g)...)
+	}
+	return matches
+}
+
+func spatialMatchGraph(pw []rune, g *keyboardGraph) []*Match {
+	var matches []*Match
+	i := 0
+	for i < len(pw)-1 {
+		j := i + 1
+		lastDirection := -1
+		turns := 0
+		shifted := 0
+		if g.name != "keypad" && strings.ContainsRune(shiftedChars, pw[i]) {
+			shifted = 1
+		}
+
+		for {
+			found := false
+			if j < len(pw) {
+				for direction, adj := range g.adjacency[pw[j-1]] {
+					idx := strings.IndexRune(adj, pw[j])
+					if adj == "" || idx < 0 {
+						continue
+					}
+					found = true
+					// 每个按键的第二个字符需要按 Shift
+					if idx > 0 {
+						shifted++
+					}
+					if lastDirection != direction {
+						turns++
+						lastDirection = direction
+					}
+					break
+				}
+			}
+			if found {
+				j++
+				continue
+			}
+			// 至少 3 个字符才算键盘图案
+			if j-i > 2 {
+				matches = append(matches, &Match{
+					Pattern:      PatternSpatial,
+					I:            i,
+					J:            j - 1,
+					Token:        string(pw[i:j]),
+					Graph:        g.name,
+					Turns:        turns,
+					ShiftedCount: shifted,
+				})
+			}
+			i = j
+			break
+		}
+	}
+	return matches
+}
+
+// ---- 重复 ----
+
+// repeatMatch 查找重复的字符或片段 (如 "aaaa"、"abcabcabc")，基础片段的猜测次数递归估算。
+func repeatMatch(pw []rune, dicts []namedDict) []*Match {
+	var matches []*Match
+	for i := 0; i < len(pw); {
+		bestBase, bestCount := 0, 0
+		for base := 1; i+2*base <= len(pw); base++ {
+			count := 1
+			for i+(count+1)*base <= len(pw) && slices.Equal(pw[i+count*base:i+(count+1)*base], pw[i:i+base]) {
+				count++
+			}
+			if count >= 2 && base*count > bestBase*bestCount {
+				bestBase, bestCount = base, count
+			}
+		}
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		end := i + bestBase*bestCount
+		baseToken := pw[i : i+bestBase]
+		matches = append(matches, &Match{
+			Pattern:     PatternRepeat,
+			I:           i,
+			J:           end - 1,
+			Token:       string(pw[i:end]),
+			BaseToken:   string(baseToken),
+			BaseGuesses: mostGuessableSequence(baseToken, omnimatch(baseToken, dicts)).guesses,
+			RepeatCount: bestCount,
+		})
+		i = end
+	}
+	return matches
+}
+
+// ---- 序列 ----
+
+// maxSequenceDelta 相邻字符编码差值不超过该值时视为序列 (如 "abcd"、"9753"、"acegi")。
+const maxSequenceDelta = 5
+
+// sequenceMatch 查找字符编码等差的片段。
+func sequenceMatch(pw []rune) []*Match {
+	if len(pw) <= 1 {
+		return nil
+	}
+
+	var matches []*Match
+	update := func(i, j, delta int) {
+		if j-i > 1 || delta == 1 || delta == -1 {
+			if delta != 0 && delta >= -maxSequenceDelta && delta <= maxSequenceDelta {
+				matches = append(matches, &Match{
+					Pattern:   PatternSequence,
+					I:         i,
+					J:         j,
+					Token:     string(pw[i : j+1]),
+					Ascending: delta > 0,
+				})
+			}
+		}
+	}
+
+	i := 0
+	lastDelta := int(pw[1]) - int(pw[0])
+	for k := 2; k < len(pw); k++ {
+		delta := int(pw[k]) - int(pw[k-1])
+		if delta == lastDelta {
+			continue
+		}
+		update(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	update(i, len(pw)-1, lastDelta)
+	return matches
+}
+
+// ---- 日期和年份 ----
+
+const (
+	dateMinYear  = 1000
+	dateMaxYear  = 2050
+	minYearSpace = 20
+)
+
+var (
+	recentYearRe    = regexp.MustCompile(`19\d\d|20[0-4]\d|2050`)
+	dateWithSepRe   = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+	dateDigitsSplit = map[int][][2]int{
+		4: {{1, 2}, {2, 3}},
+		5: {{1, 3}, {2, 3}},
+		6: {{1, 2}, {2, 4}, {4, 5}},
+		7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+		8: {{2, 4}, {4, 6}},
+	}
+)
+
+// referenceYear 计算年份距离时的参考年份。
+func referenceYear() int {
+	return time.Now().Year()
+}
+
+// yearMatch 查找近代年份 (1900-2050)。
+func yearMatch(pw []rune) []*Match {
+	s := string(pw)
+	var matches []*Match
+	for _, loc := range recentYearRe.FindAllStringIndex(s, -1) {
+		i := len([]rune(s[:loc[0]]))
+		token := s[loc[0]:loc[1]]
+		year, _ := strconv.Atoi(token)
+		matches = append(matches, &Match{
+			Pattern: PatternYear,
+			I:       i,
+			J:       i + len(token) - 1,
+			Token:   token,
+			Year:    year,
+		})
+	}
+	return matches
+}
+
+// dateMatch 查找日期: 4-8 位纯数字 (如 "13091985"、"1985913") 或带分隔符 (如 "13/9/1985"、"1985-09-13")。
+// 被其他日期完全包含的较短日期会被过滤。
+func dateMatch(pw []rune) []*Match {
+	var matches []*Match
+
+	// 无分隔符
+	for i := 0; i+4 <= len(pw); i++ {
+		for j := i + 3; j < i+8 && j < len(pw); j++ {
+			token := string(pw[i : j+1])
+			if !isDigits(token) {
+				continue
+			}
+			var best *Match
+			for _, split := range dateDigitsSplit[len(token)] {
+				a, _ := strconv.Atoi(token[:split[0]])
+				b, _ := strconv.Atoi(token[split[0]:split[1]])
+				c, _ := strconv.Atoi(token[split[1]:])
+				y, m, d, ok := mapIntsToDMY(a, b, c)
+				if !ok {
+					continue
+				}
+				if best == nil || abs(y-referenceYear()) < abs(best.Year-referenceYear()) {
+					best = &Match{Pattern: PatternDate, I: i, J: j, Token: token, Year: y, Month: m, Day: d}
+				}
+			}
+			if best != nil {
+				matches = append(matches, best)
+			}
+		}
+	}
+
+	// 带分隔符
+	for i := 0; i+6 <= len(pw); i++ {
+		for j := i + 5; j < i+10 && j < len(pw); j++ {
+			token := string(pw[i : j+1])
+			g := dateWithSepRe.FindStringSubmatch(token)
+			if g == nil || g[2] != g[4] {
+				continue
+			}
+			a, _ := strconv.Atoi(g[1])
+			b, _ := strconv.Atoi(g[3])
+			c, _ := strconv.Atoi(g[5])
+			if y, m, d, ok := mapIntsToDMY(a, b, c); ok {
+				matches = append(matches, &Match{Pattern: PatternDate, I: i, J: j, Token: token, Separator: g[2], Year: y, Month: m, Day: d})
+			}
+		}
+	}
+
+	// 过滤被其他日期包含的子匹配
+	var filtered []*Match
+	for _, m := range matches {
+		contained := false
+		for _, o := range matches {
+			if o != m && o.I <= m.I && o.J >= m.J && (o.I != m.I || o.J != m.J) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// mapIntsToDMY 将三个整数解释为 年-月-日 (年份可以在开头或结尾，两位年份补全为 19xx/20xx)。
+func mapIntsToDMY(a, b, c int) (year, month, day int, ok bool) {
+	ints := [3]int{a, b, c}
+	if b > 31 || b <= 0 {
+		return 0, 0, 0, false
+	}
+	over12, over31, under1 := 0, 0, 0
+	for _, n := range ints {
+		if (n > 99 && n < dateMinYear) || n > dateMaxYear {
+			return 0, 0, 0, false
+		}
+		if n > 31 {
+			over31++
+		}
+		if n > 12 {
+			over12++
+		}
+		if n <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return 0, 0, 0, false
+	}
+
+	splits := [][3]int{{c, a, b}, {a, b, c}} // 年份, 其余两个
+	for _, s := range splits {
+		if s[0] >= dateMinYear && s[0] <= dateMaxYear {
+			m, d, ok := mapIntsToDM(s[1], s[2])
+			return s[0], m, d, ok
+		}
+	}
+	for _, s := range splits {
+		if m, d, ok := mapIntsToDM(s[1], s[2]); ok {
+			return twoToFourDigitYear(s[0]), m, d, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+func mapIntsToDM(a, b int) (month, day int, ok bool) {
+	for _, p := range [][2]int{{a, b}, {b, a}} {
+		d, m := p[0], p[1]
+		if d >= 1 && d <= 31 && m >= 1 && m <= 12 {
+			return m, d, true
+		}
+	}
+	return 0, 0, false
+}
+
+func twoToFourDigitYear(year int) int {
+	switch {
+	case year > 99:
+		return year
+	case year > 50:
+		return year + 1900
+	default:
+		return year + 2000
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
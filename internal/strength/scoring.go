@@ -0,0 +1,306 @@
+package strength
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 猜测次数估算的常量 (与 zxcvbn 一致)
+const (
+	bruteforceCardinality    = 10
+	minGuessesBeforeGrowing  = 10000
+	minSubmatchGuessesSingle = 10
+	minSubmatchGuessesMulti  = 50
+	maxPasswordRunes         = 100
+)
+
+// guessesFor 估算单个匹配的猜测次数 (结果缓存在 Match.Guesses)。
+// 子匹配 (不覆盖整个密码) 有最低值，避免个别很短的片段拉低整体估计。
+func guessesFor(m *Match, passwordLen int) float64 {
+	if m.Guesses != 0 {
+		return m.Guesses
+	}
+
+	tokenLen := m.J - m.I + 1
+	minGuesses := 1.0
+	if tokenLen < passwordLen {
+		if tokenLen == 1 {
+			minGuesses = minSubmatchGuessesSingle
+		} else {
+			minGuesses = minSubmatchGuessesMulti
+		}
+	}
+
+	var g float64
+	switch m.Pattern {
+	case PatternDictionary:
+		g = dictionaryGuesses(m)
+	case PatternSpatial:
+		g = spatialGuesses(m)
+	case PatternRepeat:
+		g = m.BaseGuesses * float64(m.RepeatCount)
+	case PatternSequence:
+		g = sequenceGuesses(m)
+	case PatternYear:
+		g = math.Max(float64(abs(m.Year-referenceYear())), minYearSpace)
+	case PatternDate:
+		g = math.Max(float64(abs(m.Year-referenceYear())), minYearSpace) * 365
+		if m.Separator != "" {
+			g *= 4
+		}
+	case PatternBruteforce:
+		g = bruteforceGuesses(m)
+	}
+	m.Guesses = math.Max(g, minGuesses)
+	return m.Guesses
+}
+
+func bruteforceGuesses(m *Match) float64 {
+	n := len([]rune(m.Token))
+	g := math.Pow(bruteforceCardinality, float64(n))
+	if math.IsInf(g, 1) {
+		g = math.MaxFloat64
+	}
+	// 与子匹配最低值保持一致，避免暴力破解片段比相同长度的模式更"便宜"
+	if n == 1 {
+		return math.Max(g, minSubmatchGuessesSingle+1)
+	}
+	return math.Max(g, minSubmatchGuessesMulti+1)
+}
+
+func dictionaryGuesses(m *Match) float64 {
+	g := float64(m.Rank) * uppercaseVariations(m.Token) * l33tVariations(m)
+	if m.Reversed {
+		g *= 2
+	}
+	return g
+}
+
+// uppercaseVariations 大小写变化带来的额外猜测次数: 全小写 ×1，首字母/末字母/全大写 ×2，其他按组合数计算。
+func uppercaseVariations(token string) float64 {
+	var upper, lower int
+	for _, r := range token {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	runes := []rune(token)
+	first, last := unicode.IsUpper(runes[0]), unicode.IsUpper(runes[len(runes)-1])
+	if lower == 0 || (upper == 1 && (first || last)) {
+		return 2
+	}
+	var variations float64
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += nCk(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations 字符替换带来的额外猜测次数。
+func l33tVariations(m *Match) float64 {
+	if !m.L33t {
+		return 1
+	}
+	variations := 1.0
+	for subbed, letter := range m.Sub {
+		var s, u int
+		for _, r := range strings.ToLower(m.Token) {
+			switch r {
+			case subbed:
+				s++
+			case letter:
+				u++
+			}
+		}
+		if s == 0 || u == 0 {
+			// 全部替换或全部未替换: 攻击者只需尝试两种形式
+			variations *= 2
+			continue
+		}
+		var possibilities float64
+		for i := 1; i <= min(s, u); i++ {
+			possibilities += nCk(s+u, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+func spatialGuesses(m *Match) float64 {
+	var startingKeys, degree float64
+	for _, g := range keyboardGraphs() {
+		if g.name == m.Graph {
+			startingKeys, degree = g.startingKeys, g.averageDegree
+			break
+		}
+	}
+
+	l := len([]rune(m.Token))
+	var guesses float64
+	for i := 2; i <= l; i++ {
+		for j := 1; j <= min(m.Turns, i-1); j++ {
+			guesses += nCk(i-1, j-1) * startingKeys * math.Pow(degree, float64(j))
+		}
+	}
+	if m.ShiftedCount > 0 {
+		unshifted := l - m.ShiftedCount
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			var variations float64
+			for i := 1; i <= min(m.ShiftedCount, unshifted); i++ {
+				variations += nCk(m.ShiftedCount+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+func sequenceGuesses(m *Match) float64 {
+	first := []rune(m.Token)[0]
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		// 从常见起点开始的序列最容易被猜到
+		base = 4
+	case first >= '0' && first <= '9':
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.Ascending {
+		base *= 2
+	}
+	return base * float64(len([]rune(m.Token)))
+}
+
+// nCk 组合数
+func nCk(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	if k == 0 {
+		return 1
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r *= float64(n)
+		r /= float64(d)
+		n--
+	}
+	return r
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+// guessResult 最容易被猜到的匹配序列及其猜测次数。
+type guessResult struct {
+	guesses  float64
+	sequence []*Match
+}
+
+// mostGuessableSequence 动态规划: 在所有匹配 (及暴力破解片段) 中找出覆盖整个密码、总猜测次数最少的组合。
+//
+// 核心逻辑:
+// 1. 长度为 l 的组合总猜测次数 = l! × Π(各匹配猜测次数) + 10000^(l-1)
+// 2. l! 代表攻击者不知道各模式的排列顺序，10000^(l-1) 惩罚由很多片段拼接的组合
+// 3. 未被任何模式覆盖的字符按暴力破解计算
+func mostGuessableSequence(pw []rune, matches []*Match) guessResult {
+	n := len(pw)
+	if n == 0 {
+		return guessResult{guesses: 1}
+	}
+
+	byEnd := make([][]*Match, n)
+	for _, m := range matches {
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+
+	// optimal[k][l]: 覆盖前 k+1 个字符、由 l 个匹配组成的最优组合
+	type state struct {
+		m    *Match
+		pi   float64 // 各匹配猜测次数之积
+		g    float64 // 总猜测次数
+		prev *state
+	}
+	optimal := make([]map[int]*state, n)
+	for k := range optimal {
+		optimal[k] = make(map[int]*state)
+	}
+
+	update := func(m *Match, l int) {
+		k := m.J
+		pi := guessesFor(m, n)
+		var prev *state
+		if l > 1 {
+			prev = optimal[m.I-1][l-1]
+			pi *= prev.pi
+		}
+		g := factorial(l)*pi + math.Pow(minGuessesBeforeGrowing, float64(l-1))
+		// 同一终点下，较短的组合若猜测次数不更高则更优
+		for otherL, other := range optimal[k] {
+			if otherL <= l && other.g <= g {
+				return
+			}
+		}
+		optimal[k][l] = &state{m: m, pi: pi, g: g, prev: prev}
+	}
+
+	bruteforce := func(i, j int) *Match {
+		return &Match{Pattern: PatternBruteforce, I: i, J: j, Token: string(pw[i : j+1])}
+	}
+
+	bruteforceUpdate := func(k int) {
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforce(i, k)
+			for l, last := range optimal[i-1] {
+				// 相邻两个暴力破解片段合并为一个更优
+				if last.m.Pattern == PatternBruteforce {
+					continue
+				}
+				update(m, l+1)
+			}
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.I > 0 {
+				for l := range optimal[m.I-1] {
+					update(m, l+1)
+				}
+			} else {
+				update(m, 1)
+			}
+		}
+		bruteforceUpdate(k)
+	}
+
+	// 按长度顺序比较，猜测次数相同时取片段更少的组合，保证结果稳定
+	var best *state
+	for l := 1; l <= n; l++ {
+		if s, ok := optimal[n-1][l]; ok && (best == nil || s.g < best.g) {
+			best = s
+		}
+	}
+
+	var sequence []*Match
+	for s := best; s != nil; s = s.prev {
+		sequence = append([]*Match{s.m}, sequence...)
+	}
+	return guessResult{guesses: best.g, sequence: sequence}
+}
@@ -0,0 +1,146 @@
+package strength
+
+import (
+	"fmt"
+	"math"
+)
+
+// Score 强度等级 0-4
+const (
+	ScoreVeryWeak = iota
+	ScoreWeak
+	ScoreFair
+	ScoreStrong
+	ScoreVeryStrong
+)
+
+// 离线/在线攻击场景 (每秒猜测次数)
+const (
+	OnlineThrottled   = 100.0 / 3600 // 在线攻击，有频率限制 (每小时 100 次)
+	OnlineUnthrottled = 10.0         // 在线攻击，无频率限制
+	OfflineSlowHash   = 1e4          // 离线攻击，慢哈希 (bcrypt/scrypt/Argon2)
+	OfflineFastHash   = 1e10         // 离线攻击，快哈希 (MD5/SHA1)，多 GPU
+)
+
+// CrackTimes 各攻击场景下的预计破解时间 (秒)。
+type CrackTimes struct {
+	OnlineThrottled   float64
+	OnlineUnthrottled float64
+	OfflineSlowHash   float64
+	OfflineFastHash   float64
+}
+
+// Result 强度评估结果。
+type Result struct {
+	// Score 0 非常弱 … 4 非常强
+	Score        int
+	Guesses      float64
+	GuessesLog10 float64
+	CrackTimes   CrackTimes
+	// Warning 主要问题 (可能为空)
+	Warning     string
+	Suggestions []string
+	// Sequence 覆盖整个密码、最容易被猜到的模式组合
+	Sequence []*Match
+}
+
+// Estimate 估算密码强度 (zxcvbn 算法)。
+//
+// 核心逻辑:
+// 1. 用词典、l33t 替换、倒序、键盘图案、重复、序列、日期等匹配器找出所有可识别的片段
+// 2. 动态规划选出总猜测次数最少的片段组合，作为攻击者所需的猜测次数
+// 3. 由猜测次数换算等级和各场景破解时间，并根据最主要的模式给出建议
+//
+// userInputs 为与该密码相关的信息 (网站名、用户名、邮箱等)，密码中包含它们会被视为极弱。
+// 只计算前 100 个字符，更长的部分对结果几乎没有影响。
+func Estimate(password string, userInputs ...string) *Result {
+	pw := []rune(password)
+	if len(pw) > maxPasswordRunes {
+		pw = pw[:maxPasswordRunes]
+	}
+
+	dicts := builtinDicts()
+	if words := userInputsDict(userInputs); len(words) > 0 {
+		dicts = append(append([]namedDict(nil), dicts...), namedDict{name: DictUserInputs, words: words})
+	}
+
+	best := mostGuessableSequence(pw, omnimatch(pw, dicts))
+	guesses := best.guesses
+	res := &Result{
+		Score:        guessesToScore(guesses),
+		Guesses:      guesses,
+		GuessesLog10: math.Log10(guesses),
+		CrackTimes: CrackTimes{
+			OnlineThrottled:   guesses / OnlineThrottled,
+			OnlineUnthrottled: guesses / OnlineUnthrottled,
+			OfflineSlowHash:   guesses / OfflineSlowHash,
+			OfflineFastHash:   guesses / OfflineFastHash,
+		},
+		Sequence: best.sequence,
+	}
+	res.Warning, res.Suggestions = feedback(res.Score, best.sequence)
+	return res
+}
+
+// guessesToScore 猜测次数阈值: 1e3、1e6、1e8、1e10 (+5 的余量避免恰好落在边界的模式被高估)。
+func guessesToScore(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return ScoreVeryWeak
+	case guesses < 1e6+delta:
+		return ScoreWeak
+	case guesses < 1e8+delta:
+		return ScoreFair
+	case guesses < 1e10+delta:
+		return ScoreStrong
+	default:
+		return ScoreVeryStrong
+	}
+}
+
+// ScoreLabel 强度等级的中文名称。
+func ScoreLabel(score int) string {
+	switch score {
+	case ScoreVeryWeak:
+		return "非常弱"
+	case ScoreWeak:
+		return "弱"
+	case ScoreFair:
+		return "一般"
+	case ScoreStrong:
+		return "强"
+	default:
+		return "非常强"
+	}
+}
+
+// DisplayTime 将秒数格式化为易读的中文时长。
+func DisplayTime(seconds float64) string {
+	const (
+		minute  = 60.0
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+	switch {
+	case seconds < 1:
+		return "不到 1 秒"
+	case seconds < minute:
+		return fmt.Sprintf("%.0f 秒", seconds)
+	case seconds < hour:
+		return fmt.Sprintf("%.0f 分钟", seconds/minute)
+	case seconds < day:
+		return fmt.Sprintf("%.0f 小时", seconds/hour)
+	case seconds < month:
+		return fmt.Sprintf("%.0f 天", seconds/day)
+	case seconds < year:
+		return fmt.Sprintf("%.0f 个月", seconds/month)
+	case seconds < century:
+		return fmt.Sprintf("%.0f 年", seconds/year)
+	default:
+		return "数百年以上"
+	}
+}
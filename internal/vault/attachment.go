@@ -0,0 +1,216 @@
+package vault
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"key-box/internal/crypto"
+	"key-box/internal/db"
+)
+
+// infoAttachmentKeyWrap 由 Key C 派生附件密钥包装密钥的 HKDF info
+const infoAttachmentKeyWrap = "attachment-key-wrap"
+
+// ErrAttachmentTooLarge 附件超过 AttachmentLimit
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// Attachment 条目的附件 (已解密的元数据，不含内容)。
+type Attachment struct {
+	ID     int
+	ItemID int
+	Name   string
+	// Size 明文字节数
+	Size      int64
+	CreatedAt time.Time
+}
+
+// attachmentMeta 附件中被 Key C 加密的元数据。
+// 安全决策: 文件名和所属条目一起加密，附件记录被移到其他条目下时解密后可以发现。
+type attachmentMeta struct {
+	ItemID int    `json:"item_id"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+}
+
+// AddAttachment 从 r 流式读取文件内容，加密后作为条目 itemID 的附件保存。
+// 核心逻辑:
+//  1. 生成随机文件密钥，用 Key C 派生的包装密钥加密后存储。
+//  2. 按 crypto.StreamChunkSize 分块读取并加密写入，内存中只保留当前块和下一块。
+//  3. 超过 AttachmentLimit 时放弃，整个写入在一个事务中，失败不会留下残缺的附件。
+func (m *Manager) AddAttachment(username string, keyC []byte, itemID int, name string, r io.Reader) (*Attachment, error) {
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		return nil, errors.New("attachment name is empty")
+	}
+
+	fileKey, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	wrapKey, err := crypto.DeriveSubKey(keyC, infoAttachmentKeyWrap)
+	if err != nil {
+		return nil, err
+	}
+	encKey, err := crypto.EncryptAESGCM(wrapKey, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := crypto.NewStreamCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	att := &Attachment{ItemID: itemID, Name: name, CreatedAt: time.Now().UTC()}
+	err = m.db.WithTx(func(tx *sql.Tx) error {
+		id, err := db.InsertAttachment(tx, itemID, username, encKey)
+		if err != nil {
+			return err
+		}
+		att.ID = id
+
+		// 预读下一块，以便知道当前块是否是最后一块
+		cur, err := readChunk(r)
+		if err != nil {
+			return err
+		}
+		for seq := 0; ; seq++ {
+			att.Size += int64(len(cur))
+			if att.Size > m.AttachmentLimit {
+				return fmt.Errorf("%w (limit %d MiB)", ErrAttachmentTooLarge, m.AttachmentLimit>>20)
+			}
+			var next []byte
+			if len(cur) == crypto.StreamChunkSize {
+				if next, err = readChunk(r); err != nil {
+					return err
+				}
+			}
+			last := len(next) == 0
+			sealed, err := stream.Seal(cur, last)
+			if err != nil {
+				return err
+			}
+			if err := db.InsertAttachmentChunk(tx, att.ID, seq, username, sealed); err != nil {
+				return err
+			}
+			if last {
+				break
+			}
+			cur = next
+		}
+
+		meta, err := json.Marshal(attachmentMeta{ItemID: itemID, Name: name, Size: att.Size})
+		if err != nil {
+			return err
+		}
+		encMeta, err := crypto.EncryptAESGCM(keyC, meta)
+		if err != nil {
+			return err
+		}
+		return db.SetAttachmentMeta(tx, att.ID, encMeta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+// readChunk 读取最多一块，文件结束时返回空切片。
+func readChunk(r io.Reader) ([]byte, error) {
+	buf := make([]byte, crypto.StreamChunkSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+// ListAttachments 读取并解密条目的附件列表。
+func (m *Manager) ListAttachments(keyC []byte, itemID int) ([]Attachment, error) {
+	rows, err := m.db.GetAttachments(itemID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Attachment, 0, len(rows))
+	for _, row := range rows {
+		meta, err := decryptAttachmentMeta(keyC, &row)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Attachment{ID: row.ID, ItemID: row.ItemID, Name: meta.Name, Size: meta.Size, CreatedAt: row.CreatedAt})
+	}
+	return list, nil
+}
+
+// ReadAttachment 逐块解密附件内容并写入 w。
+// 内容被篡改、调换顺序或截断时返回错误，此时 w 中可能已写入部分内容，调用方应丢弃。
+func (m *Manager) ReadAttachment(keyC []byte, id int, w io.Writer) error {
+	row, err := m.db.GetAttachment(id)
+	if err != nil {
+		return err
+	}
+	meta, err := decryptAttachmentMeta(keyC, row)
+	if err != nil {
+		return err
+	}
+	wrapKey, err := crypto.DeriveSubKey(keyC, infoAttachmentKeyWrap)
+	if err != nil {
+		return err
+	}
+	fileKey, err := crypto.DecryptAESGCM(wrapKey, row.EncKey)
+	if err != nil {
+		return fmt.Errorf("attachment %d: failed to decrypt key: %v", id, err)
+	}
+	stream, err := crypto.NewStreamCipher(fileKey)
+	if err != nil {
+		return err
+	}
+
+	var written int64
+	err = m.db.ReadAttachmentChunks(id, func(data []byte, last bool) error {
+		plain, err := stream.Open(data, last)
+		if err != nil {
+			return fmt.Errorf("attachment %d: chunk authentication failed", id)
+		}
+		written += int64(len(plain))
+		_, err = w.Write(plain)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if written != meta.Size {
+		return fmt.Errorf("attachment %d: size mismatch", id)
+	}
+	return nil
+}
+
+// DeleteAttachment 删除附件，并释放空闲页使密文不残留在数据库文件中。
+func (m *Manager) DeleteAttachment(id int) error {
+	if err := m.db.DeleteAttachment(id); err != nil {
+		return err
+	}
+	return m.db.IncrementalVacuum()
+}
+
+func decryptAttachmentMeta(keyC []byte, row *db.Attachment) (*attachmentMeta, error) {
+	if row.EncMeta == nil {
+		return nil, fmt.Errorf("attachment %d is incomplete", row.ID)
+	}
+	decrypted, err := crypto.DecryptAESGCM(keyC, row.EncMeta)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %d: failed to decrypt: %v", row.ID, err)
+	}
+	var meta attachmentMeta
+	if err := json.Unmarshal(decrypted, &meta); err != nil {
+		return nil, fmt.Errorf("attachment %d: failed to unmarshal: %v", row.ID, err)
+	}
+	if meta.ItemID != row.ItemID {
+		return nil, fmt.Errorf("attachment %d does not belong to item %d", row.ID, row.ItemID)
+	}
+	return &meta, nil
+}
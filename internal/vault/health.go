@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"time"
+
+	"key-box/internal/strength"
+)
+
+// HealthOptions 密码库体检的阈值。
+type HealthOptions struct {
+	// MaxAgeMonths 超过该月数未修改的条目视为过期，0 表示不检查
+	MaxAgeMonths int `json:"max_age_months"`
+	// MinScore 强度等级 (0-4) 低于该值视为弱密码
+	MinScore int `json:"min_score"`
+}
+
+// DefaultHealthOptions 默认: 12 个月未修改为过期，强度低于 "强" (3) 为弱密码。
+func DefaultHealthOptions() HealthOptions {
+	return HealthOptions{MaxAgeMonths: 12, MinScore: strength.ScoreStrong}
+}
+
+// ItemRef 报告中引用的条目 (不含密码)。
+type ItemRef struct {
+	ID        int       `json:"id"`
+	Site      string    `json:"site"`
+	Username  string    `json:"username"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReusedGroup 使用同一密码的一组条目。
+type ReusedGroup struct {
+	Sites []string  `json:"sites"`
+	Items []ItemRef `json:"items"`
+}
+
+// WeakItem 强度不足的条目。
+type WeakItem struct {
+	ItemRef
+	Score   int    `json:"score"`
+	Label   string `json:"label"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// OldItem 长时间未修改的条目。
+type OldItem struct {
+	ItemRef
+	AgeDays int `json:"age_days"`
+}
+
+// DuplicateGroup 网站和用户名都相同的一组条目。
+type DuplicateGroup struct {
+	Site     string    `json:"site"`
+	Username string    `json:"username"`
+	Items    []ItemRef `json:"items"`
+}
+
+// HealthReport 密码库体检结果。报告中不包含任何密码明文或哈希，可以安全地输出或保存。
+type HealthReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Options     HealthOptions    `json:"options"`
+	Total       int              `json:"total"`
+	Reused      []ReusedGroup    `json:"reused"`
+	Weak        []WeakItem       `json:"weak"`
+	Old         []OldItem        `json:"old"`
+	Duplicates  []DuplicateGroup `json:"duplicates"`
+}
+
+// Issues 发现的问题总数 (重复使用按组计)。
+func (r *HealthReport) Issues() int {
+	return len(r.Reused) + len(r.Weak) + len(r.Old) + len(r.Duplicates)
+}
+
+// HealthReport 用 Key C 解密全部条目并生成体检报告。
+func (m *Manager) HealthReport(username string, keyC []byte, opts HealthOptions) (*HealthReport, error) {
+	items, err := m.ListItems(username, keyC)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeHealth(items, opts, time.Now()), nil
+}
+
+// AnalyzeHealth 检查已解密的条目。
+// 核心逻辑:
+// 1. 重复使用: 按密码的 SHA-256 分组 (仅在内存中，用于比较)，两个及以上条目共用同一密码即报告。
+// 2. 弱密码: 以网站名和用户名作为额外词典估算强度，低于 MinScore 即报告。
+// 3. 过期: vault.updated_at 早于 now 往前 MaxAgeMonths 个月。
+// 4. 重复条目: 网站 (不区分大小写) 和用户名都相同的条目。
+func AnalyzeHealth(items []VaultItem, opts HealthOptions, now time.Time) *HealthReport {
+	report := &HealthReport{
+		GeneratedAt: now,
+		Options:     opts,
+		Total:       len(items),
+		// JSON 中以空数组而不是 null 表示没有问题
+		Reused:     []ReusedGroup{},
+		Weak:       []WeakItem{},
+		Old:        []OldItem{},
+		Duplicates: []DuplicateGroup{},
+	}
+
+	byPassword := make(map[[32]byte][]ItemRef)
+	var passwordOrder [][32]byte
+	byAccount := make(map[string][]ItemRef)
+	var accountOrder []string
+	cutoff := now.AddDate(0, -opts.MaxAgeMonths, 0)
+
+	for _, item := range items {
+		// 安全笔记没有密码，不参与体检
+		if item.IsNote() {
+			continue
+		}
+		ref := ItemRef{ID: item.ID, Site: item.Site, Username: item.Username, UpdatedAt: item.UpdatedAt}
+
+		if item.Password != "" {
+			sum := sha256.Sum256([]byte(item.Password))
+			if _, ok := byPassword[sum]; !ok {
+				passwordOrder = append(passwordOrder, sum)
+			}
+			byPassword[sum] = append(byPassword[sum], ref)
+
+			res := strength.Estimate(item.Password, item.Site, item.Username)
+			if res.Score < opts.MinScore {
+				report.Weak = append(report.Weak, WeakItem{
+					ItemRef: ref,
+					Score:   res.Score,
+					Label:   strength.ScoreLabel(res.Score),
+					Warning: res.Warning,
+				})
+			}
+		}
+
+		if opts.MaxAgeMonths > 0 && !item.UpdatedAt.IsZero() && item.UpdatedAt.Before(cutoff) {
+			report.Old = append(report.Old, OldItem{
+				ItemRef: ref,
+				AgeDays: int(now.Sub(item.UpdatedAt).Hours() / 24),
+			})
+		}
+
+		key := strings.ToLower(strings.TrimSpace(item.Site)) + "\x00" + item.Username
+		if _, ok := byAccount[key]; !ok {
+			accountOrder = append(accountOrder, key)
+		}
+		byAccount[key] = append(byAccount[key], ref)
+	}
+
+	for _, sum := range passwordOrder {
+		refs := byPassword[sum]
+		if len(refs) < 2 {
+			continue
+		}
+		group := ReusedGroup{Items: refs}
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			if !seen[ref.Site] {
+				seen[ref.Site] = true
+				group.Sites = append(group.Sites, ref.Site)
+			}
+		}
+		sort.Strings(group.Sites)
+		report.Reused = append(report.Reused, group)
+	}
+	// 受影响条目最多的组排在前面
+	sort.SliceStable(report.Reused, func(i, j int) bool {
+		return len(report.Reused[i].Items) > len(report.Reused[j].Items)
+	})
+
+	for _, key := range accountOrder {
+		refs := byAccount[key]
+		if len(refs) < 2 {
+			continue
+		}
+		report.Duplicates = append(report.Duplicates, DuplicateGroup{
+			Site:     refs[0].Site,
+			Username: refs[0].Username,
+			Items:    refs,
+		})
+	}
+
+	// 最弱、最久未修改的排在前面
+	sort.SliceStable(report.Weak, func(i, j int) bool { return report.Weak[i].Score < report.Weak[j].Score })
+	sort.SliceStable(report.Old, func(i, j int) bool { return report.Old[i].AgeDays > report.Old[j].AgeDays })
+
+	return report
+}
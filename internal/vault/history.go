@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"key-box/internal/crypto"
+)
+
+// HistoryEntry 条目的一个历史版本 (已解密)。
+type HistoryEntry struct {
+	ID       int
+	ItemID   int
+	Site     string
+	Username string
+	Password string
+	Notes    string
+	// SavedAt 该版本的写入时间，ArchivedAt 被新版本替换的时间
+	SavedAt    time.Time
+	ArchivedAt time.Time
+}
+
+// ListHistory 读取并解密条目的历史版本 (最新的在前)。
+func (m *Manager) ListHistory(keyC []byte, itemID int) ([]HistoryEntry, error) {
+	rows, err := m.db.GetItemHistory(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		data, err := decryptItemData(keyC, row.EncData)
+		if err != nil {
+			return nil, fmt.Errorf("history %d: %v", row.ID, err)
+		}
+		entries = append(entries, HistoryEntry{
+			ID:         row.ID,
+			ItemID:     row.ItemID,
+			Site:       row.Site,
+			Username:   data.Username,
+			Password:   data.Password,
+			Notes:      data.Notes,
+			SavedAt:    row.SavedAt,
+			ArchivedAt: row.ArchivedAt,
+		})
+	}
+	return entries, nil
+}
+
+// RevertItem 将条目恢复为指定的历史版本。
+// 恢复本身也是一次更新: 当前内容会先归档，因此恢复操作可以再次撤销。
+func (m *Manager) RevertItem(keyC []byte, itemID, historyID int) error {
+	row, err := m.db.GetItemHistoryEntry(historyID)
+	if err != nil {
+		return err
+	}
+	if row.ItemID != itemID {
+		return fmt.Errorf("history %d does not belong to item %d", historyID, itemID)
+	}
+	data, err := decryptItemData(keyC, row.EncData)
+	if err != nil {
+		return err
+	}
+	return m.UpdateItem(keyC, newVaultItem(itemID, row.Site, time.Time{}, data))
+}
+
+func decryptItemData(keyC, encData []byte) (*ItemData, error) {
+	decrypted, err := crypto.DecryptAESGCM(keyC, encData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %v", err)
+	}
+	var data ItemData
+	if err := json.Unmarshal(decrypted, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %v", err)
+	}
+	return &data, nil
+}
@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+)
+
+// FolderSeparator 文件夹路径的层级分隔符
+const FolderSeparator = "/"
+
+// NormalizeFolder 规范化文件夹路径: 去掉各级首尾空白和空的层级。
+// 例如 " 工作 // 服务器/ " 变为 "工作/服务器"，空字符串表示不在任何文件夹中。
+func NormalizeFolder(path string) string {
+	var parts []string
+	for _, p := range strings.Split(path, FolderSeparator) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, FolderSeparator)
+}
+
+// NormalizeTags 去掉空白和空标签，按不区分大小写去重 (保留第一次出现的写法) 并排序。
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var out []string
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		key := strings.ToLower(t)
+		if t == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i]) < strings.ToLower(out[j]) })
+	return out
+}
+
+// ParseTags 解析以逗号分隔的标签 (同时接受中文逗号)。
+func ParseTags(s string) []string {
+	return NormalizeTags(strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' }))
+}
+
+// InFolder 条目是否在 folder 或其子文件夹中。folder 为空时匹配所有条目。
+func (item VaultItem) InFolder(folder string) bool {
+	folder = NormalizeFolder(folder)
+	if folder == "" {
+		return true
+	}
+	f := NormalizeFolder(item.Folder)
+	return f == folder || strings.HasPrefix(f, folder+FolderSeparator)
+}
+
+// HasTag 条目是否带有标签 tag (不区分大小写)。
+func (item VaultItem) HasTag(tag string) bool {
+	for _, t := range item.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Folders 返回条目用到的所有文件夹路径，包括各级父文件夹，按路径排序。
+func Folders(items []VaultItem) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, item := range items {
+		parts := strings.Split(NormalizeFolder(item.Folder), FolderSeparator)
+		for i := range parts {
+			p := strings.Join(parts[:i+1], FolderSeparator)
+			if p == "" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Tags 返回条目用到的所有标签 (不区分大小写去重) 及各标签的条目数。
+func Tags(items []VaultItem) ([]string, map[string]int) {
+	var all []string
+	for _, item := range items {
+		all = append(all, item.Tags...)
+	}
+	tags := NormalizeTags(all)
+	counts := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		for _, item := range items {
+			if item.HasTag(tag) {
+				counts[tag]++
+			}
+		}
+	}
+	return tags, counts
+}
+
+// ItemFilter 按文件夹、标签和收藏筛选条目，空值表示不限。
+type ItemFilter struct {
+	Folder    string
+	Tag       string
+	Favorites bool
+}
+
+// Match 条目是否满足所有条件。
+func (f ItemFilter) Match(item VaultItem) bool {
+	if f.Favorites && !item.Favorite {
+		return false
+	}
+	if f.Tag != "" && !item.HasTag(f.Tag) {
+		return false
+	}
+	return item.InFolder(f.Folder)
+}
+
+// Apply 返回满足条件的条目。
+func (f ItemFilter) Apply(items []VaultItem) []VaultItem {
+	var out []VaultItem
+	for _, item := range items {
+		if f.Match(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,368 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchField 搜索语句中可以指定的字段。
+type SearchField string
+
+const (
+	FieldSite   SearchField = "site"
+	FieldUser   SearchField = "user"
+	FieldURL    SearchField = "url"
+	FieldTag    SearchField = "tag"
+	FieldFolder SearchField = "folder"
+	FieldNotes  SearchField = "note"
+)
+
+// searchFieldNames 字段名及别名
+var searchFieldNames = map[string]SearchField{
+	"site": FieldSite, "title": FieldSite,
+	"user": FieldUser, "username": FieldUser,
+	"url": FieldURL, "uri": FieldURL,
+	"tag":    FieldTag,
+	"folder": FieldFolder,
+	"note":   FieldNotes, "notes": FieldNotes,
+}
+
+// fieldWeights 字段权重 (百分比)，同样的匹配程度下网站名最重要
+var fieldWeights = map[SearchField]int{
+	FieldSite:   100,
+	FieldUser:   80,
+	FieldURL:    70,
+	FieldTag:    70,
+	FieldFolder: 60,
+	FieldNotes:  50,
+}
+
+// defaultFields 未指定字段的词查找的字段。
+// 安全决策: 笔记内容只在 note: 中查找，避免输入的词命中笔记中的秘密而暴露笔记内容。
+var defaultFields = []SearchField{FieldSite, FieldUser, FieldURL, FieldTag, FieldFolder}
+
+// SearchTerm 搜索语句中的一个词，Field 为空时查找 defaultFields。
+type SearchTerm struct {
+	Field SearchField
+	Text  string
+}
+
+// SearchQuery 解析后的搜索语句。
+type SearchQuery struct {
+	Terms []SearchTerm
+	// Favorites 只包含收藏 (is:fav)，Type 只包含该类型 (is:note / is:login)
+	Favorites bool
+	Type      ItemType
+}
+
+// ParseSearchQuery 解析搜索语句。
+// 语法:
+//   - github                   在网站、账号、网址、标签和文件夹中模糊查找
+//   - user:alice tag:work      只在指定字段中查找，字段为 site (title)、user、url、tag、folder、note
+//   - is:fav / is:note / is:login  只包含收藏 / 安全笔记 / 登录条目
+//   - "two words" 或 user:"a b"    引号内作为一个词
+//
+// 多个词必须同时匹配。未知的字段名 (如 "https://...") 按普通词处理，只有字段名没有内容时忽略 (正在输入)。
+func ParseSearchQuery(s string) SearchQuery {
+	var q SearchQuery
+	for _, token := range tokenizeQuery(s) {
+		term := SearchTerm{Text: token}
+		if name, value, ok := strings.Cut(token, ":"); ok {
+			name = strings.ToLower(name)
+			if name == "is" {
+				switch strings.ToLower(value) {
+				case "fav", "favorite", "favourite":
+					q.Favorites = true
+					continue
+				case "note":
+					q.Type = TypeNote
+					continue
+				case "login":
+					q.Type = TypeLogin
+					continue
+				}
+			}
+			if field, known := searchFieldNames[name]; known {
+				term = SearchTerm{Field: field, Text: value}
+			}
+		}
+		if strings.TrimSpace(term.Text) == "" {
+			continue
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q
+}
+
+// tokenizeQuery 按空白切分，引号内的空白不切分 (引号本身去掉)。
+func tokenizeQuery(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+// SearchResult 搜索命中的条目及得分。
+type SearchResult struct {
+	Item  VaultItem
+	Score int
+}
+
+// Search 在已解密的条目中搜索，按匹配程度和最近使用时间排序。
+// 核心逻辑:
+//  1. 每个词取各字段中最好的匹配: 完全相同 > 开头 > 单词开头 > 包含 > 子序列 (如 "gh" 匹配 "github") > 拼写错误 (编辑距离 1-2)，乘以字段权重。
+//  2. 任一词没有匹配即排除该条目；各词得分相加，最近使用过的条目再加分。
+//  3. 没有搜索词 (空语句或只有 is: 筛选) 时按网站名排序。
+func Search(items []VaultItem, query string, now time.Time) []SearchResult {
+	q := ParseSearchQuery(query)
+
+	var results []SearchResult
+	for _, item := range items {
+		if q.Favorites && !item.Favorite {
+			continue
+		}
+		if q.Type != "" && item.Type != q.Type {
+			continue
+		}
+		score, matched := 0, true
+		for _, term := range q.Terms {
+			s := scoreTerm(item, term)
+			if s == 0 {
+				matched = false
+				break
+			}
+			score += s
+		}
+		if !matched {
+			continue
+		}
+		if len(q.Terms) > 0 {
+			score += recencyBonus(item.UsedAt, now)
+		}
+		results = append(results, SearchResult{Item: item, Score: score})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(q.Terms) > 0 && !a.Item.UsedAt.Equal(b.Item.UsedAt) {
+			return a.Item.UsedAt.After(b.Item.UsedAt)
+		}
+		return strings.ToLower(a.Item.Site) < strings.ToLower(b.Item.Site)
+	})
+	return results
+}
+
+// scoreTerm 词在条目中的得分 (0 表示不匹配)。
+func scoreTerm(item VaultItem, term SearchTerm) int {
+	fields := defaultFields
+	if term.Field != "" {
+		fields = []SearchField{term.Field}
+	}
+	best := 0
+	for _, field := range fields {
+		for _, text := range fieldTexts(item, field) {
+			if s := fuzzyScore(term.Text, text) * fieldWeights[field] / 100; s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+func fieldTexts(item VaultItem, field SearchField) []string {
+	switch field {
+	case FieldSite:
+		return []string{item.Site}
+	case FieldUser:
+		return []string{item.Username}
+	case FieldURL:
+		texts := make([]string, len(item.URIs))
+		for i, u := range item.URIs {
+			texts[i] = u.URI
+		}
+		return texts
+	case FieldTag:
+		return item.Tags
+	case FieldFolder:
+		return []string{item.Folder}
+	case FieldNotes:
+		return []string{item.Notes}
+	}
+	return nil
+}
+
+// 匹配程度得分
+const (
+	scoreExact       = 100
+	scorePrefixMatch = 90
+	scoreWordPrefix  = 80
+	scoreContains    = 70
+	scoreSubsequence = 30 // + 最多 20 (越紧凑越高)
+	scoreTypo        = 30 // - 10 × 编辑距离
+)
+
+// fuzzyScore 模式在文本中的匹配得分 (不区分大小写，0 表示不匹配)。
+func fuzzyScore(pattern, text string) int {
+	p := []rune(strings.ToLower(strings.TrimSpace(pattern)))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 || len(t) == 0 {
+		return 0
+	}
+	ps, ts := string(p), string(t)
+	switch {
+	case ts == ps:
+		return scoreExact
+	case strings.HasPrefix(ts, ps):
+		return scorePrefixMatch
+	case hasWordPrefix(t, p):
+		return scoreWordPrefix
+	case strings.Contains(ts, ps):
+		return scoreContains
+	}
+	if len(p) >= 2 {
+		if span := subsequenceSpan(p, t); span > 0 {
+			return scoreSubsequence + 20*len(p)/span
+		}
+	}
+	if d := typoDistance(p, t); d > 0 {
+		return scoreTypo - 10*d
+	}
+	return 0
+}
+
+// hasWordPrefix 文本中某个单词 (以非字母数字分隔) 以模式开头。
+func hasWordPrefix(t, p []rune) bool {
+	for i := 1; i+len(p) <= len(t); i++ {
+		if !isWordRune(t[i-1]) && isWordRune(t[i]) && string(t[i:i+len(p)]) == string(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// subsequenceSpan 模式按顺序出现在文本中时，返回从第一个到最后一个匹配字符的最短跨度，否则返回 0。
+func subsequenceSpan(p, t []rune) int {
+	best := 0
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		j := 1
+		end := start
+		for i := start + 1; i < len(t) && j < len(p); i++ {
+			if t[i] == p[j] {
+				j++
+				end = i
+			}
+		}
+		if j < len(p) {
+			break // 从更靠后的位置开始也不可能匹配完整
+		}
+		if span := end - start + 1; best == 0 || span < best {
+			best = span
+		}
+	}
+	return best
+}
+
+// typoDistance 模式与文本中某个单词 (或单词的开头部分) 的最小编辑距离 (相邻字符交换算一次)。
+// 允许的距离随模式长度增加: 少于 4 个字符不容错，4-7 个字符 1 处，8 个以上 2 处。超出时返回 0。
+func typoDistance(p, t []rune) int {
+	maxDist := 0
+	switch {
+	case len(p) >= 8:
+		maxDist = 2
+	case len(p) >= 4:
+		maxDist = 1
+	}
+	if maxDist == 0 {
+		return 0
+	}
+
+	best := maxDist + 1
+	for _, word := range strings.FieldsFunc(string(t), func(r rune) bool { return !isWordRune(r) }) {
+		w := []rune(word)
+		candidates := [][]rune{w}
+		if len(w) > len(p) {
+			candidates = append(candidates, w[:len(p)])
+		}
+		for _, c := range candidates {
+			if d := editDistance(p, c); d < best {
+				best = d
+			}
+		}
+	}
+	if best > maxDist {
+		return 0
+	}
+	return best
+}
+
+// editDistance 最优字符串对齐距离 (Levenshtein + 相邻交换)。
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// recencyBonus 最近使用过的条目加分: 1 天内 20，7 天内 15，30 天内 10，90 天内 5。
+func recencyBonus(usedAt, now time.Time) int {
+	if usedAt.IsZero() {
+		return 0
+	}
+	age := now.Sub(usedAt)
+	switch {
+	case age < 24*time.Hour:
+		return 20
+	case age < 7*24*time.Hour:
+		return 15
+	case age < 30*24*time.Hour:
+		return 10
+	case age < 90*24*time.Hour:
+		return 5
+	}
+	return 0
+}
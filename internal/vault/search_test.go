@@ -0,0 +1,184 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  SearchQuery
+	}{
+		{"", SearchQuery{}},
+		{"   ", SearchQuery{}},
+		{"github", SearchQuery{Terms: []SearchTerm{{Text: "github"}}}},
+		{"git hub", SearchQuery{Terms: []SearchTerm{{Text: "git"}, {Text: "hub"}}}},
+		{"user:alice tag:work", SearchQuery{Terms: []SearchTerm{{FieldUser, "alice"}, {FieldTag, "work"}}}},
+		{"url:example.com", SearchQuery{Terms: []SearchTerm{{FieldURL, "example.com"}}}},
+		// 别名和大小写
+		{"title:GitHub USERNAME:Bob uri:a.com notes:pin", SearchQuery{Terms: []SearchTerm{
+			{FieldSite, "GitHub"}, {FieldUser, "Bob"}, {FieldURL, "a.com"}, {FieldNotes, "pin"},
+		}}},
+		{"folder:work site:mail", SearchQuery{Terms: []SearchTerm{{FieldFolder, "work"}, {FieldSite, "mail"}}}},
+		// 引号内作为一个词
+		{`"two words"`, SearchQuery{Terms: []SearchTerm{{Text: "two words"}}}},
+		{`user:"a b" c`, SearchQuery{Terms: []SearchTerm{{FieldUser, "a b"}, {Text: "c"}}}},
+		// 筛选
+		{"is:fav", SearchQuery{Favorites: true}},
+		{"is:Favorite is:note bank", SearchQuery{Terms: []SearchTerm{{Text: "bank"}}, Favorites: true, Type: TypeNote}},
+		{"is:login", SearchQuery{Type: TypeLogin}},
+		// 未知字段按普通词处理，只有字段名时忽略
+		{"https://example.com", SearchQuery{Terms: []SearchTerm{{Text: "https://example.com"}}}},
+		{"is:unknown", SearchQuery{Terms: []SearchTerm{{Text: "is:unknown"}}}},
+		{"user:", SearchQuery{}},
+		{`user:"" github`, SearchQuery{Terms: []SearchTerm{{Text: "github"}}}},
+	}
+	for _, tt := range tests {
+		if got := ParseSearchQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    int
+	}{
+		{"exact", "github", "github", scoreExact},
+		{"case-insensitive", "GitHub", "github", scoreExact},
+		{"prefix", "git", "github", scorePrefixMatch},
+		{"word prefix", "hub", "my-hub", scoreWordPrefix},
+		{"contains", "hub", "github", scoreContains},
+		{"contains cjk", "邮箱", "工作邮箱", scoreContains},
+		{"empty pattern", " ", "github", 0},
+		{"empty text", "git", "", 0},
+
+		// 子序列: 跨度越小得分越高
+		{"subsequence", "gh", "github", scoreSubsequence + 20*2/4},
+		{"subsequence tight", "gthb", "github", scoreSubsequence + 20*4/6},
+		{"subsequence shortest span", "ab", "a--a-b", scoreSubsequence + 20*2/3},
+		{"single rune is not a subsequence", "z", "github", 0},
+		{"out of order", "bg", "github", 0},
+
+		// 拼写错误: 4-7 个字符容错 1 处，8 个以上 2 处
+		{"transposition", "gihtub", "github", scoreTypo - 10},
+		{"substitution", "gothub", "github.com", scoreTypo - 10},
+		{"typo in word prefix", "exmaple", "examples", scoreTypo - 10},
+		{"two typos in long pattern", "micorsfot", "microsoft", scoreTypo - 20},
+		{"two typos in short pattern", "gihtbu", "github", 0},
+		{"short pattern without tolerance", "gti", "github", 0},
+		{"too different", "gitlab", "github", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fuzzyScore(tt.pattern, tt.text); got != tt.want {
+				t.Errorf("fuzzyScore(%q, %q) = %d, want %d", tt.pattern, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecencyBonus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		usedAt time.Time
+		want   int
+	}{
+		{time.Time{}, 0},
+		{now.Add(-time.Hour), 20},
+		{now.Add(-3 * day), 15},
+		{now.Add(-10 * day), 10},
+		{now.Add(-60 * day), 5},
+		{now.Add(-100 * day), 0},
+	}
+	for _, tt := range tests {
+		if got := recencyBonus(tt.usedAt, now); got != tt.want {
+			t.Errorf("recencyBonus(%v) = %d, want %d", now.Sub(tt.usedAt), got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	items := []VaultItem{
+		{ID: 1, Type: TypeLogin, Site: "GitHub", Username: "alice", Tags: []string{"work"}, URIs: []URI{{"github.com", MatchDomain}}},
+		{ID: 2, Type: TypeLogin, Site: "Alice's Blog", Username: "admin", Folder: "personal"},
+		{ID: 3, Type: TypeLogin, Site: "Bank", Username: "alice@example.com", Favorite: true, URIs: []URI{{"bank.example.com", MatchHost}}},
+		{ID: 4, Site: "Wi-Fi", Type: TypeNote, Notes: "password hunter2", Tags: []string{"home"}},
+		{ID: 5, Type: TypeLogin, Site: "GitLab", Username: "bob", Tags: []string{"work"}},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// 网站名权重最高
+		{"alice", []int{2, 1, 3}},
+		// 字段限定
+		{"user:alice", []int{1, 3}},
+		{"site:alice", []int{2}},
+		{"tag:work", []int{1, 5}},
+		{"url:example.com", []int{3}},
+		{"folder:personal", []int{2}},
+		// 笔记内容只在 note: 中查找
+		{"hunter2", nil},
+		{"note:hunter2", []int{4}},
+		// 多个词必须同时匹配
+		{"tag:work bob", []int{5}},
+		{"tag:work user:carol", nil},
+		// 拼写错误和子序列
+		{"gihtub", []int{1}},
+		{"gtlb", []int{5}},
+		// 筛选
+		{"is:fav", []int{3}},
+		{"is:note", []int{4}},
+		{"is:login git", []int{1, 5}},
+		// 没有搜索词时按网站名排序
+		{"", []int{2, 3, 1, 5, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []int
+			for _, r := range Search(items, tt.query, now) {
+				got = append(got, r.Item.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRecencyRanking(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	items := []VaultItem{
+		{ID: 1, Site: "Mail A", UsedAt: now.Add(-100 * 24 * time.Hour)},
+		{ID: 2, Site: "Mail B", UsedAt: now.Add(-time.Hour)},
+		{ID: 3, Site: "Mail C"},
+		{ID: 4, Site: "Mail D", UsedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: 5, Site: "mail"},
+	}
+	var got []int
+	for _, r := range Search(items, "mail", now) {
+		got = append(got, r.Item.ID)
+	}
+	// 开头 90 + 1 天内 20 > 完全相同 100；
+	// 开头 90 + 30 天内 10 与完全相同 100 得分相同时，最近使用过的在前；超过 90 天不加分
+	if want := []int{2, 4, 5, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search ranking = %v, want %v", got, want)
+	}
+
+	// 没有搜索词时不考虑使用时间
+	got = got[:0]
+	for _, r := range Search(items, "", now) {
+		got = append(got, r.Item.ID)
+	}
+	if want := []int{5, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search with empty query = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+)
+
+// TrashedItem 回收站中的条目 (已解密)。
+type TrashedItem struct {
+	VaultItem
+	DeletedAt time.Time
+}
+
+// ListTrash 读取并解密回收站中的条目 (最近删除的在前)。
+func (m *Manager) ListTrash(username string, keyC []byte) ([]TrashedItem, error) {
+	rows, err := m.db.GetTrashedItems(username)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TrashedItem, 0, len(rows))
+	for _, row := range rows {
+		data, err := decryptItemData(keyC, row.EncData)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %v", row.ID, err)
+		}
+		items = append(items, TrashedItem{
+			VaultItem: newVaultItem(row.ID, row.Site, row.UpdatedAt, data),
+			DeletedAt: row.DeletedAt,
+		})
+	}
+	return items, nil
+}
+
+// RestoreItem 将条目从回收站还原。
+func (m *Manager) RestoreItem(id int) error {
+	defer m.changed()
+	return m.db.RestoreTrashedItem(id)
+}
+
+// PurgeItem 永久删除条目及其历史版本 (不经过回收站)。
+func (m *Manager) PurgeItem(id int) error {
+	if err := m.db.DeleteVaultItem(id); err != nil {
+		return err
+	}
+	return m.db.IncrementalVacuum()
+}
+
+// EmptyTrash 永久删除回收站中的所有条目，返回删除的条目数。
+func (m *Manager) EmptyTrash(username string) (int, error) {
+	return m.purgeTrash(username, time.Time{})
+}
+
+// PurgeExpiredTrash 永久删除在回收站中超过 days 天的条目，days 为 0 时不自动清理。
+func (m *Manager) PurgeExpiredTrash(username string, days int) (int, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+	return m.purgeTrash(username, time.Now().AddDate(0, 0, -days))
+}
+
+// purgeTrash 删除后释放空闲页，被删除的密文不残留在数据库文件中。
+func (m *Manager) purgeTrash(username string, olderThan time.Time) (int, error) {
+	n, err := m.db.PurgeTrash(username, olderThan)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, m.db.IncrementalVacuum()
+}
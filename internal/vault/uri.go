@@ -0,0 +1,259 @@
+package vault
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// MatchMode 网址的匹配方式。
+type MatchMode string
+
+const (
+	// MatchDomain 基础域名相同即匹配 (按公共后缀列表计算，如 login.example.co.uk 与 www.example.co.uk)
+	MatchDomain MatchMode = "domain"
+	// MatchHost 主机名 (及指定的端口) 完全相同
+	MatchHost MatchMode = "host"
+	// MatchPrefix 目标网址以该网址开头
+	MatchPrefix MatchMode = "prefix"
+	// MatchRegex 目标网址匹配该正则表达式
+	MatchRegex MatchMode = "regex"
+	// MatchNever 从不匹配，只作记录
+	MatchNever MatchMode = "never"
+)
+
+// MatchModes 所有匹配方式，按界面中的显示顺序。
+var MatchModes = []MatchMode{MatchDomain, MatchHost, MatchPrefix, MatchRegex, MatchNever}
+
+var matchModeLabels = map[MatchMode]string{
+	MatchDomain: "基础域名",
+	MatchHost:   "主机名",
+	MatchPrefix: "开头匹配",
+	MatchRegex:  "正则表达式",
+	MatchNever:  "从不匹配",
+}
+
+// Label 返回匹配方式的中文名称。
+func (m MatchMode) Label() string {
+	if label, ok := matchModeLabels[m.orDefault()]; ok {
+		return label
+	}
+	return string(m)
+}
+
+// orDefault 未设置匹配方式时按基础域名匹配。
+func (m MatchMode) orDefault() MatchMode {
+	if m == "" {
+		return MatchDomain
+	}
+	return m
+}
+
+// ParseMatchMode 解析匹配方式名称 (空字符串视为 domain)。
+func ParseMatchMode(s string) (MatchMode, error) {
+	m := MatchMode(strings.ToLower(strings.TrimSpace(s))).orDefault()
+	if _, ok := matchModeLabels[m]; !ok {
+		return "", fmt.Errorf("unknown match mode %q (domain, host, prefix, regex, never)", s)
+	}
+	return m, nil
+}
+
+// URI 条目关联的一个网址及其匹配方式。
+type URI struct {
+	URI   string    `json:"uri"`
+	Match MatchMode `json:"match,omitempty"`
+}
+
+// NormalizeURIs 去掉首尾空白和空网址，未设置的匹配方式写为 domain。
+func NormalizeURIs(uris []URI) []URI {
+	var out []URI
+	for _, u := range uris {
+		u.URI = strings.TrimSpace(u.URI)
+		if u.URI == "" {
+			continue
+		}
+		u.Match = u.Match.orDefault()
+		out = append(out, u)
+	}
+	return out
+}
+
+// ValidateURIs 检查网址能否按各自的匹配方式使用: 正则表达式能否编译，域名/主机名模式能否解析出主机名。
+func ValidateURIs(uris []URI) error {
+	for _, u := range uris {
+		switch u.Match.orDefault() {
+		case MatchRegex:
+			if _, err := regexp.Compile(u.URI); err != nil {
+				return fmt.Errorf("正则表达式 %q 无效: %v", u.URI, err)
+			}
+		case MatchDomain, MatchHost:
+			if _, err := ParseTargetURL(u.URI); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseTargetURL 解析网址，没有协议时按 https 处理 (例如 "github.com/login")。
+func ParseTargetURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("无法从 %q 解析出主机名", strings.TrimPrefix(raw, "https://"))
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// baseDomain 返回主机名的可注册域名 (公共后缀 + 一级)。IP 地址、localhost 等没有公共后缀的主机返回其本身。
+func baseDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
+
+// 匹配得分: 越具体的规则得分越高，同一规则内再按具体程度加分
+const (
+	scoreSiteFallback = 5  // 没有网址的旧条目，网站名看起来像域名
+	scoreDomain       = 10 // 基础域名相同 (+5: 主机名也相同)
+	scoreRegex        = 20
+	scoreHost         = 30
+	scorePrefix       = 40 // + 前缀长度
+)
+
+// uriMatcher 将网址与一个目标网址比较。
+// 同一次 MatchItems 中相同的正则表达式只编译一次 (编译失败记为 nil)。
+type uriMatcher struct {
+	target  *url.URL
+	regexps map[string]*regexp.Regexp
+}
+
+func newURIMatcher(target *url.URL) *uriMatcher {
+	return &uriMatcher{target: target, regexps: make(map[string]*regexp.Regexp)}
+}
+
+// compile 返回编译后的正则表达式，无效时返回 nil。
+func (m *uriMatcher) compile(expr string) *regexp.Regexp {
+	re, ok := m.regexps[expr]
+	if !ok {
+		re, _ = regexp.Compile(expr)
+		m.regexps[expr] = re
+	}
+	return re
+}
+
+// match 判断 u 是否匹配目标网址，返回得分 (0 表示不匹配)。
+func (m *uriMatcher) match(u URI) int {
+	target := m.target
+	switch u.Match.orDefault() {
+	case MatchDomain:
+		stored, err := ParseTargetURL(u.URI)
+		if err != nil || baseDomain(stored.Hostname()) != baseDomain(target.Hostname()) {
+			return 0
+		}
+		if stored.Hostname() == target.Hostname() {
+			return scoreDomain + 5
+		}
+		return scoreDomain
+	case MatchHost:
+		stored, err := ParseTargetURL(u.URI)
+		if err != nil || stored.Hostname() != target.Hostname() {
+			return 0
+		}
+		// 网址中写了端口时端口也必须相同
+		if stored.Port() != "" && stored.Port() != target.Port() {
+			return 0
+		}
+		return scoreHost
+	case MatchPrefix:
+		// 与目标网址一样补全协议，不区分大小写比较
+		prefix := strings.TrimSpace(u.URI)
+		if prefix != "" && !strings.Contains(prefix, "://") {
+			prefix = "https://" + prefix
+		}
+		if prefix != "" && strings.HasPrefix(strings.ToLower(target.String()), strings.ToLower(prefix)) {
+			return scorePrefix + len(prefix)
+		}
+	case MatchRegex:
+		// RE2 语法，匹配时间与输入长度成线性关系，不存在灾难性回溯
+		if re := m.compile(u.URI); re != nil && re.MatchString(target.String()) {
+			return scoreRegex
+		}
+	}
+	return 0
+}
+
+// Candidate 与目标网址匹配的条目。
+type Candidate struct {
+	Item VaultItem
+	// URI 匹配到的网址，由网站名推断时 Match 为 MatchDomain 且 URI 为网站名
+	URI   URI
+	Score int
+}
+
+// MatchItems 找出与目标网址匹配的条目，按匹配程度从高到低排序。
+// 核心逻辑:
+//  1. 每个条目取其所有网址中得分最高的一个。
+//  2. 没有网址的条目，若网站名是域名 (如 "github.com") 则按基础域名匹配，得分最低。
+//  3. 得分相同时收藏优先，其次是最近修改的条目。
+func MatchItems(items []VaultItem, rawURL string) ([]Candidate, error) {
+	target, err := ParseTargetURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	m := newURIMatcher(target)
+	var candidates []Candidate
+	for _, item := range items {
+		// 安全笔记没有登录信息
+		if item.IsNote() {
+			continue
+		}
+		best := Candidate{Item: item}
+		for _, u := range item.URIs {
+			if score := m.match(u); score > best.Score {
+				best.URI, best.Score = u, score
+			}
+		}
+		if len(item.URIs) == 0 && looksLikeDomain(item.Site) {
+			u := URI{URI: item.Site, Match: MatchDomain}
+			if m.match(u) > 0 {
+				best.URI, best.Score = u, scoreSiteFallback
+			}
+		}
+		if best.Score > 0 {
+			candidates = append(candidates, best)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Item.Favorite != b.Item.Favorite {
+			return a.Item.Favorite
+		}
+		return a.Item.UpdatedAt.After(b.Item.UpdatedAt)
+	})
+	return candidates, nil
+}
+
+// looksLikeDomain 网站名是否像域名或网址 (包含点且没有空白)。
+func looksLikeDomain(site string) bool {
+	site = strings.TrimSpace(site)
+	return strings.Contains(site, ".") && !strings.ContainsAny(site, " \t")
+}
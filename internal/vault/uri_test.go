@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchURI(t *testing.T) {
+	tests := []struct {
+		name   string
+		uri    URI
+		target string
+		want   int
+	}{
+		// 基础域名按公共后缀列表计算
+		{"same host", URI{"github.com", MatchDomain}, "https://github.com/login", scoreDomain + 5},
+		{"subdomain", URI{"github.com", MatchDomain}, "https://gist.github.com/", scoreDomain},
+		{"default mode is domain", URI{"github.com", ""}, "https://api.github.com", scoreDomain},
+		{"multi-level suffix", URI{"login.example.co.uk", MatchDomain}, "https://www.example.co.uk", scoreDomain},
+		{"different site under co.uk", URI{"example.co.uk", MatchDomain}, "https://other.co.uk", 0},
+		{"github.io pages are separate sites", URI{"a.github.io", MatchDomain}, "https://b.github.io", 0},
+		{"same github.io page", URI{"a.github.io", MatchDomain}, "https://a.github.io/docs", scoreDomain + 5},
+		{"different IP addresses", URI{"192.168.1.1", MatchDomain}, "http://192.168.1.2", 0},
+		{"same IP address", URI{"192.168.1.1", MatchDomain}, "http://192.168.1.1:8080", scoreDomain + 5},
+		{"localhost", URI{"localhost", MatchDomain}, "http://localhost:3000", scoreDomain + 5},
+
+		// 主机名，写了端口时端口也要相同
+		{"host", URI{"mail.example.com", MatchHost}, "https://mail.example.com/inbox", scoreHost},
+		{"host case-insensitive", URI{"Mail.Example.com", MatchHost}, "https://mail.example.com", scoreHost},
+		{"other subdomain", URI{"mail.example.com", MatchHost}, "https://www.example.com", 0},
+		{"host without port matches any port", URI{"example.com", MatchHost}, "https://example.com:8443", scoreHost},
+		{"host and port", URI{"example.com:8443", MatchHost}, "https://example.com:8443/admin", scoreHost},
+		{"port mismatch", URI{"example.com:8443", MatchHost}, "https://example.com/admin", 0},
+		{"port mismatch explicit", URI{"example.com:8443", MatchHost}, "https://example.com:9443", 0},
+
+		// 开头匹配，没有协议时按 https 补全
+		{"prefix default scheme", URI{"github.com/org", MatchPrefix}, "github.com/org/repo", scorePrefix + len("https://github.com/org")},
+		{"prefix with scheme", URI{"http://intranet/app", MatchPrefix}, "http://intranet/app/login", scorePrefix + len("http://intranet/app")},
+		{"prefix scheme mismatch", URI{"github.com/org", MatchPrefix}, "http://github.com/org/repo", 0},
+		{"prefix case-insensitive", URI{"GitHub.com/Org", MatchPrefix}, "https://github.com/org", scorePrefix + len("https://GitHub.com/Org")},
+		{"prefix other path", URI{"github.com/org", MatchPrefix}, "https://github.com/other", 0},
+
+		// 正则表达式和从不匹配
+		{"regex", URI{`^https://(www\.)?example\.com/`, MatchRegex}, "https://www.example.com/a", scoreRegex},
+		{"regex no match", URI{`^https://example\.com/$`, MatchRegex}, "https://example.com/a", 0},
+		{"invalid regex", URI{`(`, MatchRegex}, "https://example.com", 0},
+		{"never", URI{"example.com", MatchNever}, "https://example.com", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ParseTargetURL(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := newURIMatcher(target).match(tt.uri); got != tt.want {
+				t.Errorf("match(%q %s, %q) = %d, want %d", tt.uri.URI, tt.uri.Match, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIMatcherCompilesRegexOnce(t *testing.T) {
+	target, err := ParseTargetURL("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newURIMatcher(target)
+	for i := 0; i < 3; i++ {
+		m.match(URI{`example\.com`, MatchRegex})
+		m.match(URI{`(`, MatchRegex})
+	}
+	if len(m.regexps) != 2 {
+		t.Errorf("compiled %d expressions, want 2", len(m.regexps))
+	}
+}
+
+func TestMatchItemsRanking(t *testing.T) {
+	now := time.Now()
+	items := []VaultItem{
+		{ID: 1, Site: "github.com"}, // 没有网址，由网站名推断
+		{ID: 2, Site: "GitHub", URIs: []URI{{"github.com", MatchDomain}}},
+		{ID: 3, Site: "GitHub Enterprise", URIs: []URI{{"github.com", MatchHost}}},
+		{ID: 4, Site: "Org", URIs: []URI{{"github.com", MatchDomain}, {"github.com/org", MatchPrefix}}},
+		{ID: 5, Site: "Regex", URIs: []URI{{`github\.com`, MatchRegex}}},
+		{ID: 6, Site: "github.com", Type: TypeNote},                       // 安全笔记不参与
+		{ID: 7, Site: "GitLab", URIs: []URI{{"gitlab.com", MatchDomain}}}, // 不匹配
+		{ID: 8, Site: "Old", URIs: []URI{{"github.com", MatchNever}}},     // 从不匹配
+		{ID: 9, Site: "Deep prefix", URIs: []URI{{"github.com/org/repo", MatchPrefix}}},
+	}
+
+	got, err := MatchItems(items, "https://github.com/org/repo/issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{9, 4, 3, 5, 2, 1}
+	if len(got) != len(want) {
+		t.Fatalf("got %d candidates, want %d: %+v", len(got), len(want), got)
+	}
+	for i, c := range got {
+		if c.Item.ID != want[i] {
+			t.Errorf("candidate %d = item %d (score %d), want item %d", i, c.Item.ID, c.Score, want[i])
+		}
+	}
+	if got[1].URI.Match != MatchPrefix {
+		t.Errorf("item 4 matched by %s, want its best URI (prefix)", got[1].URI.Match)
+	}
+
+	// 得分相同时收藏优先，其次是最近修改的条目
+	tied := []VaultItem{
+		{ID: 1, Site: "a", URIs: []URI{{"example.com", MatchHost}}, UpdatedAt: now.Add(-time.Hour)},
+		{ID: 2, Site: "b", URIs: []URI{{"example.com", MatchHost}}, UpdatedAt: now},
+		{ID: 3, Site: "c", URIs: []URI{{"example.com", MatchHost}}, UpdatedAt: now.Add(-2 * time.Hour), Favorite: true},
+	}
+	got, err = MatchItems(tied, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []int{3, 2, 1} {
+		if got[i].Item.ID != id {
+			t.Errorf("tie-break position %d = item %d, want item %d", i, got[i].Item.ID, id)
+		}
+	}
+}
+
+func TestMatchItemsInvalidTarget(t *testing.T) {
+	if _, err := MatchItems(nil, "https://"); err == nil {
+		t.Error("MatchItems accepted a URL without host")
+	}
+}
@@ -3,6 +3,10 @@ package vault
 import (
 	"encoding/json"
 	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"key-box/internal/crypto"
 	"key-box/internal/db"
@@ -10,115 +14,264 @@ import (
 
 type Manager struct {
 	db *db.DB
+	// HistoryLimit 每个条目保留的历史版本数，0 表示不保留
+	HistoryLimit int
+	// AttachmentLimit 单个附件的最大字节数
+	AttachmentLimit int64
+
+	// generation 每次修改条目 (添加、更新、删除、还原) 后加一，见 Generation；记录使用时间不计入
+	generation atomic.Uint64
 }
 
+// DefaultHistoryLimit 默认每个条目保留 10 个历史版本
+const DefaultHistoryLimit = 10
+
+// DefaultAttachmentLimit 默认单个附件最大 50 MiB
+const DefaultAttachmentLimit = 50 << 20
+
+// listProgressStep 并行解密时每解密多少个条目报告一次进度
+const listProgressStep = 100
+
 func NewManager(db *db.DB) *Manager {
-	return &Manager{db: db}
+	return &Manager{db: db, HistoryLimit: DefaultHistoryLimit, AttachmentLimit: DefaultAttachmentLimit}
+}
+
+// Generation 返回条目的修改计数。调用方缓存 ListItems 的结果时，计数变化即表示缓存已过期。
+func (m *Manager) Generation() uint64 {
+	return m.generation.Load()
+}
+
+// changed 条目被修改，使调用方的缓存失效 (写入失败时也调用，宁可多解密一次)。
+func (m *Manager) changed() {
+	m.generation.Add(1)
 }
 
+// ItemType 条目类型。
+type ItemType string
+
+const (
+	// TypeLogin 登录信息 (网站、账号、密码)，没有类型字段的旧条目也是登录
+	TypeLogin ItemType = "login"
+	// TypeNote 安全笔记，只有标题和笔记内容
+	TypeNote ItemType = "note"
+)
+
+// ItemData 条目中被 Key C 加密的部分。
+// 安全决策: 文件夹、标签、收藏标记、网址和笔记与密码一起加密，数据库中只有网站名 (笔记标题) 是明文。
 type ItemData struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Type     ItemType `json:"type,omitempty"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	// Notes 笔记 (Markdown)，登录条目中作为备注
+	Notes string `json:"notes,omitempty"`
+	// Folder 文件夹路径，以 "/" 分隔层级，如 "工作/服务器"
+	Folder   string   `json:"folder,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Favorite bool     `json:"favorite,omitempty"`
+	URIs     []URI    `json:"uris,omitempty"`
 }
 
 type VaultItem struct {
-	ID       int
+	ID   int
+	Type ItemType
+	// Site 网站名，安全笔记的标题
 	Site     string
 	Username string
 	Password string
+	Notes    string
+	Folder   string
+	Tags     []string
+	Favorite bool
+	// URIs 关联的网址及匹配方式，用于按网址查找条目
+	URIs []URI
+	// UpdatedAt 最后一次修改时间 (UTC)
+	UpdatedAt time.Time
+	// CreatedAt 创建时间 (UTC)，记录创建时间之前添加的条目为零值
+	CreatedAt time.Time
+	// UsedAt 最近一次查看或复制密码的时间 (UTC)，从未使用时为零值
+	UsedAt time.Time
 }
 
-// AddItem 加密并存储一个新的密码条目。
-// 核心逻辑:
-//  1. 将明文数据 (用户名、密码) 序列化为 JSON。
-//  2. 使用 Key C 对 JSON 数据进行 AES-GCM 加密。
-//     注意: Key C 是数据专用密钥，只有在用户登录并通过 TOTP 验证后才能获取。
-//  3. 将加密后的 Blob 和明文索引 (Site) 存储到数据库。
-func (m *Manager) AddItem(username string, keyC []byte, site, itemUser, itemPass string) error {
+// newVaultItem 由数据库行的明文字段和解密后的数据组装条目。
+func newVaultItem(id int, site string, updatedAt time.Time, data *ItemData) VaultItem {
+	item := VaultItem{
+		ID:        id,
+		Type:      data.Type,
+		Site:      site,
+		Username:  data.Username,
+		Password:  data.Password,
+		Notes:     data.Notes,
+		Folder:    data.Folder,
+		Tags:      data.Tags,
+		Favorite:  data.Favorite,
+		URIs:      data.URIs,
+		UpdatedAt: updatedAt,
+	}
+	if item.Type == "" {
+		item.Type = TypeLogin
+	}
+	return item
+}
+
+// IsNote 条目是否是安全笔记。
+func (item VaultItem) IsNote() bool {
+	return item.Type == TypeNote
+}
+
+// encryptItem 将条目的私密字段序列化为 JSON 并用 Key C 加密。
+// 文件夹、标签和网址在加密前规范化，无效的网址 (如无法编译的正则表达式) 或未知的类型返回错误。
+func encryptItem(keyC []byte, item VaultItem) ([]byte, error) {
+	switch item.Type {
+	case "", TypeLogin, TypeNote:
+	default:
+		return nil, fmt.Errorf("unknown item type %q", item.Type)
+	}
+	uris := NormalizeURIs(item.URIs)
+	if err := ValidateURIs(uris); err != nil {
+		return nil, err
+	}
 	data := ItemData{
-		Username: itemUser,
-		Password: itemPass,
+		Type:     item.Type,
+		Username: item.Username,
+		Password: item.Password,
+		Notes:    item.Notes,
+		Folder:   NormalizeFolder(item.Folder),
+		Tags:     NormalizeTags(item.Tags),
+		Favorite: item.Favorite,
+		URIs:     uris,
 	}
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
 	// 使用 Key C 加密实际数据
-	encData, err := crypto.EncryptAESGCM(keyC, jsonData)
+	return crypto.EncryptAESGCM(keyC, jsonData)
+}
+
+// AddItem 加密并存储一个新的密码条目 (忽略 item.ID)。
+// 核心逻辑:
+//  1. 将明文数据 (类型、用户名、密码、笔记、文件夹、标签、收藏、网址) 序列化为 JSON。
+//  2. 使用 Key C 对 JSON 数据进行 AES-GCM 加密。
+//     注意: Key C 是数据专用密钥，只有在用户登录并通过 TOTP 验证后才能获取。
+//  3. 将加密后的 Blob 和明文索引 (Site) 存储到数据库。
+func (m *Manager) AddItem(username string, keyC []byte, item VaultItem) error {
+	defer m.changed()
+	encData, err := encryptItem(keyC, item)
 	if err != nil {
 		return err
 	}
-
-	return m.db.SaveVaultItem(username, site, encData)
+	return m.db.SaveVaultItem(username, item.Site, encData)
 }
 
 // ListItems 读取并解密所有密码条目。
+func (m *Manager) ListItems(username string, keyC []byte) ([]VaultItem, error) {
+	return m.ListItemsProgress(username, keyC, nil)
+}
+
+// ListItemsProgress 与 ListItems 相同，progress 不为 nil 时每解密 listProgressStep 个条目及全部完成时报告进度。
+// progress 可能在不同的 goroutine 中调用。
 // 核心逻辑:
 // 1. 从数据库获取该用户的所有加密条目。
-// 2. 使用传入的 Key C 逐个解密。
-// 3. 如果解密失败 (例如数据损坏或密钥错误)，返回错误。
-// 4. 反序列化 JSON 得到明文。
-func (m *Manager) ListItems(username string, keyC []byte) ([]VaultItem, error) {
+// 2. 按 CPU 核数启动多个 goroutine，使用传入的 Key C 并行解密并反序列化 JSON，结果保持数据库中的顺序。
+// 3. 如果任一条目解密失败 (例如数据损坏或密钥错误)，返回错误。
+func (m *Manager) ListItemsProgress(username string, keyC []byte, progress func(done, total int)) ([]VaultItem, error) {
 	rows, err := m.db.GetVaultItems(username)
 	if err != nil {
 		return nil, err
 	}
 
-	var results []VaultItem
-	for _, row := range rows {
-		// 解密数据
-		decrypted, err := crypto.DecryptAESGCM(keyC, row.EncData)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt item %d: %v", row.ID, err)
-		}
+	results := make([]VaultItem, len(rows))
+	errs := make([]error, len(rows))
+	jobs := make(chan int)
+	var done atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), len(rows)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = decryptVaultItem(keyC, &rows[i])
+				n := int(done.Add(1))
+				if progress != nil && (n%listProgressStep == 0 || n == len(rows)) {
+					progress(n, len(rows))
+				}
+			}
+		}()
+	}
+	for i := range rows {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-		var data ItemData
-		if err := json.Unmarshal(decrypted, &data); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal item %d: %v", row.ID, err)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
 		}
-
-		results = append(results, VaultItem{
-			ID:       row.ID,
-			Site:     row.Site,
-			Username: data.Username,
-			Password: data.Password,
-		})
 	}
 	return results, nil
 }
 
-// UpdateItem 更新已存储的密码条目。
+// decryptVaultItem 解密数据库中的一行。
+func decryptVaultItem(keyC []byte, row *db.VaultItem) (VaultItem, error) {
+	data, err := decryptItemData(keyC, row.EncData)
+	if err != nil {
+		return VaultItem{}, fmt.Errorf("item %d: %v", row.ID, err)
+	}
+	item := newVaultItem(row.ID, row.Site, row.UpdatedAt, data)
+	item.CreatedAt = row.CreatedAt
+	item.UsedAt = row.UsedAt
+	return item, nil
+}
+
+// UpdateItem 更新已存储的密码条目 (按 item.ID)。
 // 核心逻辑:
 // 1. 将新的明文数据序列化为 JSON。
 // 2. 使用 Key C 加密。
-// 3. 更新数据库记录。
-func (m *Manager) UpdateItem(keyC []byte, id int, site, itemUser, itemPass string) error {
-	data := ItemData{
-		Username: itemUser,
-		Password: itemPass,
-	}
-	jsonData, err := json.Marshal(data)
+// 3. 旧的加密内容归档为历史版本 (超出 HistoryLimit 的最旧版本被删除)，然后更新数据库记录。
+func (m *Manager) UpdateItem(keyC []byte, item VaultItem) error {
+	defer m.changed()
+	encData, err := encryptItem(keyC, item)
 	if err != nil {
 		return err
 	}
+	return m.db.UpdateVaultItem(item.ID, item.Site, encData, m.HistoryLimit)
+}
 
-	// 使用 Key C 加密实际数据
-	encData, err := crypto.EncryptAESGCM(keyC, jsonData)
+// SetFavorite 标记或取消收藏。
+// 收藏只是整理方式，不算修改内容: 不生成历史版本，也不改变修改时间 (体检按修改时间判断密码是否过旧)。
+func (m *Manager) SetFavorite(keyC []byte, item VaultItem, favorite bool) error {
+	defer m.changed()
+	item.Favorite = favorite
+	encData, err := encryptItem(keyC, item)
 	if err != nil {
 		return err
 	}
+	return m.db.SetVaultItemData(item.ID, encData)
+}
 
-	return m.db.UpdateVaultItem(id, site, encData)
+// MarkUsed 记录条目被使用 (查看或复制密码)，搜索时最近使用的条目排在前面。
+// 使用时间只影响排序，不增加修改计数 (Generation)，否则每次查看或复制都会使调用方的缓存过期；
+// 缓存条目的调用方自行更新 UsedAt。
+func (m *Manager) MarkUsed(id int) error {
+	return m.db.TouchVaultItem(id)
 }
 
-// DeleteItem 删除已存储的密码条目。
+// DeleteItem 将条目移到回收站，可通过 RestoreItem 还原。
 func (m *Manager) DeleteItem(id int) error {
-	return m.db.DeleteVaultItem(id)
+	defer m.changed()
+	return m.db.TrashVaultItem(id)
 }
 
-// DeleteAllItems 删除用户的所有密码条目（用于覆盖恢复）
+// DeleteAllItems 删除用户的所有密码条目及历史版本和附件（用于覆盖恢复）
 func (m *Manager) DeleteAllItems(username string) error {
+	defer m.changed()
+	if _, err := m.db.Exec(`DELETE FROM item_history WHERE username = ?`, username); err != nil {
+		return err
+	}
+	if err := db.DeleteItemAttachments(m.db, `username = ?`, username); err != nil {
+		return err
+	}
 	stmt := `DELETE FROM vault WHERE username = ?`
 	_, err := m.db.Exec(stmt, username)
 	return err
@@ -131,5 +284,6 @@ func (m *Manager) GetEncryptedItems(username string) ([]db.VaultItem, error) {
 
 // RestoreEncryptedItem 恢复加密的密码条目（用于恢复备份）
 func (m *Manager) RestoreEncryptedItem(username, site string, encData []byte) error {
+	defer m.changed()
 	return m.db.SaveVaultItem(username, site, encData)
 }
@@ -0,0 +1,375 @@
+// Package x11 直接实现 X11 协议的最小子集 (连接、认证、atom、原始请求)，
+// 供剪贴板、全局快捷键等功能使用，不依赖 libX11 和 cgo。
+package x11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 请求操作码
+const (
+	OpCreateWindow       = 1
+	OpChangeProperty     = 18
+	OpGetProperty        = 20
+	OpInternAtom         = 16
+	OpSetSelectionOwner  = 22
+	OpGetSelectionOwner  = 23
+	OpConvertSelection   = 24
+	OpSendEvent          = 25
+	OpGrabKey            = 33
+	OpGetInputFocus      = 43
+	OpGetKeyboardMapping = 101
+)
+
+// 事件代码
+const (
+	EventKeyPress         = 2
+	EventPropertyNotify   = 28
+	EventSelectionClear   = 29
+	EventSelectionRequest = 30
+	EventSelectionNotify  = 31
+	EventClientMessage    = 33
+)
+
+// 预定义 atom
+const (
+	AtomAtom   = 4
+	AtomString = 31
+)
+
+// Conn 与 X 服务器的连接 (小端字节序)。
+type Conn struct {
+	net.Conn
+	// Root 第一个屏幕的根窗口
+	Root uint32
+	// MaxRequest 单个请求的最大字节数
+	MaxRequest int
+	// MinKeycode、MaxKeycode 服务器使用的键码范围
+	MinKeycode, MaxKeycode byte
+
+	ridBase, ridMask, ridNext uint32
+}
+
+// Dial 连接 DISPLAY 指定的 X 服务器并完成认证。
+func Dial(display string) (*Conn, error) {
+	network, addr, number, err := parseDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := net.Dial(network, addr)
+	if err != nil && network == "unix" {
+		// Linux 抽象命名空间套接字
+		nc, err = net.Dial("unix", "@"+addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X display %q: %v", display, err)
+	}
+
+	authName, authData := readXauth(number)
+	c, err := NewConn(nc, authName, authData)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewConn 在已建立的连接上发送连接请求并完成认证 (authName 为空表示不认证)。
+// 失败时不关闭 nc。
+func NewConn(nc net.Conn, authName, authData []byte) (*Conn, error) {
+	c := &Conn{Conn: nc}
+	if err := c.setup(authName, authData); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseDisplay 解析 DISPLAY ([host]:number[.screen] 或 XQuartz 的套接字路径)。
+func parseDisplay(display string) (network, addr, number string, err error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	host, number := display[:i], display[i+1:]
+	if j := strings.Index(number, "."); j >= 0 {
+		number = number[:j]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+
+	switch {
+	case strings.HasPrefix(display, "/"):
+		return "unix", display, number, nil
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, number, nil
+	default:
+		return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), number, nil
+	}
+}
+
+// readXauth 从 Xauthority 文件读取显示对应的 MIT-MAGIC-COOKIE-1，找不到时返回空 (无认证连接)。
+func readXauth(number string) (name, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	hostname, _ := os.Hostname()
+
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+	)
+	r := bytes.NewReader(content)
+	readField := func() ([]byte, bool) {
+		var n uint16
+		if binary.Read(r, binary.BigEndian, &n) != nil {
+			return nil, false
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, false
+		}
+		return b, true
+	}
+
+	for {
+		var family uint16
+		if binary.Read(r, binary.BigEndian, &family) != nil {
+			break
+		}
+		addr, ok1 := readField()
+		num, ok2 := readField()
+		authName, ok3 := readField()
+		authData, ok4 := readField()
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			break
+		}
+		if string(authName) != "MIT-MAGIC-COOKIE-1" || (len(num) > 0 && string(num) != number) {
+			continue
+		}
+		if family == familyWild || (family == familyLocal && string(addr) == hostname) {
+			return authName, authData
+		}
+		if name == nil {
+			name, data = authName, authData
+		}
+	}
+	return name, data
+}
+
+// setup 发送连接请求 (小端字节序) 并解析服务器信息 (资源 ID 范围、键码范围、第一个屏幕的根窗口)。
+func (c *Conn) setup(authName, authData []byte) error {
+	req := make([]byte, 12)
+	req[0] = 'l'
+	binary.LittleEndian.PutUint16(req[2:], 11)
+	binary.LittleEndian.PutUint16(req[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(req[8:], uint16(len(authData)))
+	req = append(req, Pad4(authName)...)
+	req = append(req, Pad4(authData)...)
+	if _, err := c.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(c, head); err != nil {
+		return err
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(head[6:]))*4)
+	if _, err := io.ReadFull(c, body); err != nil {
+		return err
+	}
+	if head[0] != 1 {
+		reason := string(body)
+		if head[0] == 0 && int(head[1]) <= len(body) {
+			reason = string(body[:head[1]])
+		}
+		return fmt.Errorf("X server refused connection: %s", strings.TrimRight(reason, "\x00"))
+	}
+	if len(body) < 32 {
+		return errors.New("invalid X server setup reply")
+	}
+
+	c.ridBase = binary.LittleEndian.Uint32(body[4:])
+	c.ridMask = binary.LittleEndian.Uint32(body[8:])
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	c.MaxRequest = int(binary.LittleEndian.Uint16(body[18:])) * 4
+	numFormats := int(body[21])
+	c.MinKeycode, c.MaxKeycode = body[26], body[27]
+
+	screen := 32 + (vendorLen+3)/4*4 + numFormats*8
+	if len(body) < screen+4 {
+		return errors.New("invalid X server setup reply")
+	}
+	c.Root = binary.LittleEndian.Uint32(body[screen:])
+	return nil
+}
+
+// NewID 分配一个新的资源 ID (窗口等)。
+func (c *Conn) NewID() uint32 {
+	c.ridNext++
+	return c.ridBase | (c.ridMask & (c.ridNext * (c.ridMask & -c.ridMask)))
+}
+
+// Request 分配请求缓冲区: 4 字节请求头 + bodyLen 字节 (已按 4 字节对齐)。
+func (c *Conn) Request(opcode, data byte, bodyLen int) []byte {
+	req := make([]byte, 4+bodyLen)
+	req[0] = opcode
+	req[1] = data
+	binary.LittleEndian.PutUint16(req[2:], uint16(len(req)/4))
+	return req
+}
+
+// Reply 读取下一个回复 (32 字节头 + 附加数据)，跳过期间收到的事件。
+// 收到错误时返回 *Error。
+func (c *Conn) Reply() ([]byte, error) {
+	for {
+		buf, err := c.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch buf[0] {
+		case 0:
+			return nil, ErrorFromPacket(buf)
+		case 1:
+			return buf, nil
+		}
+	}
+}
+
+// ReadPacket 读取下一个事件、错误或回复 (回复包含附加数据)。
+func (c *Conn) ReadPacket() ([]byte, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return nil, err
+	}
+	if buf[0] == 1 {
+		extra := make([]byte, int(binary.LittleEndian.Uint32(buf[4:]))*4)
+		if _, err := io.ReadFull(c, extra); err != nil {
+			return nil, err
+		}
+		buf = append(buf, extra...)
+	}
+	return buf, nil
+}
+
+// Error X 服务器返回的错误。
+type Error struct {
+	Code  byte
+	Major byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("X server error %d (request %d)", e.Code, e.Major)
+}
+
+// 错误代码
+const (
+	ErrorAccess = 10
+)
+
+// ErrorFromPacket 解析 X 服务器发来的错误包。
+func ErrorFromPacket(buf []byte) *Error {
+	return &Error{Code: buf[1], Major: buf[10]}
+}
+
+// InternAtom 查询 (必要时创建) 名称对应的 atom。
+func (c *Conn) InternAtom(name string) (uint32, error) {
+	req := c.Request(OpInternAtom, 0, 4+len(Pad4([]byte(name))))
+	binary.LittleEndian.PutUint16(req[4:], uint16(len(name)))
+	copy(req[8:], name)
+	if _, err := c.Write(req); err != nil {
+		return 0, err
+	}
+	reply, err := c.Reply()
+	if err != nil {
+		return 0, fmt.Errorf("failed to intern atom %q: %w", name, err)
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// Sync 等待之前发送的请求全部处理完毕 (GetInputFocus 往返)，返回期间收到的第一个错误。
+// 期间收到的事件被丢弃。
+func (c *Conn) Sync() error {
+	if _, err := c.Write(c.Request(OpGetInputFocus, 0, 0)); err != nil {
+		return err
+	}
+	var first error
+	for {
+		buf, err := c.ReadPacket()
+		if err != nil {
+			return err
+		}
+		switch buf[0] {
+		case 0:
+			if first == nil {
+				first = ErrorFromPacket(buf)
+			}
+		case 1:
+			return first
+		}
+	}
+}
+
+// ChangeProperty 以替换方式写入窗口属性，format 为 8、16 或 32。
+func (c *Conn) ChangeProperty(window, property, typ uint32, format byte, data []byte) error {
+	req := c.Request(OpChangeProperty, 0, 20+len(Pad4(data)))
+	binary.LittleEndian.PutUint32(req[4:], window)
+	binary.LittleEndian.PutUint32(req[8:], property)
+	binary.LittleEndian.PutUint32(req[12:], typ)
+	req[16] = format
+	binary.LittleEndian.PutUint32(req[20:], uint32(len(data)/int(format/8)))
+	copy(req[24:], data)
+	_, err := c.Write(req)
+	return err
+}
+
+// GetProperty 读取窗口属性的前 maxLen 个 32 位单位 (typ 为 0 表示任意类型)。
+// 属性不存在时 format 为 0，data 为空。
+func (c *Conn) GetProperty(window, property, typ uint32, maxLen uint32) (format byte, data []byte, err error) {
+	req := c.Request(OpGetProperty, 0, 20) // delete = false
+	binary.LittleEndian.PutUint32(req[4:], window)
+	binary.LittleEndian.PutUint32(req[8:], property)
+	binary.LittleEndian.PutUint32(req[12:], typ)
+	binary.LittleEndian.PutUint32(req[16:], 0)
+	binary.LittleEndian.PutUint32(req[20:], maxLen)
+	if _, err := c.Write(req); err != nil {
+		return 0, nil, err
+	}
+	reply, err := c.Reply()
+	if err != nil {
+		return 0, nil, err
+	}
+	format = reply[1]
+	n := int(binary.LittleEndian.Uint32(reply[16:])) * int(format/8)
+	if 32+n > len(reply) {
+		return 0, nil, errors.New("invalid GetProperty reply")
+	}
+	return format, reply[32 : 32+n], nil
+}
+
+// Pad4 将数据补齐到 4 字节边界。
+func Pad4(b []byte) []byte {
+	n := (len(b) + 3) / 4 * 4
+	out := make([]byte, n)
+	copy(out, b)
+	return out
+}
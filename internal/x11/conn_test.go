@@ -0,0 +1,153 @@
+package x11_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"key-box/internal/x11"
+	"key-box/internal/x11/x11test"
+)
+
+func dial(t *testing.T, s *x11test.Server) *x11.Conn {
+	t.Helper()
+	c, err := s.Dial()
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestNewConnParsesSetup(t *testing.T) {
+	c := dial(t, x11test.NewServer())
+
+	if c.Root != x11test.Root {
+		t.Errorf("Root = %#x, want %#x", c.Root, x11test.Root)
+	}
+	if c.MinKeycode != 8 || c.MaxKeycode != 255 {
+		t.Errorf("keycodes = %d..%d, want 8..255", c.MinKeycode, c.MaxKeycode)
+	}
+	if c.MaxRequest != 0xffff*4 {
+		t.Errorf("MaxRequest = %d, want %d", c.MaxRequest, 0xffff*4)
+	}
+	a, b := c.NewID(), c.NewID()
+	if a == 0 || a == b {
+		t.Errorf("NewID returned %#x, %#x", a, b)
+	}
+}
+
+func TestInternAtom(t *testing.T) {
+	s := x11test.NewServer()
+	c := dial(t, s)
+
+	a, err := c.InternAtom("CLIPBOARD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := s.Atom("CLIPBOARD"); a != want {
+		t.Errorf("InternAtom = %d, want %d", a, want)
+	}
+	again, err := c.InternAtom("CLIPBOARD")
+	if err != nil || again != a {
+		t.Errorf("second InternAtom = %d, %v; want %d", again, err, a)
+	}
+	if a == x11.AtomString || a == x11.AtomAtom {
+		t.Errorf("InternAtom returned a predefined atom %d", a)
+	}
+}
+
+func TestGetPropertyAndChangeProperty(t *testing.T) {
+	s := x11test.NewServer()
+	c := dial(t, s)
+	prop, err := c.InternAtom("KEY_BOX_TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format, data, err := c.GetProperty(c.Root, prop, 0, 16)
+	if err != nil || format != 0 || len(data) != 0 {
+		t.Fatalf("missing property: format=%d data=%q err=%v", format, data, err)
+	}
+
+	if err := c.ChangeProperty(c.Root, prop, x11.AtomString, 8, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	format, data, err = c.GetProperty(c.Root, prop, x11.AtomString, 16)
+	if err != nil || format != 8 || string(data) != "hello" {
+		t.Errorf("GetProperty = %d %q %v, want 8 \"hello\"", format, data, err)
+	}
+}
+
+func TestSyncReportsErrors(t *testing.T) {
+	c := dial(t, x11test.NewServer())
+
+	// 不存在的窗口: ChangeProperty 没有回复，错误由 Sync 取回
+	if err := c.ChangeProperty(0xdead, x11.AtomString, x11.AtomString, 8, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Sync()
+	var xerr *x11.Error
+	if !errors.As(err, &xerr) || xerr.Major != x11.OpChangeProperty {
+		t.Fatalf("Sync = %v, want an error for ChangeProperty", err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Errorf("second Sync = %v, want nil", err)
+	}
+}
+
+func TestIsIconic(t *testing.T) {
+	const (
+		normalState = 1
+		iconicState = 3
+	)
+	s := x11test.NewServer()
+	c := dial(t, s)
+	wmState := s.Atom("WM_STATE")
+	hidden := s.Atom("_NET_WM_STATE_HIDDEN")
+	above := s.Atom("_NET_WM_STATE_ABOVE")
+
+	tests := []struct {
+		name   string
+		set    func(win uint32)
+		iconic bool
+	}{
+		{"no properties", func(uint32) {}, false},
+		{"WM_STATE normal", func(w uint32) { s.SetProperty(w, "WM_STATE", wmState, normalState, 0) }, false},
+		{"WM_STATE iconic", func(w uint32) { s.SetProperty(w, "WM_STATE", wmState, iconicState, 0) }, true},
+		{"_NET_WM_STATE above", func(w uint32) { s.SetProperty(w, "_NET_WM_STATE", x11.AtomAtom, above) }, false},
+		{"_NET_WM_STATE hidden", func(w uint32) { s.SetProperty(w, "_NET_WM_STATE", x11.AtomAtom, above, hidden) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			win := createWindow(t, c)
+			tt.set(win)
+			got, err := c.IsIconic(win)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.iconic {
+				t.Errorf("IsIconic = %v, want %v", got, tt.iconic)
+			}
+		})
+	}
+}
+
+// createWindow 创建一个 InputOnly 窗口并等待服务器处理完毕。
+func createWindow(t *testing.T, c *x11.Conn) uint32 {
+	t.Helper()
+	win := c.NewID()
+	req := c.Request(x11.OpCreateWindow, 0, 28)
+	binary.LittleEndian.PutUint32(req[4:], win)
+	binary.LittleEndian.PutUint32(req[8:], c.Root)
+	binary.LittleEndian.PutUint16(req[16:], 1)
+	binary.LittleEndian.PutUint16(req[18:], 1)
+	binary.LittleEndian.PutUint16(req[22:], 2) // InputOnly
+	if _, err := c.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return win
+}
@@ -0,0 +1,106 @@
+package x11
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	eventMaskSubstructureNotify   = 1 << 19
+	eventMaskSubstructureRedirect = 1 << 20
+
+	netWMStateAdd = 1
+	// sourcePager 请求来源为桌面工具 (而不是普通程序)，窗口管理器的防抢焦点机制不拦截
+	sourcePager = 2
+)
+
+// RaiseAbove 请求窗口管理器将已显示的窗口置顶并激活 (EWMH _NET_WM_STATE_ABOVE 和 _NET_ACTIVE_WINDOW)。
+// 窗口由其他库创建 (如 Fyne 的窗口)，这里另外建立一个短连接发送请求。
+// 不支持 EWMH 的窗口管理器会忽略这些请求。
+func RaiseAbove(display string, window uint32) error {
+	if window == 0 {
+		return errors.New("invalid window")
+	}
+	c, err := Dial(display)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	state, err := c.InternAtom("_NET_WM_STATE")
+	if err != nil {
+		return err
+	}
+	above, err := c.InternAtom("_NET_WM_STATE_ABOVE")
+	if err != nil {
+		return err
+	}
+	active, err := c.InternAtom("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+
+	if err := c.sendClientMessage(window, state, netWMStateAdd, above, 0, sourcePager); err != nil {
+		return err
+	}
+	if err := c.sendClientMessage(window, active, sourcePager, 0, 0); err != nil {
+		return err
+	}
+	return c.Sync()
+}
+
+// sendClientMessage 向根窗口发送关于 window 的 32 位格式 ClientMessage (窗口管理器接收)。
+func (c *Conn) sendClientMessage(window, messageType uint32, data ...uint32) error {
+	req := c.Request(OpSendEvent, 0, 40) // propagate = false
+	binary.LittleEndian.PutUint32(req[4:], c.Root)
+	binary.LittleEndian.PutUint32(req[8:], eventMaskSubstructureRedirect|eventMaskSubstructureNotify)
+	ev := req[12:]
+	ev[0] = EventClientMessage
+	ev[1] = 32 // format
+	binary.LittleEndian.PutUint32(ev[4:], window)
+	binary.LittleEndian.PutUint32(ev[8:], messageType)
+	for i, d := range data {
+		binary.LittleEndian.PutUint32(ev[12+4*i:], d)
+	}
+	_, err := c.Write(req)
+	return err
+}
+
+// iconicState ICCCM WM_STATE 中表示窗口已最小化 (图标化) 的状态值
+const iconicState = 3
+
+// IsIconic 查询窗口是否已被窗口管理器最小化: ICCCM WM_STATE 为 IconicState，
+// 或 EWMH _NET_WM_STATE 含 _NET_WM_STATE_HIDDEN。
+// 两个属性都不存在 (没有窗口管理器、窗口未映射) 时返回 false。
+func (c *Conn) IsIconic(window uint32) (bool, error) {
+	wmState, err := c.InternAtom("WM_STATE")
+	if err != nil {
+		return false, err
+	}
+	format, data, err := c.GetProperty(window, wmState, 0, 1)
+	if err != nil {
+		return false, err
+	}
+	if format == 32 && len(data) >= 4 && binary.LittleEndian.Uint32(data) == iconicState {
+		return true, nil
+	}
+
+	netState, err := c.InternAtom("_NET_WM_STATE")
+	if err != nil {
+		return false, err
+	}
+	hidden, err := c.InternAtom("_NET_WM_STATE_HIDDEN")
+	if err != nil {
+		return false, err
+	}
+	format, data, err = c.GetProperty(window, netState, AtomAtom, 32)
+	if err != nil || format != 32 {
+		return false, err
+	}
+	for i := 0; i+4 <= len(data); i += 4 {
+		if binary.LittleEndian.Uint32(data[i:]) == hidden {
+			return true, nil
+		}
+	}
+	return false, nil
+}
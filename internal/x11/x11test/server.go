@@ -0,0 +1,434 @@
+// Package x11test 提供内存中的假 X 服务器，用于测试 internal/x11 及其使用者的协议实现。
+// 只实现这些代码用到的请求 (窗口、atom、属性、选区、SendEvent、GetInputFocus)，行为按 X11 协议规范:
+// 例如 SetSelectionOwner 的时间戳早于选区上次变更或晚于服务器当前时间时不生效。
+package x11test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+
+	"key-box/internal/x11"
+)
+
+// Root 假服务器根窗口的 ID
+const Root = 0x100
+
+// 窗口事件掩码
+const (
+	eventMaskPropertyChange = 1 << 22
+	cwEventMask             = 1 << 11
+)
+
+// 错误代码
+const (
+	errRequest = 1
+	errWindow  = 3
+)
+
+// 属性修改方式
+const (
+	propModeReplace = 0
+	propModePrepend = 1
+	propModeAppend  = 2
+)
+
+// Server 内存中的假 X 服务器，可同时接受多个客户端连接。
+type Server struct {
+	mu       sync.Mutex
+	time     uint32
+	atoms    map[string]uint32
+	nextAtom uint32
+	windows  map[uint32]*window
+	owners   map[uint32]selectionOwner
+	clients  int
+
+	ignoreOwner      bool
+	currentTimeCount int
+}
+
+type window struct {
+	client    *client
+	eventMask uint32
+	props     map[uint32]property
+}
+
+type property struct {
+	typ    uint32
+	format byte
+	data   []byte
+}
+
+type selectionOwner struct {
+	window     uint32
+	lastChange uint32
+}
+
+type client struct {
+	conn   net.Conn
+	out    chan []byte
+	seq    uint16
+	closed bool
+}
+
+// NewServer 创建假服务器，服务器时间从 1000 开始，每处理一个请求加 1。
+func NewServer() *Server {
+	s := &Server{
+		time:     1000,
+		atoms:    map[string]uint32{"ATOM": x11.AtomAtom, "STRING": x11.AtomString},
+		nextAtom: 100,
+		windows:  map[uint32]*window{},
+		owners:   map[uint32]selectionOwner{},
+	}
+	s.windows[Root] = &window{props: map[uint32]property{}}
+	return s
+}
+
+// Dial 建立一个新的客户端连接并完成连接请求。
+func (s *Server) Dial() (*x11.Conn, error) {
+	clientEnd, serverEnd := net.Pipe()
+	s.mu.Lock()
+	s.clients++
+	base := uint32(s.clients) << 21
+	s.mu.Unlock()
+
+	c := &client{conn: serverEnd, out: make(chan []byte, 256)}
+	go c.write()
+	go s.serve(c, base)
+
+	xc, err := x11.NewConn(clientEnd, nil, nil)
+	if err != nil {
+		clientEnd.Close()
+		return nil, err
+	}
+	return xc, nil
+}
+
+// Atom 返回名称对应的 atom (不存在时创建)。
+func (s *Server) Atom(name string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.intern(name)
+}
+
+// SetProperty 以 32 位格式设置窗口属性 (模拟窗口管理器写入 WM_STATE 等)。
+func (s *Server) SetProperty(win uint32, name string, typ uint32, values ...uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, v)
+	}
+	if w := s.windows[win]; w != nil {
+		w.props[s.intern(name)] = property{typ: typ, format: 32, data: data}
+	}
+}
+
+// Property 返回窗口属性的类型和内容。
+func (s *Server) Property(win uint32, name string) (typ uint32, data []byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.windows[win]
+	if w == nil {
+		return 0, nil, false
+	}
+	p, ok := w.props[s.intern(name)]
+	return p.typ, p.data, ok
+}
+
+// Owner 返回选区当前的所有者窗口 (0 表示没有所有者)。
+func (s *Server) Owner(selection string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owners[s.intern(selection)].window
+}
+
+// IgnoreSelectionOwner 设置为 true 后 SetSelectionOwner 不生效 (模拟时间戳过期、其他程序抢先取得所有权)。
+func (s *Server) IgnoreSelectionOwner(ignore bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignoreOwner = ignore
+}
+
+// CurrentTimeRequests 返回使用 CurrentTime (0) 的 SetSelectionOwner 请求数 (ICCCM 禁止这样做)。
+func (s *Server) CurrentTimeRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentTimeCount
+}
+
+// Time 返回服务器当前时间。
+func (s *Server) Time() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.time
+}
+
+func (s *Server) intern(name string) uint32 {
+	a, ok := s.atoms[name]
+	if !ok {
+		a = s.nextAtom
+		s.nextAtom++
+		s.atoms[name] = a
+	}
+	return a
+}
+
+// write 按顺序把回复、事件和错误写给客户端，客户端暂时不读取时不阻塞其他连接。
+func (c *client) write() {
+	for buf := range c.out {
+		if _, err := c.conn.Write(buf); err != nil {
+			return
+		}
+	}
+}
+
+// send 把数据放入发送队列，只在持有 Server.mu 时调用。
+func (c *client) send(buf []byte) {
+	if c.closed {
+		return
+	}
+	binary.LittleEndian.PutUint16(buf[2:], c.seq)
+	c.out <- buf
+}
+
+func (c *client) sendError(code, major byte, value uint32) {
+	buf := make([]byte, 32)
+	buf[1] = code
+	binary.LittleEndian.PutUint32(buf[4:], value)
+	buf[10] = major
+	c.send(buf)
+}
+
+// sendEvent 发送 32 字节的事件 (事件不更新序号以外的内容)。
+func (c *client) sendEvent(ev []byte) {
+	c.send(append([]byte(nil), ev...))
+}
+
+// reply 发送回复: 32 字节头 + 附加数据 (按 4 字节对齐)。
+func (c *client) reply(head []byte, extra []byte) {
+	buf := make([]byte, 32, 32+len(extra))
+	copy(buf, head)
+	buf[0] = 1
+	extra = x11.Pad4(extra)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(extra)/4))
+	c.send(append(buf, extra...))
+}
+
+// serve 完成连接请求后逐个处理客户端的请求，连接关闭时退出。
+func (s *Server) serve(c *client, base uint32) {
+	defer func() {
+		s.mu.Lock()
+		c.closed = true
+		close(c.out)
+		s.mu.Unlock()
+	}()
+
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(c.conn, head); err != nil {
+		return
+	}
+	nameLen := int(binary.LittleEndian.Uint16(head[6:]))
+	dataLen := int(binary.LittleEndian.Uint16(head[8:]))
+	if _, err := io.ReadFull(c.conn, make([]byte, (nameLen+3)/4*4+(dataLen+3)/4*4)); err != nil {
+		return
+	}
+	c.out <- setupReply(base)
+
+	for {
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(c.conn, req); err != nil {
+			return
+		}
+		n := int(binary.LittleEndian.Uint16(req[2:])) * 4
+		if n < 4 {
+			return
+		}
+		req = append(req, make([]byte, n-4)...)
+		if _, err := io.ReadFull(c.conn, req[4:]); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		c.seq++
+		s.time++
+		s.handle(c, req)
+		s.mu.Unlock()
+	}
+}
+
+// setupReply 连接成功的回复: 资源 ID 范围、最大请求长度、键码范围和一个屏幕。
+func setupReply(base uint32) []byte {
+	const vendor = "key-box-test" // 12 字节，已对齐
+	body := make([]byte, 32+len(vendor)+40)
+	binary.LittleEndian.PutUint32(body[4:], base)
+	binary.LittleEndian.PutUint32(body[8:], 1<<21-1)
+	binary.LittleEndian.PutUint16(body[16:], uint16(len(vendor)))
+	binary.LittleEndian.PutUint16(body[18:], 0xffff)
+	body[20] = 1 // 屏幕数
+	body[26], body[27] = 8, 255
+	copy(body[32:], vendor)
+	binary.LittleEndian.PutUint32(body[32+len(vendor):], Root)
+
+	head := make([]byte, 8)
+	head[0] = 1
+	binary.LittleEndian.PutUint16(head[2:], 11)
+	binary.LittleEndian.PutUint16(head[6:], uint16(len(body)/4))
+	return append(head, body...)
+}
+
+func (s *Server) handle(c *client, req []byte) {
+	le := binary.LittleEndian
+	switch op := req[0]; op {
+	case x11.OpCreateWindow:
+		w := &window{client: c, props: map[uint32]property{}}
+		mask := le.Uint32(req[28:])
+		if mask&cwEventMask != 0 {
+			// 属性值按掩码位从低到高排列
+			i := 0
+			for bit := uint32(1); bit < cwEventMask; bit <<= 1 {
+				if mask&bit != 0 {
+					i++
+				}
+			}
+			w.eventMask = le.Uint32(req[32+4*i:])
+		}
+		s.windows[le.Uint32(req[4:])] = w
+
+	case x11.OpInternAtom:
+		n := int(le.Uint16(req[4:]))
+		name := string(req[8 : 8+n])
+		atom, ok := s.atoms[name]
+		if !ok && req[1] == 0 {
+			atom = s.intern(name)
+		}
+		head := make([]byte, 32)
+		le.PutUint32(head[8:], atom)
+		c.reply(head, nil)
+
+	case x11.OpChangeProperty:
+		win, prop := le.Uint32(req[4:]), le.Uint32(req[8:])
+		w := s.windows[win]
+		if w == nil {
+			c.sendError(errWindow, op, win)
+			return
+		}
+		format := req[16]
+		data := append([]byte(nil), req[24:24+int(le.Uint32(req[20:]))*int(format/8)]...)
+		p := property{typ: le.Uint32(req[12:]), format: format, data: data}
+		if old, ok := w.props[prop]; ok {
+			switch req[1] {
+			case propModeAppend:
+				p.data = append(old.data, data...)
+			case propModePrepend:
+				p.data = append(data, old.data...)
+			}
+		}
+		w.props[prop] = p
+		if w.client != nil && w.eventMask&eventMaskPropertyChange != 0 {
+			ev := make([]byte, 32)
+			ev[0] = x11.EventPropertyNotify
+			le.PutUint32(ev[4:], win)
+			le.PutUint32(ev[8:], prop)
+			le.PutUint32(ev[12:], s.time)
+			w.client.sendEvent(ev)
+		}
+
+	case x11.OpGetProperty:
+		win, prop, typ := le.Uint32(req[4:]), le.Uint32(req[8:]), le.Uint32(req[12:])
+		w := s.windows[win]
+		if w == nil {
+			c.sendError(errWindow, op, win)
+			return
+		}
+		head := make([]byte, 32)
+		p, ok := w.props[prop]
+		if !ok {
+			c.reply(head, nil)
+			return
+		}
+		head[1] = p.format
+		le.PutUint32(head[8:], p.typ)
+		if typ != 0 && typ != p.typ {
+			le.PutUint32(head[12:], uint32(len(p.data)))
+			c.reply(head, nil)
+			return
+		}
+		offset := min(int(le.Uint32(req[16:]))*4, len(p.data))
+		end := min(offset+int(le.Uint32(req[20:]))*4, len(p.data))
+		le.PutUint32(head[12:], uint32(len(p.data)-end))
+		le.PutUint32(head[16:], uint32((end-offset)/int(p.format/8)))
+		c.reply(head, p.data[offset:end])
+
+	case x11.OpSetSelectionOwner:
+		owner, selection, t := le.Uint32(req[4:]), le.Uint32(req[8:]), le.Uint32(req[12:])
+		if t == 0 {
+			s.currentTimeCount++
+			t = s.time
+		}
+		prev := s.owners[selection]
+		if s.ignoreOwner || t < prev.lastChange || t > s.time {
+			return
+		}
+		if prev.window != 0 && prev.window != owner {
+			if w := s.windows[prev.window]; w != nil && w.client != nil {
+				ev := make([]byte, 32)
+				ev[0] = x11.EventSelectionClear
+				le.PutUint32(ev[4:], t)
+				le.PutUint32(ev[8:], prev.window)
+				le.PutUint32(ev[12:], selection)
+				w.client.sendEvent(ev)
+			}
+		}
+		s.owners[selection] = selectionOwner{window: owner, lastChange: t}
+
+	case x11.OpGetSelectionOwner:
+		head := make([]byte, 32)
+		le.PutUint32(head[8:], s.owners[le.Uint32(req[4:])].window)
+		c.reply(head, nil)
+
+	case x11.OpConvertSelection:
+		requestor, selection := le.Uint32(req[4:]), le.Uint32(req[8:])
+		owner := s.owners[selection].window
+		ow := s.windows[owner]
+		if owner == 0 || ow == nil || ow.client == nil {
+			ev := make([]byte, 32)
+			ev[0] = x11.EventSelectionNotify
+			le.PutUint32(ev[4:], le.Uint32(req[20:]))
+			le.PutUint32(ev[8:], requestor)
+			le.PutUint32(ev[12:], selection)
+			le.PutUint32(ev[16:], le.Uint32(req[12:]))
+			if w := s.windows[requestor]; w != nil && w.client != nil {
+				w.client.sendEvent(ev)
+			}
+			return
+		}
+		ev := make([]byte, 32)
+		ev[0] = x11.EventSelectionRequest
+		le.PutUint32(ev[4:], le.Uint32(req[20:]))
+		le.PutUint32(ev[8:], owner)
+		le.PutUint32(ev[12:], requestor)
+		le.PutUint32(ev[16:], selection)
+		le.PutUint32(ev[20:], le.Uint32(req[12:]))
+		le.PutUint32(ev[24:], le.Uint32(req[16:]))
+		ow.client.sendEvent(ev)
+
+	case x11.OpSendEvent:
+		dest := le.Uint32(req[4:])
+		ev := append([]byte(nil), req[12:44]...)
+		ev[0] |= 0x80
+		// 发往根窗口的事件 (给窗口管理器的请求) 没有接收方，直接丢弃
+		if w := s.windows[dest]; w != nil && w.client != nil {
+			w.client.sendEvent(ev)
+		}
+
+	case x11.OpGetInputFocus:
+		head := make([]byte, 32)
+		le.PutUint32(head[8:], Root)
+		c.reply(head, nil)
+
+	default:
+		c.sendError(errRequest, op, 0)
+	}
+}